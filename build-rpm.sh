@@ -11,7 +11,7 @@ fi
 
 mkdir -p SOURCES
 
-tar czvf SOURCES/cosmos-exporter-${VERSION}.tar.gz dashboards images *.md *.go *.mod *.sum *.abi
+tar czvf SOURCES/cosmos-exporter-${VERSION}.tar.gz dashboards images *.md *.go exporter *.mod *.sum *.abi
 
 echo "Build the package"
 
@@ -0,0 +1,634 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CudoVentures/cosmos-exporter/exporter"
+	"github.com/mitchellh/mapstructure"
+	"github.com/rs/zerolog"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// redacted replaces secrets in the output of config print.
+const redacted = "<redacted>"
+
+// nonConfigFlags can't be set from the config file.
+var nonConfigFlags = map[string]bool{
+	"config": true,
+	"help":   true,
+}
+
+// configSections are the keys of the config file that aren't flags, with the
+// types they're decoded into.
+func configSections() map[string]interface{} {
+	return map[string]interface{}{
+		"chains":                    &map[string]exporter.ChainConfig{},
+		"optional-network-settings": &map[string]exporter.NodeSettings{},
+		"targets":                   &exporter.TargetsConfig{},
+	}
+}
+
+// flagTypes are the values the config file can set each type of flag to.
+var flagTypes = map[string]string{
+	"string":         "a string",
+	"bool":           "true or false",
+	"float64":        "a number",
+	"int":            "an integer",
+	"uint64":         "a non-negative integer",
+	"duration":       `a duration like "30s"`,
+	"stringSlice":    "a string or a list of strings",
+	"stringToString": "an object of strings",
+}
+
+// configErrors are all the problems found in the config, reported together so
+// they can be fixed in one go.
+type configErrors []error
+
+func (e configErrors) Error() string {
+	lines := make([]string, len(e))
+	for i, err := range e {
+		lines[i] = "- " + err.Error()
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// loadConfig applies the config file to the flags that aren't set on the
+// command line, and validates the result.
+func loadConfig(cmd *cobra.Command) error {
+	if ConfigPath == "" {
+		log.Info().Msg("Config file not provided")
+	} else {
+		log.Info().Msg("Config file provided")
+
+		viper.SetConfigFile(ConfigPath)
+		if err := viper.ReadInConfig(); err != nil {
+			log.Info().Err(err).Msg("Error reading config file")
+			if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+				return err
+			}
+		}
+
+		if errs := checkConfigFile(viper.GetViper(), cmd.Flags()); len(errs) > 0 {
+			return errs
+		}
+
+		// Credits to https://carolynvanslyck.com/blog/2020/08/sting-of-the-viper/
+		var err error
+		cmd.Flags().VisitAll(func(f *pflag.Flag) {
+			if err == nil && !f.Changed && viper.IsSet(f.Name) {
+				if setErr := cmd.Flags().Set(f.Name, flagValue(viper.Get(f.Name))); setErr != nil {
+					err = fmt.Errorf("invalid %s: %w", f.Name, setErr)
+				}
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	setBechPrefixes(cmd)
+
+	if errs := validateConfig(); len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// checkConfigFile checks the config file against its schema: every key is a
+// flag or a config section, flags are set to values of their type, and the
+// sections have no unknown keys either.
+func checkConfigFile(config *viper.Viper, flags *pflag.FlagSet) configErrors {
+	var errs configErrors
+
+	settings := config.AllSettings()
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	sections := configSections()
+	for _, key := range keys {
+		value := settings[key]
+
+		if target, ok := sections[key]; ok {
+			for _, err := range decodeStrict(value, target) {
+				errs = append(errs, fmt.Errorf("%s: %w", key, err))
+			}
+			continue
+		}
+
+		flag := flags.Lookup(key)
+		if flag == nil || nonConfigFlags[key] {
+			errs = append(errs, fmt.Errorf("unknown key %s", key))
+			continue
+		}
+
+		if !isFlagValue(flag.Value.Type(), value) {
+			errs = append(errs, fmt.Errorf("%s: expected %s, got %s", key, flagTypes[flag.Value.Type()], describeValue(value)))
+		}
+	}
+
+	return errs
+}
+
+// decodeStrict decodes a config section, failing on unknown keys and on
+// values of the wrong type instead of converting them.
+func decodeStrict(input, output interface{}) []error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:  mapstructure.StringToSliceHookFunc(","),
+		ErrorUnused: true,
+		Result:      output,
+	})
+	if err != nil {
+		return []error{err}
+	}
+
+	err = decoder.Decode(input)
+
+	var decodeErr *mapstructure.Error
+	if errors.As(err, &decodeErr) {
+		errs := make([]error, len(decodeErr.Errors))
+		for i, message := range decodeErr.Errors {
+			errs[i] = errors.New(message)
+		}
+		return errs
+	}
+
+	if err != nil {
+		return []error{err}
+	}
+
+	return nil
+}
+
+func isFlagValue(flagType string, value interface{}) bool {
+	switch flagType {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "bool":
+		_, ok := value.(bool)
+		return ok
+	case "float64":
+		_, ok := toFloat(value)
+		return ok
+	case "int", "uint64":
+		number, ok := toFloat(value)
+		return ok && number == math.Trunc(number) && (flagType == "int" || number >= 0)
+	case "duration":
+		duration, ok := value.(string)
+		if !ok {
+			return false
+		}
+		_, err := time.ParseDuration(duration)
+		return err == nil
+	case "stringSlice":
+		if _, ok := value.(string); ok {
+			return true
+		}
+		list, ok := value.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, item := range list {
+			if _, ok := item.(string); !ok {
+				return false
+			}
+		}
+		return true
+	case "stringToString":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for _, item := range object {
+			if _, ok := item.(string); !ok {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+// toFloat returns numbers of any of the types the config formats decode to.
+func toFloat(value interface{}) (float64, bool) {
+	switch number := reflect.ValueOf(value); number.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return number.Float(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(number.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(number.Uint()), true
+	}
+
+	return 0, false
+}
+
+func describeValue(value interface{}) string {
+	switch value := value.(type) {
+	case string:
+		return fmt.Sprintf("the string %q", value)
+	case []interface{}:
+		return "a list"
+	case map[string]interface{}:
+		return "an object"
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
+
+// validateConfig checks the values of the merged config, from the command
+// line and the config file.
+func validateConfig() configErrors {
+	var errs configErrors
+	add := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	defaultPrefixes := exporter.Bech32Prefixes{
+		Account:             AccountPrefix,
+		AccountPubkey:       AccountPubkeyPrefix,
+		Validator:           ValidatorPrefix,
+		ValidatorPubkey:     ValidatorPubkeyPrefix,
+		ConsensusNode:       ConsensusNodePrefix,
+		ConsensusNodePubkey: ConsensusNodePubkeyPrefix,
+	}
+	errs = append(errs, checkBech32Prefixes("", defaultPrefixes)...)
+
+	for _, node := range NodeAddresses {
+		add(checkNodeAddress("node", node))
+	}
+
+	for _, rpc := range TendermintRPCs {
+		add(exporter.CheckURL("tendermint-rpc", rpc, "http", "https", "tcp", "unix"))
+	}
+
+	// an IPC path has no scheme
+	add(exporter.CheckURL("eth-rpc", EthRPC, "http", "https", "ws", "wss", ""))
+
+	add(checkDenomCoefficient("denom-coefficient", DenomCoefficient))
+
+	if err := exporter.CheckDenomOverrides(DenomOverrides); err != nil {
+		add(fmt.Errorf("denoms: %w", err))
+	}
+
+	if exporter.Limit == 0 {
+		add(fmt.Errorf("limit: must be positive"))
+	}
+
+	if exporter.MaxPages <= 0 {
+		add(fmt.Errorf("max-pages: must be positive"))
+	}
+
+	for endpoint, interval := range PollIntervals {
+		if _, err := time.ParseDuration(interval); err != nil {
+			add(fmt.Errorf("poll-intervals: invalid interval of %s: %w", endpoint, err))
+		}
+	}
+
+	add(StartupConfig.Validate())
+
+	errs = append(errs, exporter.PushSettings.Check()...)
+
+	for name, address := range StartupConfig.OptionalNetworks {
+		add(checkNodeAddress("optional-networks."+name, address))
+	}
+
+	if _, err := optionalNetworkSettings(viper.GetViper(), StartupConfig.OptionalNetworks); err != nil {
+		add(fmt.Errorf("optional-network-settings: %w", err))
+	}
+
+	configs, err := chainConfigs()
+	if err != nil {
+		add(fmt.Errorf("chains: %w", err))
+	}
+
+	for name, config := range configs {
+		scope := "chains." + name + "."
+
+		errs = append(errs, checkBech32Prefixes(scope, config.Prefixes())...)
+
+		for _, node := range config.Node {
+			add(checkNodeAddress(scope+"node", node))
+		}
+
+		for _, rpc := range config.TendermintRPC {
+			add(exporter.CheckURL(scope+"tendermint-rpc", rpc, "http", "https", "tcp", "unix"))
+		}
+
+		add(checkDenomCoefficient(scope+"denom-coefficient", config.DenomCoefficient))
+
+		if err := exporter.CheckDenomOverrides(config.Denoms); err != nil {
+			add(fmt.Errorf("%sdenoms: %w", scope, err))
+		}
+	}
+
+	targets, err := targetsConfig()
+	if err != nil {
+		add(fmt.Errorf("targets: %w", err))
+	}
+
+	prefixes := map[string]exporter.Bech32Prefixes{
+		"":                        defaultPrefixes,
+		exporter.DefaultChainName: defaultPrefixes,
+	}
+	for name, config := range configs {
+		prefixes[name] = config.Prefixes()
+	}
+	errs = append(errs, targets.Check(prefixes)...)
+
+	return errs
+}
+
+// checkBech32Prefixes validates the prefixes as Bech32 human-readable parts.
+// Keys are reported with the scope prepended.
+func checkBech32Prefixes(scope string, p exporter.Bech32Prefixes) []error {
+	var errs []error
+
+	for key, prefix := range map[string]string{
+		"bech-account-prefix":               p.Account,
+		"bech-account-pubkey-prefix":        p.AccountPubkey,
+		"bech-validator-prefix":             p.Validator,
+		"bech-validator-pubkey-prefix":      p.ValidatorPubkey,
+		"bech-consensus-node-prefix":        p.ConsensusNode,
+		"bech-consensus-node-pubkey-prefix": p.ConsensusNodePubkey,
+	} {
+		if err := checkBech32Prefix(prefix); err != nil {
+			errs = append(errs, fmt.Errorf("%s%s: %w", scope, key, err))
+		}
+	}
+
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].Error() < errs[j].Error()
+	})
+
+	return errs
+}
+
+// checkBech32Prefix follows BIP-173: 1 to 83 printable ASCII characters, and
+// lowercase, as addresses are.
+func checkBech32Prefix(prefix string) error {
+	if len(prefix) == 0 || len(prefix) > 83 {
+		return fmt.Errorf("invalid Bech32 prefix %q, must be 1 to 83 characters", prefix)
+	}
+
+	for _, char := range prefix {
+		if char < 33 || char > 126 || (char >= 'A' && char <= 'Z') {
+			return fmt.Errorf("invalid Bech32 prefix %q, must be lowercase printable ASCII", prefix)
+		}
+	}
+
+	return nil
+}
+
+// checkNodeAddress accepts host:port, or a gRPC target with a resolver like
+// dns:///host:port.
+func checkNodeAddress(key, address string) error {
+	if strings.Contains(address, ":///") {
+		return nil
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if err == nil {
+		_, err = strconv.ParseUint(port, 10, 16)
+	}
+
+	if err != nil || host == "" || strings.Contains(host, "/") {
+		return fmt.Errorf("%s: invalid gRPC address %q, expected host:port", key, address)
+	}
+
+	return nil
+}
+
+// checkDenomCoefficient allows 0, for a coefficient resolved from the chain,
+// or a power of 10, as amounts are scaled exactly by its exponent.
+func checkDenomCoefficient(key string, coefficient float64) error {
+	if coefficient == 0 {
+		return nil
+	}
+
+	if _, ok := exporter.DenomExponent(coefficient); !ok {
+		return fmt.Errorf("%s: %v is not a power of 10", key, coefficient)
+	}
+
+	return nil
+}
+
+// effectiveConfig is the merged config in the format of the config file, with
+// the values of headers and the passwords of URLs redacted.
+func effectiveConfig(flags *pflag.FlagSet) (map[string]interface{}, error) {
+	config := make(map[string]interface{})
+
+	var err error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if err != nil || nonConfigFlags[f.Name] {
+			return
+		}
+
+		switch f.Value.Type() {
+		case "stringSlice":
+			config[f.Name], err = flags.GetStringSlice(f.Name)
+		case "stringToString":
+			config[f.Name], err = flags.GetStringToString(f.Name)
+		case "bool":
+			config[f.Name], err = flags.GetBool(f.Name)
+		case "float64":
+			config[f.Name], err = flags.GetFloat64(f.Name)
+		case "int":
+			config[f.Name], err = flags.GetInt(f.Name)
+		case "uint64":
+			config[f.Name], err = flags.GetUint64(f.Name)
+		default:
+			config[f.Name] = f.Value.String()
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the Bech32 prefixes that aren't set are derived from bech-prefix
+	config["bech-account-prefix"] = AccountPrefix
+	config["bech-account-pubkey-prefix"] = AccountPubkeyPrefix
+	config["bech-validator-prefix"] = ValidatorPrefix
+	config["bech-validator-pubkey-prefix"] = ValidatorPubkeyPrefix
+	config["bech-consensus-node-prefix"] = ConsensusNodePrefix
+	config["bech-consensus-node-pubkey-prefix"] = ConsensusNodePubkeyPrefix
+
+	for section := range configSections() {
+		if viper.IsSet(section) {
+			config[section] = viper.Get(section)
+		}
+	}
+
+	return redact("", config).(map[string]interface{}), nil
+}
+
+// redact replaces the values of headers, which carry API keys, and the
+// passwords of URLs.
+func redact(key string, value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for name, item := range value {
+			if key == "headers" || key == "node-headers" || key == "push-headers" {
+				result[name] = redacted
+			} else {
+				result[name] = redact(name, item)
+			}
+		}
+		return result
+	case map[string]string:
+		result := make(map[string]interface{}, len(value))
+		for name, item := range value {
+			result[name] = item
+		}
+		return redact(key, result)
+	case []string:
+		result := make([]interface{}, len(value))
+		for i, item := range value {
+			result[i] = item
+		}
+		return redact(key, result)
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, item := range value {
+			result[i] = redact(key, item)
+		}
+		return result
+	case string:
+		if parsed, err := url.Parse(value); err == nil && parsed.User != nil {
+			return parsed.Redacted()
+		}
+		return value
+	default:
+		return value
+	}
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Check or show the configuration",
+	// config files are read by the subcommands, and only errors are logged
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		zerolog.SetGlobalLevel(zerolog.ErrorLevel)
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate [config file]",
+	Short: "Validate the config file, and the flags along with it",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) > 0 {
+			ConfigPath = args[0]
+		}
+
+		if err := loadConfig(cmd); err != nil {
+			fmt.Fprintf(os.Stderr, "%s is invalid:\n%s\n", ConfigPath, err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("%s is valid\n", ConfigPath)
+	},
+}
+
+var configPrintCmd = &cobra.Command{
+	Use:   "print",
+	Short: "Print the effective config, merged from the flags and the config file, with secrets redacted",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := loadConfig(cmd); err != nil {
+			fmt.Fprintf(os.Stderr, "%s is invalid:\n%s\n", ConfigPath, err)
+			os.Exit(1)
+		}
+
+		config, err := effectiveConfig(cmd.Flags())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not print config: %s\n", err)
+			os.Exit(1)
+		}
+
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "    ")
+		if err := encoder.Encode(config); err != nil {
+			fmt.Fprintf(os.Stderr, "Could not print config: %s\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// chainConfigs reads the additional chains from the chains object of the
+// config file.
+func chainConfigs() (map[string]exporter.ChainConfig, error) {
+	configs := make(map[string]exporter.ChainConfig)
+	if err := viper.UnmarshalKey("chains", &configs); err != nil {
+		return nil, err
+	}
+
+	for name, config := range configs {
+		if name == exporter.DefaultChainName {
+			return nil, fmt.Errorf("chain name %s is reserved for the chain of the top-level flags", exporter.DefaultChainName)
+		}
+
+		if config.BechPrefix == "" && config.BechAccountPrefix == "" {
+			return nil, fmt.Errorf("chain %s has no bech-prefix", name)
+		}
+
+		if len(config.Node) == 0 || len(config.TendermintRPC) == 0 {
+			return nil, fmt.Errorf("chain %s requires both node and tendermint-rpc", name)
+		}
+	}
+
+	return configs, nil
+}
+
+// targetsConfig reads the targets object of the config file.
+func targetsConfig() (exporter.TargetsConfig, error) {
+	var targets exporter.TargetsConfig
+	err := viper.UnmarshalKey("targets", &targets)
+	return targets, err
+}
+
+// optionalNetworkSettings reads the settings of the optional networks from
+// the config file. Viper lowercases keys, so the settings are keyed by the
+// lowercased network name.
+func optionalNetworkSettings(v *viper.Viper, addresses map[string]string) (map[string]exporter.NodeSettings, error) {
+	settings := make(map[string]exporter.NodeSettings)
+	if err := v.UnmarshalKey("optional-network-settings", &settings); err != nil {
+		return nil, err
+	}
+
+	networks := make(map[string]bool)
+	for network := range addresses {
+		networks[strings.ToLower(network)] = true
+	}
+
+	for network := range settings {
+		if !networks[network] {
+			return nil, fmt.Errorf("settings for unknown optional network %s", network)
+		}
+	}
+
+	return settings, nil
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+func testConfigFlags() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.String("node", "localhost:9090", "")
+	flags.Bool("json", false, "")
+	flags.Float64("denom-coefficient", 1, "")
+	flags.Int("max-pages", 0, "")
+	flags.Uint64("limit", 1000, "")
+	flags.Duration("poll-interval", 0, "")
+	flags.StringSlice("denoms", nil, "")
+	flags.StringToString("headers", nil, "")
+	flags.String("config", "", "")
+
+	return flags
+}
+
+func TestCheckConfigFile(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   []string
+	}{
+		{
+			name: "valid",
+			config: `
+node: localhost:9090
+json: true
+denom-coefficient: 1000000
+max-pages: -1
+limit: 100
+poll-interval: 30s
+denoms: uatom
+headers:
+  x-api-key: secret
+chains:
+  cosmoshub:
+    node: [grpc.cosmos.network:443]
+    denom: uatom
+    tls: true
+    headers:
+      x-api-key: secret
+optional-network-settings:
+  osmosis:
+    tls: true
+targets:
+  wallets:
+    - name: treasury
+      chain: cosmoshub
+      address: cosmos1abc
+`,
+		},
+		{
+			name:   "string list",
+			config: "denoms: [uatom, uosmo]\n",
+		},
+		{
+			name:   "comma-separated list in a section",
+			config: "chains:\n  cosmoshub:\n    node: a:9090,b:9090\n",
+		},
+		{
+			name:   "unknown key",
+			config: "nodes: localhost:9090\n",
+			want:   []string{"unknown key nodes"},
+		},
+		{
+			name:   "not a config flag",
+			config: "config: other.toml\n",
+			want:   []string{"unknown key config"},
+		},
+		{
+			name: "wrong types",
+			config: `
+json: "yes"
+denom-coefficient: a million
+max-pages: 1.5
+limit: -1
+poll-interval: 30
+denoms: [1, 2]
+headers: [x-api-key]
+`,
+			want: []string{
+				`denom-coefficient: expected a number, got the string "a million"`,
+				"denoms: expected a string or a list of strings, got a list",
+				"headers: expected an object of strings, got a list",
+				`json: expected true or false, got the string "yes"`,
+				"limit: expected a non-negative integer, got -1",
+				"max-pages: expected an integer, got 1.5",
+				`poll-interval: expected a duration like "30s", got 30`,
+			},
+		},
+		{
+			name: "unknown keys in sections",
+			config: `
+chains:
+  cosmoshub:
+    nodes: [localhost:9090]
+targets:
+  wallet: []
+`,
+			want: []string{
+				"chains: '[cosmoshub]' has invalid keys: nodes",
+				"targets: '' has invalid keys: wallet",
+			},
+		},
+		{
+			name:   "wrong type in a section",
+			config: "chains:\n  cosmoshub:\n    denom-coefficient: a million\n",
+			want:   []string{"chains: '[cosmoshub].denom-coefficient' expected type 'float64', got unconvertible type 'string'"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := viper.New()
+			config.SetConfigType("yaml")
+			if err := config.ReadConfig(strings.NewReader(test.config)); err != nil {
+				t.Fatal(err)
+			}
+
+			errs := checkConfigFile(config, testConfigFlags())
+
+			got := make([]string, len(errs))
+			for i, err := range errs {
+				got[i] = err.Error()
+			}
+
+			if len(got) != len(test.want) {
+				t.Fatalf("checkConfigFile() = %q, want %q", got, test.want)
+			}
+
+			for i := range got {
+				if !strings.HasPrefix(got[i], test.want[i]) {
+					t.Errorf("error %d = %q, want %q", i, got[i], test.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeStrict(t *testing.T) {
+	type section struct {
+		Name  string        `mapstructure:"name"`
+		Nodes []string      `mapstructure:"nodes"`
+		Every time.Duration `mapstructure:"every"`
+	}
+
+	tests := []struct {
+		name    string
+		input   map[string]interface{}
+		want    section
+		wantErr int
+	}{
+		{
+			name:  "valid",
+			input: map[string]interface{}{"name": "hub", "nodes": []interface{}{"a:9090", "b:9090"}},
+			want:  section{Name: "hub", Nodes: []string{"a:9090", "b:9090"}},
+		},
+		{
+			name:  "comma-separated list",
+			input: map[string]interface{}{"nodes": "a:9090,b:9090"},
+			want:  section{Nodes: []string{"a:9090", "b:9090"}},
+		},
+		{
+			name:    "unknown key",
+			input:   map[string]interface{}{"nodes": "a:9090", "node": "b:9090"},
+			wantErr: 1,
+		},
+		{
+			name:    "not converted",
+			input:   map[string]interface{}{"name": 42, "every": "30s"},
+			wantErr: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got section
+			errs := decodeStrict(test.input, &got)
+			if len(errs) != test.wantErr {
+				t.Fatalf("decodeStrict() = %v, want %d errors", errs, test.wantErr)
+			}
+
+			if test.wantErr == 0 && !reflect.DeepEqual(got, test.want) {
+				t.Errorf("decodeStrict() decoded %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckBech32Prefix(t *testing.T) {
+	tests := []struct {
+		prefix string
+		valid  bool
+	}{
+		{"cosmos", true},
+		{"cosmosvaloper", true},
+		{"cudos", true},
+		{"a", true},
+		{strings.Repeat("a", 83), true},
+		{"", false},
+		{strings.Repeat("a", 84), false},
+		{"Cosmos", false},
+		{"cos mos", false},
+		{"cosmos\n", false},
+		{"cösmos", false},
+	}
+
+	for _, test := range tests {
+		if err := checkBech32Prefix(test.prefix); (err == nil) != test.valid {
+			t.Errorf("checkBech32Prefix(%q) = %v, want valid %t", test.prefix, err, test.valid)
+		}
+	}
+}
+
+func TestCheckNodeAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		valid   bool
+	}{
+		{"localhost:9090", true},
+		{"grpc.cosmos.network:443", true},
+		{"127.0.0.1:9090", true},
+		{"[::1]:9090", true},
+		{"dns:///grpc.cosmos.network:443", true},
+		{"localhost", false},
+		{":9090", false},
+		{"localhost:port", false},
+		{"localhost:65536", false},
+		{"http://localhost:9090", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		err := checkNodeAddress("node", test.address)
+		if (err == nil) != test.valid {
+			t.Errorf("checkNodeAddress(%q) = %v, want valid %t", test.address, err, test.valid)
+		}
+
+		if err != nil && !strings.HasPrefix(err.Error(), "node: ") {
+			t.Errorf("checkNodeAddress(%q) = %q, want the key first", test.address, err)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package exporter
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Amount is an exact decimal token amount: value × 10^-scale. Amounts are
+// scaled by shifting the decimal point, and converted to float64 only once,
+// when they're set on a metric, so a metric is the float64 closest to the
+// exact amount, even for denoms with 18 decimals.
+type Amount struct {
+	value *big.Int
+	scale int
+}
+
+func IntAmount(amount sdk.Int) Amount {
+	if amount.IsNil() {
+		return Amount{}
+	}
+
+	return Amount{value: amount.BigInt()}
+}
+
+// DecAmount keeps all the decimals of the sdk.Dec.
+func DecAmount(amount sdk.Dec) Amount {
+	if amount.IsNil() {
+		return Amount{}
+	}
+
+	return Amount{value: amount.BigInt(), scale: sdk.Precision}
+}
+
+// BigAmount is for amounts that don't come from the SDK, like ERC20 balances.
+func BigAmount(amount *big.Int) Amount {
+	return Amount{value: amount}
+}
+
+// ParseAmount parses a decimal string, like the amounts of REST APIs.
+func ParseAmount(amount string) (Amount, error) {
+	dec, err := sdk.NewDecFromStr(amount)
+	if err != nil {
+		return Amount{}, err
+	}
+
+	return DecAmount(dec), nil
+}
+
+// Shift divides the amount by 10^exponent.
+func (a Amount) Shift(exponent int) Amount {
+	return Amount{value: a.value, scale: a.scale + exponent}
+}
+
+// String returns the exact amount, without trailing zeros.
+func (a Amount) String() string {
+	if a.value == nil || a.value.Sign() == 0 {
+		return "0"
+	}
+
+	sign := ""
+	if a.value.Sign() < 0 {
+		sign = "-"
+	}
+
+	digits := new(big.Int).Abs(a.value).String()
+	if a.scale <= 0 {
+		return sign + digits + strings.Repeat("0", -a.scale)
+	}
+
+	if len(digits) <= a.scale {
+		digits = strings.Repeat("0", a.scale-len(digits)+1) + digits
+	}
+
+	integer := digits[:len(digits)-a.scale]
+	fraction := strings.TrimRight(digits[len(digits)-a.scale:], "0")
+	if fraction == "" {
+		return sign + integer
+	}
+
+	return sign + integer + "." + fraction
+}
+
+// MarshalText encodes the amount as its exact decimal string, so JSON parsers
+// that read numbers as float64s don't round it.
+func (a Amount) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// Float64 returns the float64 closest to the amount. strconv rounds decimal
+// strings correctly, which dividing two float64s doesn't.
+func (a Amount) Float64() float64 {
+	value, _ := strconv.ParseFloat(a.String(), 64)
+	return value
+}
+
+// DenomExponent returns the exponent of a denom coefficient, which has to be
+// a power of 10.
+func DenomExponent(coefficient float64) (int, bool) {
+	exponent := int(math.Round(math.Log10(coefficient)))
+	return exponent, exponent >= 0 && math.Pow10(exponent) == coefficient
+}
+
+// ExactAmounts enables the _exact_info metrics of AmountGaugeVec.
+var ExactAmounts bool
+
+// AmountGaugeVec is a GaugeVec of token amounts. With --exact-amounts, every
+// amount is also exported exactly, as the amount label of an info metric named
+// after the gauge with an _exact_info suffix, for accounting that can't live
+// with float64 rounding.
+type AmountGaugeVec struct {
+	name  string
+	gauge *prometheus.GaugeVec
+
+	mutex sync.Mutex
+	// the labels and exact amount of every series, sent along with its
+	// gauge as an AmountMetric
+	series map[string]AmountMetric
+	exact  *prometheus.GaugeVec
+	// the latest exact labels of every series, to replace them when it's
+	// set again
+	exactLabels map[string]prometheus.Labels
+}
+
+func NewAmountGaugeVec(opts prometheus.GaugeOpts, labelNames []string) *AmountGaugeVec {
+	vec := &AmountGaugeVec{
+		name:   prometheus.BuildFQName(opts.Namespace, opts.Subsystem, opts.Name),
+		gauge:  prometheus.NewGaugeVec(opts, labelNames),
+		series: make(map[string]AmountMetric),
+	}
+
+	if ExactAmounts {
+		exactOpts := opts
+		exactOpts.Name += "_exact_info"
+		exactOpts.Help += ", exactly, in the amount label"
+
+		exactLabelNames := append(append([]string{}, labelNames...), "amount")
+		vec.exact = prometheus.NewGaugeVec(exactOpts, exactLabelNames)
+		vec.exactLabels = make(map[string]prometheus.Labels)
+	}
+
+	return vec
+}
+
+// newChainAmountGaugeVec is newChainGaugeVec for amounts. The series are
+// only collected by the returned vec.
+func newChainAmountGaugeVec(opts prometheus.GaugeOpts, chainID string, labelNames []string) *AmountGaugeVec {
+	vec := NewAmountGaugeVec(opts, append([]string{"chain_id"}, labelNames...))
+
+	chainLabels := prometheus.Labels{"chain_id": chainID}
+	vec.gauge = vec.gauge.MustCurryWith(chainLabels)
+	if vec.exact != nil {
+		vec.exact = vec.exact.MustCurryWith(chainLabels)
+	}
+
+	return vec
+}
+
+// Set sets the series with labels, which can be nil for a gauge without
+// labels, to the amount.
+func (v *AmountGaugeVec) Set(labels prometheus.Labels, amount Amount) {
+	gauge := v.gauge.With(labels)
+	gauge.Set(amount.Float64())
+
+	key := labelsKey(labels)
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	v.series[key] = AmountMetric{Metric: gauge, Name: v.name, Labels: labels, Amount: amount}
+
+	if v.exact == nil {
+		return
+	}
+
+	exactLabels := mergeLabels(labels, prometheus.Labels{"amount": amount.String()})
+
+	if previous, ok := v.exactLabels[key]; ok {
+		v.exact.Delete(previous)
+	}
+
+	v.exact.With(exactLabels).Set(1)
+	v.exactLabels[key] = exactLabels
+}
+
+func (v *AmountGaugeVec) Describe(ch chan<- *prometheus.Desc) {
+	v.gauge.Describe(ch)
+	if v.exact != nil {
+		v.exact.Describe(ch)
+	}
+}
+
+func (v *AmountGaugeVec) Collect(ch chan<- prometheus.Metric) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	for _, series := range v.series {
+		series := series
+		ch <- &series
+	}
+
+	if v.exact != nil {
+		v.exact.Collect(ch)
+	}
+}
+
+// AmountMetric is the gauge of a series of an AmountGaugeVec, along with its
+// exact amount, for consumers of the collectors that read the amounts
+// themselves instead of gathering them as float64s, like the JSON API.
+type AmountMetric struct {
+	prometheus.Metric
+
+	Name   string
+	Labels prometheus.Labels
+	Amount Amount
+}
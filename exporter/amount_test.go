@@ -0,0 +1,134 @@
+package exporter
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestAmountString(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount Amount
+		want   string
+	}{
+		{"nil", Amount{}, "0"},
+		{"zero", BigAmount(big.NewInt(0)).Shift(6), "0"},
+		{"integer", IntAmount(sdk.NewInt(1234)), "1234"},
+		{"shifted", IntAmount(sdk.NewInt(1234567)).Shift(6), "1.234567"},
+		{"trailing zeros", IntAmount(sdk.NewInt(1500000)).Shift(6), "1.5"},
+		{"whole after shift", IntAmount(sdk.NewInt(2000000)).Shift(6), "2"},
+		{"below one", IntAmount(sdk.NewInt(42)).Shift(6), "0.000042"},
+		{"negative", IntAmount(sdk.NewInt(-1234567)).Shift(6), "-1.234567"},
+		{"negative shift", IntAmount(sdk.NewInt(12)).Shift(-3), "12000"},
+		{"dec", DecAmount(sdk.MustNewDecFromStr("0.5")), "0.5"},
+		{"18 decimals", IntAmount(sdk.NewIntFromBigInt(mustBigInt(t, "123456789012345678123456789012345678"))).Shift(18), "123456789012345678.123456789012345678"},
+		{"dec shifted", DecAmount(sdk.MustNewDecFromStr("1234.5")).Shift(3), "1.2345"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.amount.String(); got != test.want {
+				t.Errorf("String() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	amount, err := ParseAmount("1000000.250000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := amount.Shift(6).String(); got != "1.00000025" {
+		t.Errorf("String() = %q, want 1.00000025", got)
+	}
+
+	if _, err := ParseAmount("not a number"); err == nil {
+		t.Error("ParseAmount() of an invalid amount didn't fail")
+	}
+}
+
+func TestAmountFloat64(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount Amount
+		want   float64
+	}{
+		{"nil", Amount{}, 0},
+		{"shifted", IntAmount(sdk.NewInt(1234567)).Shift(6), 1.234567},
+		{"negative", IntAmount(sdk.NewInt(-5)).Shift(1), -0.5},
+		// the float64 closest to the decimal, not the result of a division
+		{"rounded correctly", IntAmount(sdk.NewInt(3)).Shift(1), 0.3},
+		{"18 decimals", IntAmount(sdk.NewIntFromBigInt(mustBigInt(t, "1000000000000000001"))).Shift(18), 1.000000000000000001},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.amount.Float64(); got != test.want {
+				t.Errorf("Float64() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDenomExponent(t *testing.T) {
+	tests := []struct {
+		coefficient float64
+		exponent    int
+		ok          bool
+	}{
+		{1, 0, true},
+		{10, 1, true},
+		{1000000, 6, true},
+		{1e18, 18, true},
+		{0.1, -1, false},
+		{2, 0, false},
+		{1500, 3, false},
+	}
+
+	for _, test := range tests {
+		exponent, ok := DenomExponent(test.coefficient)
+		if ok != test.ok || (ok && exponent != test.exponent) {
+			t.Errorf("DenomExponent(%v) = %d, %t, want %d, %t", test.coefficient, exponent, ok, test.exponent, test.ok)
+		}
+	}
+}
+
+func TestAmountGaugeVecExact(t *testing.T) {
+	ExactAmounts = true
+	defer func() { ExactAmounts = false }()
+
+	vec := NewAmountGaugeVec(prometheus.GaugeOpts{Name: "balance", Help: "Balance"}, []string{"denom"})
+	vec.Set(prometheus.Labels{"denom": "atom"}, IntAmount(sdk.NewInt(1)).Shift(6))
+	vec.Set(prometheus.Labels{"denom": "atom"}, IntAmount(sdk.NewInt(1500000)).Shift(6))
+
+	// the exact series of the previous amount is replaced, not kept
+	want := `
+# HELP balance Balance
+# TYPE balance gauge
+balance{denom="atom"} 1.5
+# HELP balance_exact_info Balance, exactly, in the amount label
+# TYPE balance_exact_info gauge
+balance_exact_info{amount="1.5",denom="atom"} 1
+`
+	if err := testutil.CollectAndCompare(vec, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
+
+func mustBigInt(t *testing.T, value string) *big.Int {
+	t.Helper()
+
+	parsed, ok := new(big.Int).SetString(value, 10)
+	if !ok {
+		t.Fatalf("invalid big int %s", value)
+	}
+
+	return parsed
+}
@@ -0,0 +1,279 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// API serves the data of the params, validators, validator and wallet
+// collectors as JSON under /api/v1/, for status pages and bots that don't
+// read the Prometheus format. Requests are always queried live by the
+// collectors of the metric endpoints, from the same results as their metrics,
+// so they take the same chain, network and height parameters and fail the
+// same way.
+type API struct {
+	factories map[string]CollectorFactory
+}
+
+// APICollector is a collector the API serves.
+type APICollector interface {
+	Collector
+
+	// Query queries what CollectContext exports, as one of the Data types of
+	// APIResponse. It fails the live scrape of ctx the same way.
+	Query(ctx context.Context) interface{}
+}
+
+// NewAPI takes the factories of the params, validators, validator and wallet
+// endpoints, by endpoint name.
+func NewAPI(factories map[string]CollectorFactory) *API {
+	return &API{factories: factories}
+}
+
+// APIResponse is the body of every API response. Data is one of APIParams,
+// []APIValidator, APIValidatorDetails or APIWallet.
+type APIResponse struct {
+	ChainID string `json:"chain_id"`
+	// the block height the data was queried at
+	Height int64       `json:"height,omitempty"`
+	Data   interface{} `json:"data"`
+}
+
+// APIAmount is an exact token amount, scaled to the display denom like the
+// metrics, as a decimal string, since most JSON parsers read numbers as
+// float64s.
+type APIAmount struct {
+	Amount    Amount `json:"amount"`
+	Denom     string `json:"denom"`
+	BaseDenom string `json:"base_denom"`
+}
+
+func newAPIAmount(denom DenomUnit, amount Amount) APIAmount {
+	return APIAmount{
+		Amount:    amount,
+		Denom:     denom.Display,
+		BaseDenom: denom.Base,
+	}
+}
+
+// labels adds the denom labels of the amount to labels.
+func (a APIAmount) labels(labels prometheus.Labels) prometheus.Labels {
+	return mergeLabels(labels, prometheus.Labels{
+		"denom":      a.Denom,
+		"base_denom": a.BaseDenom,
+	})
+}
+
+// sortAmounts sorts amounts by denom.
+func sortAmounts(amounts []APIAmount) {
+	sort.Slice(amounts, func(i, j int) bool {
+		return amounts[i].Denom < amounts[j].Denom
+	})
+}
+
+type APIValidator struct {
+	Address string `json:"address"`
+	Moniker string `json:"moniker"`
+	// bonded, unbonding or unbonded
+	Status string `json:"status,omitempty"`
+	Jailed bool   `json:"jailed"`
+	// whether the validator is in the active set, by rank
+	Active            *bool      `json:"active,omitempty"`
+	Rank              int64      `json:"rank,omitempty"`
+	Tokens            *APIAmount `json:"tokens,omitempty"`
+	DelegatorShares   *APIAmount `json:"delegator_shares,omitempty"`
+	MinSelfDelegation *APIAmount `json:"min_self_delegation,omitempty"`
+	CommissionRate    *float64   `json:"commission_rate,omitempty"`
+	MissedBlocks      *int64     `json:"missed_blocks,omitempty"`
+}
+
+type APIValidatorDetails struct {
+	APIValidator
+
+	Commission    []APIAmount       `json:"commission"`
+	Rewards       []APIAmount       `json:"rewards"`
+	Delegations   []APIDelegation   `json:"delegations"`
+	Unbondings    []APIDelegation   `json:"unbondings"`
+	Redelegations []APIRedelegation `json:"redelegations"`
+}
+
+// APIDelegation is a delegation, unbonding or reward between a delegator and
+// a validator. The side the response is about is left out.
+type APIDelegation struct {
+	Delegator string `json:"delegator,omitempty"`
+	Validator string `json:"validator,omitempty"`
+	APIAmount
+}
+
+// sortDelegations sorts delegations by delegator, then validator.
+func sortDelegations(delegations []APIDelegation) {
+	sort.Slice(delegations, func(i, j int) bool {
+		if delegations[i].Delegator != delegations[j].Delegator {
+			return delegations[i].Delegator < delegations[j].Delegator
+		}
+
+		return delegations[i].Validator < delegations[j].Validator
+	})
+}
+
+type APIRedelegation struct {
+	Delegator   string `json:"delegator,omitempty"`
+	Source      string `json:"source,omitempty"`
+	Destination string `json:"destination"`
+	APIAmount
+}
+
+// sortRedelegations sorts redelegations by delegator, then source, then
+// destination.
+func sortRedelegations(redelegations []APIRedelegation) {
+	sort.Slice(redelegations, func(i, j int) bool {
+		if redelegations[i].Delegator != redelegations[j].Delegator {
+			return redelegations[i].Delegator < redelegations[j].Delegator
+		}
+
+		if redelegations[i].Source != redelegations[j].Source {
+			return redelegations[i].Source < redelegations[j].Source
+		}
+
+		return redelegations[i].Destination < redelegations[j].Destination
+	})
+}
+
+type APIWallet struct {
+	Address       string            `json:"address"`
+	Balances      []APIAmount       `json:"balances"`
+	Delegations   []APIDelegation   `json:"delegations"`
+	Unbondings    []APIDelegation   `json:"unbondings"`
+	Redelegations []APIRedelegation `json:"redelegations"`
+	Rewards       []APIDelegation   `json:"rewards"`
+}
+
+// APIParams are the chain params, durations in seconds. Params whose query
+// failed are left out.
+type APIParams struct {
+	MaxValidators           *int64   `json:"max_validators,omitempty"`
+	UnbondingTime           *float64 `json:"unbonding_time,omitempty"`
+	BlocksPerYear           *int64   `json:"blocks_per_year,omitempty"`
+	GoalBonded              *float64 `json:"goal_bonded,omitempty"`
+	InflationMin            *float64 `json:"inflation_min,omitempty"`
+	InflationMax            *float64 `json:"inflation_max,omitempty"`
+	InflationRateChange     *float64 `json:"inflation_rate_change,omitempty"`
+	DowntimeJailDuration    *float64 `json:"downtime_jail_duration,omitempty"`
+	MinSignedPerWindow      *float64 `json:"min_signed_per_window,omitempty"`
+	SignedBlocksWindow      *int64   `json:"signed_blocks_window,omitempty"`
+	SlashFractionDoubleSign *float64 `json:"slash_fraction_double_sign,omitempty"`
+	SlashFractionDowntime   *float64 `json:"slash_fraction_downtime,omitempty"`
+	BaseProposerReward      *float64 `json:"base_proposer_reward,omitempty"`
+	BonusProposerReward     *float64 `json:"bonus_proposer_reward,omitempty"`
+	CommunityTax            *float64 `json:"community_tax,omitempty"`
+}
+
+func (a *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	requestStart := time.Now()
+
+	query := r.URL.Query()
+
+	name, address := apiEndpoint(r.URL.Path)
+	if address != "" {
+		query.Set("address", address)
+	}
+
+	factory, ok := a.factories[name]
+	if !ok {
+		writeError(w, r, &RequestError{
+			StatusCode: http.StatusNotFound,
+			Err:        fmt.Errorf("unknown API path %s", r.URL.Path),
+		})
+		return
+	}
+
+	ctx, cancel := scrapeContext(r)
+	defer cancel()
+
+	ctx, status := withScrapeStatus(ctx)
+
+	height, historical, err := parseHeight(query)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if historical {
+		ctx = withHeight(ctx, height)
+	}
+
+	collector, err := factory(query)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	apiCollector, ok := collector.(APICollector)
+	if !ok {
+		writeError(w, r, &RequestError{
+			StatusCode: http.StatusInternalServerError,
+			Err:        fmt.Errorf("the %s collector can't be served by the API", collector.Name()),
+		})
+		return
+	}
+
+	ctx = withHeightRecorder(ctx)
+	data := querySafely(ctx, apiCollector)
+
+	if err := status.Err(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response := APIResponse{
+		ChainID: collector.Labels()["chain_id"],
+		Data:    data,
+	}
+	if height, ok := recordedHeight(ctx); ok {
+		response.Height = height
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Error().Err(err).Msg("Could not write API response")
+		return
+	}
+
+	log.Info().
+		Str("method", "GET").
+		Str("endpoint", r.URL.RequestURI()).
+		Float64("request-time", time.Since(requestStart).Seconds()).
+		Msg("Request processed")
+}
+
+// apiEndpoint returns the name of the endpoint an API path mirrors, and the
+// address in the path, if any: /api/v1/validator/{address},
+// /api/v1/wallet/{address}, /api/v1/validators and /api/v1/params. The name
+// is empty for other paths.
+func apiEndpoint(path string) (name, address string) {
+	switch parts := strings.Split(strings.TrimPrefix(path, "/api/v1/"), "/"); {
+	case !strings.HasPrefix(path, "/api/v1/"):
+		return "", ""
+	case len(parts) == 2 && (parts[0] == "validator" || parts[0] == "wallet") && parts[1] != "":
+		return parts[0], parts[1]
+	case len(parts) == 1 && (parts[0] == "validators" || parts[0] == "params"):
+		return parts[0], ""
+	}
+
+	return "", ""
+}
+
+// querySafely is collectSafely for the API: a panic of the query fails the
+// live scrape of ctx with 500.
+func querySafely(ctx context.Context, collector APICollector) interface{} {
+	defer recoverCollection(ctx, collector)
+
+	return collector.Query(ctx)
+}
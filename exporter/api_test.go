@@ -0,0 +1,323 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	grpctypes "github.com/cosmos/cosmos-sdk/types/grpc"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc/metadata"
+)
+
+// fakeCollector sends the metrics of collect, as the collectors of a chain
+// with the chain ID test-1 would.
+type fakeCollector struct {
+	name    string
+	collect func(ctx context.Context, ch chan<- prometheus.Metric)
+}
+
+func (c *fakeCollector) Name() string {
+	return c.name
+}
+
+func (c *fakeCollector) Labels() prometheus.Labels {
+	return prometheus.Labels{"chain_id": "test-1"}
+}
+
+func (c *fakeCollector) Describe(ch chan<- *prometheus.Desc) {}
+
+func (c *fakeCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+func (c *fakeCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	if height, ok := requestedHeight(ctx); ok {
+		ch <- prometheus.MustNewConstMetric(queryHeightDesc, prometheus.GaugeValue, float64(height), "test-1", c.name)
+	}
+
+	c.collect(ctx, ch)
+}
+
+// fakeAPICollector returns the result of query, at the requested height like
+// the collectors do.
+type fakeAPICollector struct {
+	fakeCollector
+	query func(ctx context.Context) interface{}
+}
+
+func (c *fakeAPICollector) Query(ctx context.Context) interface{} {
+	if height, ok := requestedHeight(ctx); ok {
+		recordHeight(ctx, metadata.Pairs(grpctypes.GRPCBlockHeightHeader, strconv.FormatInt(height, 10)))
+	}
+
+	return c.query(ctx)
+}
+
+// sendGauge sends a gauge of name with the labels of pairs.
+func sendGauge(ch chan<- prometheus.Metric, name string, value float64, pairs ...string) {
+	var labelNames, labelValues []string
+	for i := 0; i < len(pairs); i += 2 {
+		labelNames = append(labelNames, pairs[i])
+		labelValues = append(labelValues, pairs[i+1])
+	}
+
+	desc := prometheus.NewDesc(name, name, labelNames, prometheus.Labels{"chain_id": "test-1"})
+	ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, labelValues...)
+}
+
+// atoms is an amount of uatom in atoms.
+func atoms(uatoms int64) Amount {
+	return IntAmount(sdk.NewInt(uatoms)).Shift(6)
+}
+
+func testAPI() *API {
+	float := func(value float64) *float64 { return &value }
+	integer := func(value int64) *int64 { return &value }
+	boolean := func(value bool) *bool { return &value }
+	amount := func(amount Amount) *APIAmount {
+		apiAmount := newAPIAmount(DenomUnit{Display: "atom", Base: "uatom"}, amount)
+		return &apiAmount
+	}
+
+	return NewAPI(map[string]CollectorFactory{
+		"params": StaticCollector(&fakeAPICollector{fakeCollector: fakeCollector{name: "params"}, query: func(ctx context.Context) interface{} {
+			return APIParams{
+				MaxValidators: integer(175),
+				UnbondingTime: float(1814400),
+				CommunityTax:  float(0.02),
+			}
+		}}),
+		"validators": StaticCollector(&fakeAPICollector{fakeCollector: fakeCollector{name: "validators"}, query: func(ctx context.Context) interface{} {
+			return []APIValidator{
+				{Address: "cosmosvaloper1a", Moniker: "Alpha", Status: "bonded", Active: boolean(true), Rank: 1, Tokens: amount(atoms(1234567890123)), CommissionRate: float(0.05)},
+				{Address: "cosmosvaloper1b", Moniker: "Bravo", Status: "unbonded", Active: boolean(false), Rank: 2, Tokens: amount(atoms(1000000)), CommissionRate: float(0.05)},
+			}
+		}}),
+		"wallet": func(query url.Values) (Collector, error) {
+			address := query.Get("address")
+			if address == "invalid" {
+				return nil, BadRequest("invalid address %s", address)
+			}
+
+			return &fakeAPICollector{fakeCollector: fakeCollector{name: "wallet"}, query: func(ctx context.Context) interface{} {
+				return APIWallet{
+					Address: address,
+					Balances: []APIAmount{
+						// more digits than a float64 has
+						*amount(IntAmount(sdk.NewInt(123456789012345678)).Shift(6)),
+						newAPIAmount(DenomUnit{Display: "ibc/27394FB0", Base: "ibc/27394FB0"}, IntAmount(sdk.NewInt(5))),
+					},
+					Delegations: []APIDelegation{
+						{Validator: "cosmosvaloper1a", APIAmount: *amount(atoms(1500000))},
+						{Validator: "cosmosvaloper1b", APIAmount: *amount(atoms(2000000))},
+					},
+					Unbondings:    []APIDelegation{},
+					Redelegations: []APIRedelegation{},
+					Rewards: []APIDelegation{
+						{Validator: "cosmosvaloper1a", APIAmount: *amount(atoms(42))},
+					},
+				}
+			}}, nil
+		},
+		"validator": StaticCollector(&fakeAPICollector{fakeCollector: fakeCollector{name: "validator"}, query: func(ctx context.Context) interface{} {
+			failScrape(ctx, &RequestError{StatusCode: http.StatusBadGateway, Err: errors.New("node is down")})
+			return APIValidatorDetails{APIValidator: APIValidator{Address: "cosmosvaloper1a"}}
+		}}),
+	})
+}
+
+func TestAPI(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		want       string
+	}{
+		{
+			name:       "params",
+			path:       "/api/v1/params",
+			wantStatus: http.StatusOK,
+			want:       `{"chain_id": "test-1", "data": {"max_validators": 175, "unbonding_time": 1814400, "community_tax": 0.02}}`,
+		},
+		{
+			name:       "validators by rank",
+			path:       "/api/v1/validators",
+			wantStatus: http.StatusOK,
+			want: `{"chain_id": "test-1", "data": [
+				{"address": "cosmosvaloper1a", "moniker": "Alpha", "status": "bonded", "jailed": false, "active": true, "rank": 1,
+				 "tokens": {"amount": "1234567.890123", "denom": "atom", "base_denom": "uatom"}, "commission_rate": 0.05},
+				{"address": "cosmosvaloper1b", "moniker": "Bravo", "status": "unbonded", "jailed": false, "active": false, "rank": 2,
+				 "tokens": {"amount": "1", "denom": "atom", "base_denom": "uatom"}, "commission_rate": 0.05}
+			]}`,
+		},
+		{
+			name:       "wallet at a height",
+			path:       "/api/v1/wallet/cosmos1abc?height=100",
+			wantStatus: http.StatusOK,
+			want: `{"chain_id": "test-1", "height": 100, "data": {
+				"address": "cosmos1abc",
+				"balances": [
+					{"amount": "123456789012.345678", "denom": "atom", "base_denom": "uatom"},
+					{"amount": "5", "denom": "ibc/27394FB0", "base_denom": "ibc/27394FB0"}
+				],
+				"delegations": [
+					{"validator": "cosmosvaloper1a", "amount": "1.5", "denom": "atom", "base_denom": "uatom"},
+					{"validator": "cosmosvaloper1b", "amount": "2", "denom": "atom", "base_denom": "uatom"}
+				],
+				"unbondings": [],
+				"redelegations": [],
+				"rewards": [{"validator": "cosmosvaloper1a", "amount": "0.000042", "denom": "atom", "base_denom": "uatom"}]
+			}}`,
+		},
+		{name: "invalid parameter", path: "/api/v1/wallet/invalid", wantStatus: http.StatusBadRequest},
+		{name: "invalid height", path: "/api/v1/params?height=latest", wantStatus: http.StatusBadRequest},
+		{name: "failed scrape", path: "/api/v1/validator/cosmosvaloper1a", wantStatus: http.StatusBadGateway},
+		{name: "unknown path", path: "/api/v1/delegations", wantStatus: http.StatusNotFound},
+		{name: "no address", path: "/api/v1/wallet/", wantStatus: http.StatusNotFound},
+	}
+
+	api := testAPI()
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			api.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.path, nil))
+
+			if w.Code != test.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, test.wantStatus, w.Body)
+			}
+
+			if test.want == "" {
+				return
+			}
+
+			var got, want interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(test.want), &want); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("response = %s, want %s", w.Body, test.want)
+			}
+		})
+	}
+}
+
+func TestAPIResultMetrics(t *testing.T) {
+	atom := DenomUnit{Display: "atom", Base: "uatom"}
+	tokens := newAPIAmount(atom, atoms(1500000))
+	missedBlocks := int64(3)
+
+	tests := []struct {
+		name       string
+		collectors []prometheus.Collector
+		want       string
+	}{
+		{
+			name: "validator",
+			collectors: func() []prometheus.Collector {
+				metrics := newValidatorMetrics("test-1")
+				metrics.set(APIValidatorDetails{
+					APIValidator: APIValidator{
+						Address:         "cosmosvaloper1a",
+						Moniker:         "Alpha",
+						Status:          "bonded",
+						Tokens:          &tokens,
+						DelegatorShares: &tokens,
+						MissedBlocks:    &missedBlocks,
+					},
+					Delegations: []APIDelegation{
+						{Delegator: "cosmos1abc", APIAmount: newAPIAmount(atom, atoms(1500000))},
+					},
+					Redelegations: []APIRedelegation{
+						{Delegator: "cosmos1abc", Destination: "cosmosvaloper1b", APIAmount: newAPIAmount(atom, atoms(42))},
+					},
+				})
+				return metrics.collectors()
+			}(),
+			want: `
+# HELP cosmos_validator_delegations Delegations of the Cosmos-based blockchain validator
+# TYPE cosmos_validator_delegations gauge
+cosmos_validator_delegations{address="cosmosvaloper1a",base_denom="uatom",chain_id="test-1",delegated_by="cosmos1abc",denom="atom",moniker="Alpha"} 1.5
+# HELP cosmos_validator_missed_blocks Missed blocks of the Cosmos-based blockchain validator
+# TYPE cosmos_validator_missed_blocks gauge
+cosmos_validator_missed_blocks{address="cosmosvaloper1a",chain_id="test-1",moniker="Alpha"} 3
+# HELP cosmos_validator_redelegations Redelegations of the Cosmos-based blockchain validator
+# TYPE cosmos_validator_redelegations gauge
+cosmos_validator_redelegations{address="cosmosvaloper1a",base_denom="uatom",chain_id="test-1",denom="atom",moniker="Alpha",redelegated_by="cosmos1abc",redelegated_to="cosmosvaloper1b"} 4.2e-05
+# HELP cosmos_validator_status Status of the Cosmos-based blockchain validator
+# TYPE cosmos_validator_status gauge
+cosmos_validator_status{address="cosmosvaloper1a",chain_id="test-1",moniker="Alpha"} 3
+`,
+		},
+		{
+			name: "validator query failed",
+			collectors: func() []prometheus.Collector {
+				metrics := newValidatorMetrics("test-1")
+				metrics.set(APIValidatorDetails{APIValidator: APIValidator{Address: "cosmosvaloper1a"}})
+				return metrics.collectors()
+			}(),
+		},
+		{
+			name: "wallet",
+			collectors: func() []prometheus.Collector {
+				metrics := newWalletMetrics("test-1")
+				metrics.set(APIWallet{
+					Address:  "cosmos1abc",
+					Balances: []APIAmount{newAPIAmount(atom, atoms(2000000))},
+					Redelegations: []APIRedelegation{
+						{Source: "cosmosvaloper1a", Destination: "cosmosvaloper1b", APIAmount: newAPIAmount(atom, atoms(42))},
+					},
+					Rewards: []APIDelegation{
+						{Validator: "cosmosvaloper1a", APIAmount: newAPIAmount(atom, atoms(42))},
+					},
+				})
+				return metrics.collectors()
+			}(),
+			want: `
+# HELP cosmos_wallet_balance Balance of the Cosmos-based blockchain wallet
+# TYPE cosmos_wallet_balance gauge
+cosmos_wallet_balance{address="cosmos1abc",base_denom="uatom",chain_id="test-1",denom="atom"} 2
+# HELP cosmos_wallet_redelegations Redlegations of the Cosmos-based blockchain wallet
+# TYPE cosmos_wallet_redelegations gauge
+cosmos_wallet_redelegations{address="cosmos1abc",base_denom="uatom",chain_id="test-1",denom="atom",redelegated_from="cosmosvaloper1a",redelegated_to="cosmosvaloper1b"} 4.2e-05
+# HELP cosmos_wallet_rewards Rewards of the Cosmos-based blockchain wallet
+# TYPE cosmos_wallet_rewards gauge
+cosmos_wallet_rewards{address="cosmos1abc",base_denom="uatom",chain_id="test-1",denom="atom",validator_address="cosmosvaloper1a"} 4.2e-05
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			registry := prometheus.NewPedanticRegistry()
+			registry.MustRegister(test.collectors...)
+
+			names := []string{
+				"cosmos_validator_delegations",
+				"cosmos_validator_missed_blocks",
+				"cosmos_validator_redelegations",
+				"cosmos_validator_status",
+				"cosmos_wallet_balance",
+				"cosmos_wallet_redelegations",
+				"cosmos_wallet_rewards",
+			}
+			if err := testutil.GatherAndCompare(registry, strings.NewReader(test.want), names...); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,565 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+	querytypes "github.com/cosmos/cosmos-sdk/types/query"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/prometheus/client_golang/prometheus"
+	tmrpc "github.com/tendermint/tendermint/rpc/client/http"
+)
+
+// DefaultChainName is the name of the chain configured with the top-level
+// flags, served when a request has no chain parameter.
+const DefaultChainName = "default"
+
+const (
+	chainResolveTimeout = 10 * time.Second
+
+	// the chain ID and denom are retried with exponential backoff between
+	// these, so a node that isn't up yet isn't flooded with queries
+	chainRetryMinBackoff = time.Second
+	chainRetryMaxBackoff = time.Minute
+)
+
+var chainReadyGauge = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "cosmos_exporter_chain_ready",
+		Help: "1 if the chain ID and denom of the chain are resolved and its metrics are served, 0 if no",
+	},
+	[]string{"chain"},
+)
+
+func init() {
+	prometheus.MustRegister(chainReadyGauge)
+}
+
+// Chain is a single chain the exporter serves, with its own nodes, Bech32
+// prefixes and denom.
+type Chain struct {
+	Name     string
+	GRPC     *BackendPool
+	RPC      *BackendPool
+	Prefixes Bech32Prefixes
+
+	// the denom and coefficient of the bond denom set in the config, if
+	// any, and the units of other denoms
+	denom            string
+	denomCoefficient float64
+	denomOverrides   []denomOverride
+
+	mutex       sync.RWMutex
+	info        ChainInfo
+	ready       bool
+	lastCheck   time.Time
+	lastLatency time.Duration
+	lastError   error
+
+	done chan struct{}
+}
+
+// ChainInfo is what's resolved from the nodes of a chain: its chain ID and
+// the units amounts are reported in. It's resolved in the background and
+// refreshed when the chain ID of the nodes changes, so collectors take a copy
+// at the start of every scrape.
+type ChainInfo struct {
+	ChainID string
+	// Denom is the bond denom, which staking amounts without a denom of
+	// their own, like unbondings, are in
+	Denom  DenomUnit
+	Denoms DenomRegistry
+	Labels prometheus.Labels
+}
+
+// Amount converts an amount of the bond denom in base units to Denom,
+// exactly.
+func (i ChainInfo) Amount(amount sdk.Int) Amount {
+	return IntAmount(amount).Shift(i.Denom.Exponent)
+}
+
+// DecAmount converts a decimal amount of the bond denom, like delegator
+// shares, to Denom, exactly.
+func (i ChainInfo) DecAmount(amount sdk.Dec) Amount {
+	return DecAmount(amount).Shift(i.Denom.Exponent)
+}
+
+// Coin converts a coin to the unit of its own denom.
+func (i ChainInfo) Coin(coin sdk.Coin) (DenomUnit, Amount) {
+	denom := i.Denoms.Lookup(coin.Denom)
+	return denom, IntAmount(coin.Amount).Shift(denom.Exponent)
+}
+
+// DecCoin converts a decimal coin, like rewards, to the unit of its own
+// denom.
+func (i ChainInfo) DecCoin(coin sdk.DecCoin) (DenomUnit, Amount) {
+	denom := i.Denoms.Lookup(coin.Denom)
+	return denom, DecAmount(coin.Amount).Shift(denom.Exponent)
+}
+
+type Bech32Prefixes struct {
+	Account             string
+	AccountPubkey       string
+	Validator           string
+	ValidatorPubkey     string
+	ConsensusNode       string
+	ConsensusNodePubkey string
+}
+
+// ChainConfig is a chain in the chains object of the config file. The keys
+// match the top-level flags, and the connection settings of the gRPC nodes
+// those of optional-network-settings.
+type ChainConfig struct {
+	Node             []string `mapstructure:"node"`
+	TendermintRPC    []string `mapstructure:"tendermint-rpc"`
+	Denom            string   `mapstructure:"denom"`
+	DenomCoefficient float64  `mapstructure:"denom-coefficient"`
+	Denoms           []string `mapstructure:"denoms"`
+
+	BechPrefix                    string `mapstructure:"bech-prefix"`
+	BechAccountPrefix             string `mapstructure:"bech-account-prefix"`
+	BechAccountPubkeyPrefix       string `mapstructure:"bech-account-pubkey-prefix"`
+	BechValidatorPrefix           string `mapstructure:"bech-validator-prefix"`
+	BechValidatorPubkeyPrefix     string `mapstructure:"bech-validator-pubkey-prefix"`
+	BechConsensusNodePrefix       string `mapstructure:"bech-consensus-node-prefix"`
+	BechConsensusNodePubkeyPrefix string `mapstructure:"bech-consensus-node-pubkey-prefix"`
+
+	NodeSettings `mapstructure:",squash"`
+}
+
+func (c ChainConfig) Prefixes() Bech32Prefixes {
+	orDefault := func(value, suffix string) string {
+		if value != "" {
+			return value
+		}
+		return c.BechPrefix + suffix
+	}
+
+	return Bech32Prefixes{
+		Account:             orDefault(c.BechAccountPrefix, ""),
+		AccountPubkey:       orDefault(c.BechAccountPubkeyPrefix, "pub"),
+		Validator:           orDefault(c.BechValidatorPrefix, "valoper"),
+		ValidatorPubkey:     orDefault(c.BechValidatorPubkeyPrefix, "valoperpub"),
+		ConsensusNode:       orDefault(c.BechConsensusNodePrefix, "valcons"),
+		ConsensusNodePubkey: orDefault(c.BechConsensusNodePubkeyPrefix, "valconspub"),
+	}
+}
+
+// NewChain connects to the nodes of the chain and starts resolving its chain
+// ID and denom. The nodes don't have to be up yet: until both are resolved
+// the chain isn't ready and its collectors skip scrapes.
+func NewChain(name string, config ChainConfig, prefixes Bech32Prefixes) (*Chain, error) {
+	denomOverrides, err := parseDenomOverrides(config.Denoms)
+	if err != nil {
+		return nil, err
+	}
+
+	grpcPool, err := NewGRPCPool(PoolOwner{Chain: name}, config.Node, config.NodeSettings)
+	if err != nil {
+		return nil, err
+	}
+
+	rpcPool, err := NewRPCPool(PoolOwner{Chain: name}, config.TendermintRPC)
+	if err != nil {
+		return nil, err
+	}
+
+	grpcPool.Start(HealthCheckInterval)
+	rpcPool.Start(HealthCheckInterval)
+
+	chain := &Chain{
+		Name:             name,
+		GRPC:             grpcPool,
+		RPC:              rpcPool,
+		Prefixes:         prefixes,
+		denom:            config.Denom,
+		denomCoefficient: config.DenomCoefficient,
+		denomOverrides:   denomOverrides,
+		done:             make(chan struct{}),
+	}
+
+	chainReadyGauge.WithLabelValues(name).Set(0)
+	chain.Start(HealthCheckInterval)
+
+	return chain, nil
+}
+
+// Info returns the resolved chain ID and denom, and whether they're resolved
+// yet.
+func (c *Chain) Info() (ChainInfo, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.info, c.ready
+}
+
+// ScrapeInfo is Info for a collection: if the chain isn't ready yet, the live
+// scrape of ctx fails with 503.
+func (c *Chain) ScrapeInfo(ctx context.Context) (ChainInfo, bool) {
+	info, ready := c.Info()
+	if !ready {
+		failScrape(ctx, &RequestError{
+			StatusCode: http.StatusServiceUnavailable,
+			Err:        fmt.Errorf("chain %s is not ready yet, its chain ID and denom are not resolved", c.Name),
+		})
+	}
+
+	return info, ready
+}
+
+// Ready returns whether the chain ID and denom are resolved.
+func (c *Chain) Ready() bool {
+	_, ready := c.Info()
+	return ready
+}
+
+// Close stops refreshing the chain ID and closes the connections to the nodes.
+func (c *Chain) Close() {
+	close(c.done)
+	c.GRPC.Close()
+	c.RPC.Close()
+}
+
+// LastCheck returns when the latest attempt to resolve or refresh the chain ID
+// and denom was made, how long it took and its error, if it failed.
+func (c *Chain) LastCheck() (time.Time, time.Duration, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.lastCheck, c.lastLatency, c.lastError
+}
+
+// Labels are the labels every metric of the chain has, i.e. its chain_id,
+// empty until it's ready.
+func (c *Chain) Labels() prometheus.Labels {
+	info, _ := c.Info()
+	return info.Labels
+}
+
+// Start tries to resolve the chain ID and denom once, so a chain whose nodes
+// are up is ready right away, and then keeps retrying with backoff until they
+// are resolved. After that, the chain ID is checked every interval, and the
+// denom is resolved again if it changed.
+func (c *Chain) Start(interval time.Duration) {
+	resolve := func() error {
+		checkStart := time.Now()
+		err := c.resolve()
+
+		c.mutex.Lock()
+		c.lastCheck = time.Now()
+		c.lastLatency = time.Since(checkStart)
+		c.lastError = err
+		c.mutex.Unlock()
+
+		return err
+	}
+
+	err := resolve()
+
+	go func() {
+		backoff := chainRetryMinBackoff
+
+		for {
+			wait := interval
+			if err == nil {
+				backoff = chainRetryMinBackoff
+			} else {
+				log.Warn().
+					Err(err).
+					Str("chain", c.Name).
+					Dur("retry-in", backoff).
+					Msg("Could not resolve chain ID and denom")
+				wait = backoff
+
+				backoff *= 2
+				if backoff > chainRetryMaxBackoff {
+					backoff = chainRetryMaxBackoff
+				}
+			}
+
+			select {
+			case <-time.After(wait):
+			case <-c.done:
+				return
+			}
+
+			err = resolve()
+		}
+	}()
+}
+
+func (c *Chain) resolve() error {
+	ctx, cancel := context.WithTimeout(context.Background(), chainResolveTimeout)
+	defer cancel()
+
+	chainID, err := c.queryChainID(ctx)
+	if err != nil {
+		return err
+	}
+
+	current, ready := c.Info()
+	if ready && current.ChainID == chainID {
+		return nil
+	}
+
+	denom, denoms, err := c.queryDenoms(ctx)
+	if err != nil {
+		return err
+	}
+
+	if ready {
+		log.Warn().
+			Str("chain", c.Name).
+			Str("from", current.ChainID).
+			Str("to", chainID).
+			Msg("Chain ID of the nodes changed, serving the new one")
+	}
+
+	c.mutex.Lock()
+	c.info = ChainInfo{
+		ChainID: chainID,
+		Denom:   denom,
+		Denoms:  denoms,
+		Labels: prometheus.Labels{
+			"chain_id": chainID,
+		},
+	}
+	c.ready = true
+	c.mutex.Unlock()
+
+	chainReadyGauge.WithLabelValues(c.Name).Set(1)
+	log.Info().
+		Str("chain", c.Name).
+		Str("chain-id", chainID).
+		Str("denom", denom.Display).
+		Str("base-denom", denom.Base).
+		Int("exponent", denom.Exponent).
+		Int("denoms", len(denoms)).
+		Msg("Chain is ready")
+
+	return nil
+}
+
+func (c *Chain) queryChainID(ctx context.Context) (string, error) {
+	client, err := tmrpc.New(c.RPC.Address(), "/websocket")
+	if err != nil {
+		return "", fmt.Errorf("could not create Tendermint client: %w", err)
+	}
+
+	status, err := client.Status(ctx)
+	if err != nil {
+		return "", fmt.Errorf("could not query Tendermint status: %w", err)
+	}
+
+	log.Debug().
+		Str("chain", c.Name).
+		Str("network", status.NodeInfo.Network).
+		Msg("Got network status from Tendermint")
+
+	return status.NodeInfo.Network, nil
+}
+
+// queryDenoms resolves the units of the bond denom and of every denom with
+// metadata. The denom and coefficient set in the config take precedence, and
+// if both are set, the chain doesn't need denom metadata at all, which can be
+// useful for networks like osmosis.
+func (c *Chain) queryDenoms(ctx context.Context) (DenomUnit, DenomRegistry, error) {
+	stakingClient := stakingtypes.NewQueryClient(c.GRPC)
+	params, err := stakingClient.Params(ctx, &stakingtypes.QueryParamsRequest{})
+	if err != nil {
+		return DenomUnit{}, nil, fmt.Errorf("error querying bond denom: %w", err)
+	}
+	bondDenom := params.Params.BondDenom
+
+	bankClient := banktypes.NewQueryClient(c.GRPC)
+	var metadatas []banktypes.Metadata
+	err = paginate(nil, "DenomsMetadata", func(pageRequest *querytypes.PageRequest) (*querytypes.PageResponse, error) {
+		response, err := bankClient.DenomsMetadata(
+			ctx,
+			&banktypes.QueryDenomsMetadataRequest{Pagination: pageRequest},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		metadatas = append(metadatas, response.Metadatas...)
+		return response.Pagination, nil
+	})
+
+	configured := c.denom != "" && c.denomCoefficient != 0
+	if err != nil {
+		if !configured {
+			return DenomUnit{}, nil, fmt.Errorf("error querying denom metadata: %w", err)
+		}
+
+		log.Warn().
+			Err(err).
+			Str("chain", c.Name).
+			Msg("Could not query denom metadata, reporting denoms other than the configured ones in base units")
+		metadatas = nil
+	}
+
+	for _, metadata := range metadatas {
+		log.Debug().
+			Str("chain", c.Name).
+			Str("base", metadata.Base).
+			Str("display", metadata.Display).
+			Int("units", len(metadata.DenomUnits)).
+			Msg("Denom metadata")
+	}
+
+	// --denom picks the unit of the bond denom, so it goes after --denoms
+	overrides := c.denomOverrides
+	if c.denom != "" {
+		bondOverride := denomOverride{
+			DenomUnit:    DenomUnit{Base: bondDenom, Display: c.denom},
+			fromMetadata: !configured,
+		}
+
+		if configured {
+			exponent, ok := DenomExponent(c.denomCoefficient)
+			if !ok {
+				return DenomUnit{}, nil, fmt.Errorf("denom coefficient %v is not a power of 10", c.denomCoefficient)
+			}
+			bondOverride.Exponent = exponent
+		}
+
+		overrides = append(append([]denomOverride{}, overrides...), bondOverride)
+	}
+
+	denoms, err := newDenomRegistry(metadatas, overrides)
+	if err != nil {
+		return DenomUnit{}, nil, err
+	}
+
+	if _, ok := denoms[bondDenom]; !ok {
+		log.Warn().
+			Str("chain", c.Name).
+			Str("denom", bondDenom).
+			Msg("No denom metadata of the bond denom, reporting it in base units, set --denom and --denom-coefficient to change it")
+	}
+
+	return denoms.Lookup(bondDenom), denoms, nil
+}
+
+// ParseAccAddress parses a wallet address with the account prefix of the
+// chain, rather than the global one of the SDK config.
+func (c *Chain) ParseAccAddress(address string) (sdk.AccAddress, error) {
+	bz, err := c.parseAddress(address, c.Prefixes.Account)
+	return sdk.AccAddress(bz), err
+}
+
+// ParseValAddress parses a validator operator address with the validator
+// prefix of the chain.
+func (c *Chain) ParseValAddress(address string) (sdk.ValAddress, error) {
+	bz, err := c.parseAddress(address, c.Prefixes.Validator)
+	return sdk.ValAddress(bz), err
+}
+
+func (c *Chain) parseAddress(address, prefix string) ([]byte, error) {
+	bz, err := sdk.GetFromBech32(address, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := sdk.VerifyAddressFormat(bz); err != nil {
+		return nil, err
+	}
+
+	return bz, nil
+}
+
+// ConsAddress encodes a consensus address with the consensus node prefix of
+// the chain.
+func (c *Chain) ConsAddress(address sdk.ConsAddress) (string, error) {
+	return bech32.ConvertAndEncode(c.Prefixes.ConsensusNode, address)
+}
+
+// Chains are all the chains the exporter serves.
+type Chains struct {
+	Default *Chain
+	byName  map[string]*Chain
+}
+
+func NewChains(defaultChain *Chain) *Chains {
+	return &Chains{
+		Default: defaultChain,
+		byName:  map[string]*Chain{defaultChain.Name: defaultChain},
+	}
+}
+
+func (c *Chains) Add(chain *Chain) error {
+	if _, ok := c.byName[chain.Name]; ok {
+		return fmt.Errorf("duplicate chain %s", chain.Name)
+	}
+
+	c.byName[chain.Name] = chain
+	return nil
+}
+
+// Get returns the chain with the given name or chain ID, or the default
+// chain if it's empty. Chains are only found by chain ID once they're ready,
+// and if several chains have the same chain ID, the first by name is returned.
+func (c *Chains) Get(name string) (*Chain, error) {
+	if name == "" {
+		return c.Default, nil
+	}
+
+	if chain, ok := c.byName[name]; ok {
+		return chain, nil
+	}
+
+	for _, chain := range c.All() {
+		if info, ready := chain.Info(); ready && info.ChainID == name {
+			return chain, nil
+		}
+	}
+
+	return nil, BadRequest("unknown chain %s", name)
+}
+
+// All returns the chains sorted by name.
+func (c *Chains) All() []*Chain {
+	chains := make([]*Chain, 0, len(c.byName))
+	for _, chain := range c.byName {
+		chains = append(chains, chain)
+	}
+
+	sort.Slice(chains, func(i, j int) bool {
+		return chains[i].Name < chains[j].Name
+	})
+
+	return chains
+}
+
+// ChainCollector builds a collector for every chain once, and serves the one
+// of the chain selected by the chain parameter of the request.
+func ChainCollector(chains *Chains, build func(chain *Chain) (Collector, error)) (CollectorFactory, error) {
+	collectors := make(map[*Chain]Collector)
+
+	for _, chain := range chains.All() {
+		collector, err := build(chain)
+		if err != nil {
+			return nil, fmt.Errorf("chain %s: %w", chain.Name, err)
+		}
+
+		collectors[chain] = collector
+	}
+
+	return func(query url.Values) (Collector, error) {
+		chain, err := chains.Get(query.Get("chain"))
+		if err != nil {
+			return nil, err
+		}
+
+		return collectors[chain], nil
+	}, nil
+}
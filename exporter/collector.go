@@ -0,0 +1,211 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector is implemented by every domain collector of the exporter. Every
+// Collect call queries the node and emits fresh metrics, so one collector can
+// be served from its own endpoint, combined with others into a single
+// registry or registered in any other prometheus.Registerer.
+type Collector interface {
+	prometheus.Collector
+
+	// Name is the name of the collector's endpoint, e.g. "validators" for
+	// /metrics/validators.
+	Name() string
+
+	// Labels are the labels of the chain every metric of the collector has,
+	// i.e. its chain_id. They're empty until the chain is ready, and can
+	// change if the chain ID of its nodes does, so they're variable labels
+	// of the metrics, and the descriptors stay the same.
+	Labels() prometheus.Labels
+
+	// CollectContext is Collect with every query bounded by ctx. Queries that
+	// don't finish in time are left out, and the metrics of the ones that
+	// did are still sent. Collect itself uses the default --scrape-timeout.
+	CollectContext(ctx context.Context, ch chan<- prometheus.Metric)
+}
+
+// CollectorFactory builds a collector for the parameters of a scrape request,
+// returning an error if they are invalid.
+type CollectorFactory func(query url.Values) (Collector, error)
+
+// StaticCollector returns a factory that always serves the same collector,
+// for collectors that don't take any request parameters.
+func StaticCollector(collector Collector) CollectorFactory {
+	return func(query url.Values) (Collector, error) {
+		return collector, nil
+	}
+}
+
+// Collectors keep their gauges in a per-domain metrics struct (e.g.
+// validatorsMetrics). Every Collect call creates a fresh one for the current
+// chain ID, so concurrent scrapes of the same collector never share values.
+// The descriptors don't depend on the chain ID, so they're only built once.
+
+// describeOnce returns the Describe of the metrics built by newMetrics, along
+// with the exporter's own metrics. The descriptors are built on the first
+// call, after the flags they depend on, like --exact-amounts, are parsed.
+func describeOnce(newMetrics func() []prometheus.Collector) func(ch chan<- *prometheus.Desc) {
+	var once sync.Once
+	var descs []*prometheus.Desc
+
+	return func(ch chan<- *prometheus.Desc) {
+		once.Do(func() {
+			for _, collector := range newMetrics() {
+				descs = append(descs, describe(collector)...)
+			}
+
+			descs = append(descs, upDesc, scrapeSuccessDesc, queryTimeoutDesc, queryPagesDesc, queryHeightDesc)
+		})
+
+		for _, desc := range descs {
+			ch <- desc
+		}
+	}
+}
+
+// describe returns the descriptors collector sends, in order.
+func describe(collector prometheus.Collector) []*prometheus.Desc {
+	ch := make(chan *prometheus.Desc)
+	go func() {
+		defer close(ch)
+		collector.Describe(ch)
+	}()
+
+	var descs []*prometheus.Desc
+	for desc := range ch {
+		descs = append(descs, desc)
+	}
+
+	return descs
+}
+
+// Metrics of a chain have chain_id as a variable label rather than a constant
+// one, so their descriptors are the same for every chain ID.
+
+func newChainGauge(opts prometheus.GaugeOpts, chainID string) prometheus.Gauge {
+	return prometheus.NewGaugeVec(opts, []string{"chain_id"}).WithLabelValues(chainID)
+}
+
+func newChainGaugeVec(opts prometheus.GaugeOpts, chainID string, labelNames []string) *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(opts, append([]string{"chain_id"}, labelNames...)).
+		MustCurryWith(prometheus.Labels{"chain_id": chainID})
+}
+
+func collectAll(ch chan<- prometheus.Metric, collectors []prometheus.Collector) {
+	for _, collector := range collectors {
+		collector.Collect(ch)
+	}
+}
+
+func collectWithTimeout(collector Collector, ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), ScrapeTimeout)
+	defer cancel()
+
+	collectSafely(ctx, collector, ch)
+}
+
+// collectSafely runs CollectContext, recovering from panics outside of the
+// query goroutines, which the observers of the collectors recover themselves.
+// The live scrape of ctx then fails with 500. Every collection records the
+// block height of its own queries.
+func collectSafely(ctx context.Context, collector Collector, ch chan<- prometheus.Metric) {
+	ctx = withHeightRecorder(ctx)
+
+	defer recoverCollection(ctx, collector)
+
+	collector.CollectContext(ctx, ch)
+}
+
+// recoverCollection recovers a panic of a collection by collector, failing
+// the live scrape of ctx with 500. It must be deferred.
+func recoverCollection(ctx context.Context, collector Collector) {
+	if r := recover(); r != nil {
+		logPanic(collector.Labels()["chain_id"], collector.Name(), "", r)
+		failScrape(ctx, &RequestError{
+			StatusCode: http.StatusInternalServerError,
+			Err:        fmt.Errorf("collecting %s failed: %v", collector.Name(), r),
+		})
+	}
+}
+
+// WithContext binds a collector to ctx, so registering the result in a
+// registry makes every Gather call use CollectContext with that context.
+func WithContext(ctx context.Context, collector Collector) prometheus.Collector {
+	return &contextCollector{ctx: ctx, collector: collector}
+}
+
+type contextCollector struct {
+	ctx       context.Context
+	collector Collector
+}
+
+func (c *contextCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.collector.Describe(ch)
+}
+
+func (c *contextCollector) Collect(ch chan<- prometheus.Metric) {
+	collectSafely(c.ctx, c.collector, ch)
+}
+
+// MultiCollector combines several collectors into one, collecting all of them
+// concurrently. It's used for the aggregate /metrics endpoint. A collector
+// that fails, like a target that isn't found or the gravity bridge contract
+// while the Ethereum node is down, doesn't fail the scrape of the others, and
+// only shows as down in its cosmos_exporter_up, like in background polls.
+type MultiCollector struct {
+	name       string
+	labels     func() prometheus.Labels
+	collectors []Collector
+}
+
+func NewMultiCollector(name string, labels func() prometheus.Labels, collectors ...Collector) *MultiCollector {
+	return &MultiCollector{
+		name:       name,
+		labels:     labels,
+		collectors: collectors,
+	}
+}
+
+func (c *MultiCollector) Name() string {
+	return c.name
+}
+
+func (c *MultiCollector) Labels() prometheus.Labels {
+	return c.labels()
+}
+
+func (c *MultiCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, collector := range c.collectors {
+		collector.Describe(ch)
+	}
+}
+
+func (c *MultiCollector) Collect(ch chan<- prometheus.Metric) {
+	collectWithTimeout(c, ch)
+}
+
+func (c *MultiCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	var wg sync.WaitGroup
+
+	for _, collector := range c.collectors {
+		wg.Add(1)
+		go func(collector Collector) {
+			defer wg.Done()
+
+			// a scrape status of its own, which nothing reads
+			collectorCtx, _ := withScrapeStatus(ctx)
+			collectSafely(collectorCtx, collector, ch)
+		}(collector)
+	}
+
+	wg.Wait()
+}
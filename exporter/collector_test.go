@@ -0,0 +1,161 @@
+package exporter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMultiCollectorIsolatesFailures(t *testing.T) {
+	healthy := &fakeCollector{name: "validators", collect: func(ctx context.Context, ch chan<- prometheus.Metric) {
+		sendGauge(ch, "cosmos_validators_rank", 1, "address", "cosmosvaloper1abc")
+	}}
+
+	notFound := &fakeCollector{name: "validator", collect: func(ctx context.Context, ch chan<- prometheus.Metric) {
+		observer := NewScrapeObserver(ctx, "test-1", "validator")
+		observer.Fail(status.Error(codes.NotFound, "validator not found"))
+		observer.Finish(ch)
+	}}
+
+	panicking := &fakeCollector{name: "wallet", collect: func(ctx context.Context, ch chan<- prometheus.Metric) {
+		panic("malformed response")
+	}}
+
+	tests := []struct {
+		name       string
+		collector  Collector
+		wantStatus int
+		want       []string
+	}{
+		{
+			name:       "aggregate",
+			collector:  NewMultiCollector("all", healthy.Labels, healthy, NewNamedCollector("ours", notFound), panicking),
+			wantStatus: http.StatusOK,
+			want: []string{
+				`cosmos_validators_rank{address="cosmosvaloper1abc",chain_id="test-1"} 1`,
+				`cosmos_exporter_up{chain_id="test-1",endpoint="validator",name="ours"} 0`,
+			},
+		},
+		{
+			name:       "single target",
+			collector:  notFound,
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			endpoint := NewEndpoint(test.collector.Name(), 0, StaticCollector(test.collector))
+
+			w := httptest.NewRecorder()
+			endpoint.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+			if w.Code != test.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, test.wantStatus, w.Body)
+			}
+
+			for _, line := range test.want {
+				if !strings.Contains(w.Body.String(), line+"\n") {
+					t.Errorf("response doesn't have %s:\n%s", line, w.Body)
+				}
+			}
+		})
+	}
+}
+
+// describedCollector describes the metrics of a collector type, and collects
+// metrics built for a chain with them.
+type describedCollector struct {
+	describe func(ch chan<- *prometheus.Desc)
+	metrics  []prometheus.Collector
+}
+
+func (c describedCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.describe(ch)
+}
+
+func (c describedCollector) Collect(ch chan<- prometheus.Metric) {
+	collectAll(ch, c.metrics)
+}
+
+func TestCollectorDescriptorsDontDependOnChainID(t *testing.T) {
+	tests := []struct {
+		name     string
+		describe func(ch chan<- *prometheus.Desc)
+		metrics  func(chainID string) []prometheus.Collector
+	}{
+		{
+			name:     "params",
+			describe: (&ParamsCollector{}).Describe,
+			metrics: func(chainID string) []prometheus.Collector {
+				return newParamsMetrics(chainID).collectors()
+			},
+		},
+		{
+			name:     "status",
+			describe: (&StatusCollector{}).Describe,
+			metrics: func(chainID string) []prometheus.Collector {
+				return newStatusMetrics(chainID).collectors()
+			},
+		},
+		{
+			name:     "validators",
+			describe: (&ValidatorsCollector{}).Describe,
+			metrics: func(chainID string) []prometheus.Collector {
+				metrics := newValidatorsMetrics(chainID)
+				metrics.validatorsRankGauge.With(prometheus.Labels{"address": "cosmosvaloper1abc", "moniker": "ours"}).Set(1)
+				metrics.validatorsTokensGauge.Set(prometheus.Labels{
+					"address":    "cosmosvaloper1abc",
+					"moniker":    "ours",
+					"denom":      "atom",
+					"base_denom": "uatom",
+				}, atoms(1500000))
+				return metrics.collectors()
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// the same registration serves every chain ID, as the pedantic
+			// registry checks every collected metric against the descriptors
+			registry := prometheus.NewPedanticRegistry()
+			collector := &describedCollector{describe: test.describe}
+			registry.MustRegister(collector)
+
+			for _, chainID := range []string{"test-1", "test-2"} {
+				collector.metrics = test.metrics(chainID)
+
+				families, err := registry.Gather()
+				if err != nil {
+					t.Fatalf("Gather() for %s = %v", chainID, err)
+				}
+
+				for _, family := range families {
+					for _, metric := range family.GetMetric() {
+						if got := metricLabel(metric, "chain_id"); got != chainID {
+							t.Errorf("%s has chain_id %q, want %q", family.GetName(), got, chainID)
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func metricLabel(metric *dto.Metric, name string) string {
+	for _, pair := range metric.GetLabel() {
+		if pair.GetName() == name {
+			return pair.GetValue()
+		}
+	}
+
+	return ""
+}
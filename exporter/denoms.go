@@ -0,0 +1,141 @@
+package exporter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+// DenomUnit is the unit amounts of a base denom are reported in, e.g. atom for
+// uatom.
+type DenomUnit struct {
+	Base    string
+	Display string
+	// Exponent is the power of 10 that amounts in Base are divided by to get
+	// amounts in Display
+	Exponent int
+}
+
+// DenomRegistry maps the base denoms of a chain to the units they're
+// reported in. It's never modified once built, so it can be shared between
+// scrapes.
+type DenomRegistry map[string]DenomUnit
+
+// Lookup returns the unit of a base denom. Denoms without metadata, like
+// most IBC denoms, are reported in base units.
+func (r DenomRegistry) Lookup(base string) DenomUnit {
+	if denom, ok := r[base]; ok {
+		return denom
+	}
+
+	return DenomUnit{Base: base, Display: base}
+}
+
+// denomOverride is an entry of --denoms, base=display or
+// base=display:exponent. Without an exponent, display has to be a unit in the
+// metadata of base.
+type denomOverride struct {
+	DenomUnit
+	fromMetadata bool
+}
+
+// CheckDenomOverrides validates the entries of --denoms.
+func CheckDenomOverrides(entries []string) error {
+	_, err := parseDenomOverrides(entries)
+	return err
+}
+
+func parseDenomOverrides(entries []string) ([]denomOverride, error) {
+	overrides := make([]denomOverride, 0, len(entries))
+
+	for _, entry := range entries {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid denom %q, expected base=display or base=display:exponent", entry)
+		}
+
+		override := denomOverride{
+			DenomUnit:    DenomUnit{Base: parts[0], Display: parts[1]},
+			fromMetadata: true,
+		}
+
+		if i := strings.LastIndex(parts[1], ":"); i >= 0 {
+			exponent, err := strconv.ParseUint(parts[1][i+1:], 10, 8)
+			if err != nil || i == 0 {
+				return nil, fmt.Errorf("invalid denom %q, expected base=display or base=display:exponent", entry)
+			}
+
+			override.Display = parts[1][:i]
+			override.Exponent = int(exponent)
+			override.fromMetadata = false
+		}
+
+		overrides = append(overrides, override)
+	}
+
+	return overrides, nil
+}
+
+// newDenomRegistry reports every denom with metadata in its display unit,
+// unless it's overridden.
+func newDenomRegistry(metadatas []banktypes.Metadata, overrides []denomOverride) (DenomRegistry, error) {
+	registry := make(DenomRegistry)
+	byBase := make(map[string]banktypes.Metadata)
+
+	for _, metadata := range metadatas {
+		byBase[metadata.Base] = metadata
+
+		if denom, ok := metadataUnit(metadata, metadata.Display); ok {
+			registry[metadata.Base] = denom
+		}
+	}
+
+	for _, override := range overrides {
+		if !override.fromMetadata {
+			registry[override.Base] = override.DenomUnit
+			continue
+		}
+
+		metadata, ok := byBase[override.Base]
+		if !ok {
+			return nil, fmt.Errorf("no denom metadata of %s, set its exponent with %s=%s:<exponent>", override.Base, override.Base, override.Display)
+		}
+
+		denom, ok := metadataUnit(metadata, override.Display)
+		if !ok {
+			return nil, fmt.Errorf("denom metadata of %s has no unit %s", override.Base, override.Display)
+		}
+
+		registry[override.Base] = denom
+	}
+
+	return registry, nil
+}
+
+// metadataUnit finds the unit named display, or with display as an alias,
+// in the metadata of a denom.
+func metadataUnit(metadata banktypes.Metadata, display string) (DenomUnit, bool) {
+	for _, unit := range metadata.DenomUnits {
+		if unit.Denom == display || containsString(unit.Aliases, display) {
+			return DenomUnit{
+				Base:     metadata.Base,
+				Display:  display,
+				Exponent: int(unit.Exponent),
+			}, true
+		}
+	}
+
+	return DenomUnit{}, false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,149 @@
+package exporter
+
+import (
+	"reflect"
+	"testing"
+
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+func TestParseDenomOverrides(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		want    []denomOverride
+		wantErr bool
+	}{
+		{
+			name:    "from metadata",
+			entries: []string{"uatom=atom"},
+			want:    []denomOverride{{DenomUnit: DenomUnit{Base: "uatom", Display: "atom"}, fromMetadata: true}},
+		},
+		{
+			name:    "with exponent",
+			entries: []string{"ibc/27394FB0=osmo:6"},
+			want:    []denomOverride{{DenomUnit: DenomUnit{Base: "ibc/27394FB0", Display: "osmo", Exponent: 6}}},
+		},
+		{
+			name:    "zero exponent",
+			entries: []string{"ucudos=ucudos:0"},
+			want:    []denomOverride{{DenomUnit: DenomUnit{Base: "ucudos", Display: "ucudos"}}},
+		},
+		{
+			name:    "several",
+			entries: []string{"uatom=atom", "acudos=cudos:18"},
+			want: []denomOverride{
+				{DenomUnit: DenomUnit{Base: "uatom", Display: "atom"}, fromMetadata: true},
+				{DenomUnit: DenomUnit{Base: "acudos", Display: "cudos", Exponent: 18}},
+			},
+		},
+		{name: "empty", entries: nil, want: []denomOverride{}},
+		{name: "no display", entries: []string{"uatom"}, wantErr: true},
+		{name: "empty base", entries: []string{"=atom"}, wantErr: true},
+		{name: "empty display", entries: []string{"uatom="}, wantErr: true},
+		{name: "invalid exponent", entries: []string{"uatom=atom:x"}, wantErr: true},
+		{name: "negative exponent", entries: []string{"uatom=atom:-6"}, wantErr: true},
+		{name: "exponent too large", entries: []string{"uatom=atom:256"}, wantErr: true},
+		{name: "exponent only", entries: []string{"uatom=:6"}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseDenomOverrides(test.entries)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("parseDenomOverrides() error = %v, want error %t", err, test.wantErr)
+			}
+
+			if !test.wantErr && !reflect.DeepEqual(got, test.want) {
+				t.Errorf("parseDenomOverrides() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNewDenomRegistry(t *testing.T) {
+	metadatas := []banktypes.Metadata{
+		{
+			Base:    "uatom",
+			Display: "atom",
+			DenomUnits: []*banktypes.DenomUnit{
+				{Denom: "uatom", Exponent: 0},
+				{Denom: "matom", Exponent: 3, Aliases: []string{"milliatom"}},
+				{Denom: "atom", Exponent: 6},
+			},
+		},
+		{
+			Base:    "acudos",
+			Display: "cudos",
+			DenomUnits: []*banktypes.DenomUnit{
+				{Denom: "acudos", Exponent: 0},
+				{Denom: "cudos", Exponent: 18},
+			},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		overrides []string
+		lookups   map[string]DenomUnit
+		wantErr   bool
+	}{
+		{
+			name: "display units",
+			lookups: map[string]DenomUnit{
+				"uatom":  {Base: "uatom", Display: "atom", Exponent: 6},
+				"acudos": {Base: "acudos", Display: "cudos", Exponent: 18},
+			},
+		},
+		{
+			name:      "other unit of the metadata",
+			overrides: []string{"uatom=matom"},
+			lookups: map[string]DenomUnit{
+				"uatom": {Base: "uatom", Display: "matom", Exponent: 3},
+			},
+		},
+		{
+			name:      "alias",
+			overrides: []string{"uatom=milliatom"},
+			lookups: map[string]DenomUnit{
+				"uatom": {Base: "uatom", Display: "milliatom", Exponent: 3},
+			},
+		},
+		{
+			name:      "explicit exponent",
+			overrides: []string{"ibc/27394FB0=osmo:6", "acudos=acudos:0"},
+			lookups: map[string]DenomUnit{
+				"ibc/27394FB0": {Base: "ibc/27394FB0", Display: "osmo", Exponent: 6},
+				"acudos":       {Base: "acudos", Display: "acudos"},
+			},
+		},
+		{
+			name: "unknown denom in base units",
+			lookups: map[string]DenomUnit{
+				"ibc/ABCDEF": {Base: "ibc/ABCDEF", Display: "ibc/ABCDEF"},
+			},
+		},
+		{name: "override without metadata", overrides: []string{"uosmo=osmo"}, wantErr: true},
+		{name: "unit not in metadata", overrides: []string{"uatom=katom"}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			overrides, err := parseDenomOverrides(test.overrides)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			registry, err := newDenomRegistry(metadatas, overrides)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("newDenomRegistry() error = %v, want error %t", err, test.wantErr)
+			}
+
+			for base, want := range test.lookups {
+				if got := registry.Lookup(base); got != want {
+					t.Errorf("Lookup(%q) = %+v, want %+v", base, got, want)
+				}
+			}
+		})
+	}
+}
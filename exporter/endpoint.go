@@ -0,0 +1,436 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Endpoint serves a single /metrics/* path. Without a poll interval every
+// scrape queries the node directly, bounded by the scrape timeout; with one,
+// the metrics are fetched in the background and scrapes are served from the
+// latest snapshot.
+type Endpoint struct {
+	Name     string
+	Interval time.Duration
+	Factory  CollectorFactory
+
+	// Historical endpoints only make gRPC queries, so they take a height
+	// parameter to query the state at a past block. Those scrapes are
+	// always served live.
+	Historical bool
+
+	// Extra is gathered live on every scrape in addition to the collector,
+	// even when the collector is served from the background poller.
+	Extra prometheus.Gatherer
+
+	// Params are the query parameters the factory uses. Background pollers
+	// are keyed by them only, so other parameters don't start pollers of
+	// their own.
+	Params []string
+
+	mutex   sync.Mutex
+	pollers map[string]*Poller
+
+	registries liveRegistries
+}
+
+// maxEndpointPollers is how many background pollers an endpoint runs at most,
+// one per set of parameters. Scrapes with other parameters are served live.
+const maxEndpointPollers = 1000
+
+func NewEndpoint(name string, interval time.Duration, factory CollectorFactory, params ...string) *Endpoint {
+	return &Endpoint{
+		Name:     name,
+		Interval: interval,
+		Factory:  factory,
+		Params:   params,
+		pollers:  make(map[string]*Poller),
+	}
+}
+
+// RequestError is an error served as an HTTP error with its status code:
+// 400 for invalid request parameters, 404 for targets that don't exist, 502
+// for nodes that fail to answer and 503 for chains that aren't ready yet.
+type RequestError struct {
+	StatusCode int
+	Err        error
+}
+
+func (e *RequestError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+func BadRequest(format string, args ...interface{}) error {
+	return &RequestError{StatusCode: http.StatusBadRequest, Err: fmt.Errorf(format, args...)}
+}
+
+// scrapeStatus is the error that makes a live scrape fail as a whole, set by
+// the collectors through the context of the scrape. The first one wins.
+type scrapeStatus struct {
+	mutex sync.Mutex
+	err   *RequestError
+}
+
+type scrapeStatusKey struct{}
+
+func withScrapeStatus(ctx context.Context) (context.Context, *scrapeStatus) {
+	status := &scrapeStatus{}
+	return context.WithValue(ctx, scrapeStatusKey{}, status), status
+}
+
+// failScrape makes the live scrape of ctx fail with err. Background polls
+// have no scrape status, and the collectors of a MultiCollector each have
+// their own, so those are only reported by cosmos_exporter_up.
+func failScrape(ctx context.Context, err *RequestError) {
+	status, ok := ctx.Value(scrapeStatusKey{}).(*scrapeStatus)
+	if !ok {
+		return
+	}
+
+	status.mutex.Lock()
+	defer status.mutex.Unlock()
+
+	if status.err == nil {
+		status.err = err
+	}
+}
+
+func (s *scrapeStatus) Err() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.err == nil {
+		return nil
+	}
+
+	return s.err
+}
+
+func (e *Endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	requestStart := time.Now()
+
+	ctx, cancel := scrapeContext(r)
+	defer cancel()
+
+	ctx, status := withScrapeStatus(ctx)
+
+	query := r.URL.Query()
+	height, historical, err := parseHeight(query)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if historical {
+		if !e.Historical {
+			writeError(w, r, BadRequest("the %s endpoint doesn't support the height parameter", e.Name))
+			return
+		}
+
+		ctx = withHeight(ctx, height)
+	}
+
+	gatherer, err := e.gatherer(ctx, query)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if e.Extra != nil {
+		gatherer = prometheus.Gatherers{gatherer, e.Extra}
+	}
+
+	// gathered before writing anything, so a failed scrape is still
+	// answered with an error status
+	families, gatherErr := gatherer.Gather()
+	if err := status.Err(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	gathered := prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		return families, gatherErr
+	})
+
+	h := promhttp.HandlerFor(gathered, promhttp.HandlerOpts{})
+	h.ServeHTTP(w, r)
+	log.Info().
+		Str("method", "GET").
+		Str("endpoint", r.URL.RequestURI()).
+		Bool("cached", e.Interval > 0 && !historical).
+		Float64("request-time", time.Since(requestStart).Seconds()).
+		Msg("Request processed")
+}
+
+// writeError answers with the status code of a RequestError, or 500 for
+// other errors, and the error message as the body.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	statusCode := http.StatusInternalServerError
+
+	var requestError *RequestError
+	if errors.As(err, &requestError) {
+		statusCode = requestError.StatusCode
+	}
+
+	log.Warn().
+		Err(err).
+		Str("method", "GET").
+		Str("endpoint", r.URL.RequestURI()).
+		Int("status", statusCode).
+		Msg("Request failed")
+
+	http.Error(w, err.Error(), statusCode)
+}
+
+// scrapeContext returns the context the queries of a scrape are bound to. Its
+// deadline is the timeout Prometheus sends in X-Prometheus-Scrape-Timeout-Seconds
+// minus --scrape-timeout-offset, leaving time to write the response, or
+// --scrape-timeout for scrapers that don't send the header.
+func scrapeContext(r *http.Request) (context.Context, context.CancelFunc) {
+	timeout := ScrapeTimeout
+
+	if header := r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds"); header != "" {
+		seconds, err := strconv.ParseFloat(header, 64)
+		if err != nil || seconds <= 0 {
+			log.Warn().
+				Str("header", header).
+				Msg("Invalid scrape timeout header, using the default timeout")
+		} else {
+			timeout = time.Duration(seconds * float64(time.Second))
+			if timeout > ScrapeTimeoutOffset {
+				timeout -= ScrapeTimeoutOffset
+			}
+		}
+	}
+
+	return context.WithTimeout(r.Context(), timeout)
+}
+
+func newRegistry(collector prometheus.Collector) (*prometheus.Registry, error) {
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(collector); err != nil {
+		return nil, err
+	}
+
+	return registry, nil
+}
+
+// liveRegistries are the registries of the live scrapes of an endpoint. A
+// registry checks the descriptors of a collector when it's registered, so
+// instead of registering every scrape in a new one, the registries are
+// reused by the scrapes of collectors with the same descriptors, like the
+// validator collectors of different addresses.
+type liveRegistries struct {
+	mutex sync.Mutex
+	pools map[uint64]*sync.Pool
+}
+
+// liveRegistry is registered with a scrapeCollector once, which is bound to
+// the collector and context of a scrape for the time of its Gather.
+type liveRegistry struct {
+	registry *prometheus.Registry
+	scrape   *scrapeCollector
+}
+
+type scrapeCollector struct {
+	descs     []*prometheus.Desc
+	ctx       context.Context
+	collector Collector
+}
+
+func (c *scrapeCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range c.descs {
+		ch <- desc
+	}
+}
+
+func (c *scrapeCollector) Collect(ch chan<- prometheus.Metric) {
+	collectSafely(c.ctx, c.collector, ch)
+}
+
+func (r *liveRegistries) gather(ctx context.Context, collector Collector) ([]*dto.MetricFamily, error) {
+	descs := describe(collector)
+	pool := r.pool(descsKey(descs))
+
+	live, _ := pool.Get().(*liveRegistry)
+	if live == nil {
+		live = &liveRegistry{scrape: &scrapeCollector{descs: descs}}
+
+		var err error
+		live.registry, err = newRegistry(live.scrape)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	live.scrape.ctx, live.scrape.collector = ctx, collector
+	defer func() {
+		live.scrape.ctx, live.scrape.collector = nil, nil
+		pool.Put(live)
+	}()
+
+	return live.registry.Gather()
+}
+
+func (r *liveRegistries) pool(key uint64) *sync.Pool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.pools == nil {
+		r.pools = make(map[uint64]*sync.Pool)
+	}
+
+	pool, ok := r.pools[key]
+	if !ok {
+		pool = &sync.Pool{}
+		r.pools[key] = pool
+	}
+
+	return pool
+}
+
+// descsKey identifies a set of descriptors, in the order they're described.
+func descsKey(descs []*prometheus.Desc) uint64 {
+	hash := fnv.New64a()
+	for _, desc := range descs {
+		hash.Write([]byte(desc.String()))
+		hash.Write([]byte{0})
+	}
+
+	return hash.Sum64()
+}
+
+// gatherer returns the live registry of the request bound to ctx or, with a
+// poll interval, the poller of the request. Background polls don't belong to
+// any scrape, so they use the default --scrape-timeout. The state at a past
+// height doesn't change, so those requests never get a poller.
+func (e *Endpoint) gatherer(ctx context.Context, query url.Values) (prometheus.Gatherer, error) {
+	if _, historical := requestedHeight(ctx); e.Interval <= 0 || historical {
+		return e.live(ctx, query)
+	}
+
+	key := e.pollerKey(query)
+
+	e.mutex.Lock()
+	poller, ok := e.pollers[key]
+	e.mutex.Unlock()
+
+	if ok {
+		return e.wait(ctx, poller)
+	}
+
+	// requests with invalid parameters never start a poller
+	collector, err := e.Factory(query)
+	if err != nil {
+		return nil, err
+	}
+
+	registry, err := newRegistry(collector)
+	if err != nil {
+		return nil, err
+	}
+
+	e.mutex.Lock()
+	if poller, ok := e.pollers[key]; ok {
+		e.mutex.Unlock()
+		return e.wait(ctx, poller)
+	}
+
+	if len(e.pollers) >= maxEndpointPollers {
+		e.mutex.Unlock()
+
+		log.Warn().
+			Str("endpoint", e.Name).
+			Int("pollers", maxEndpointPollers).
+			Msg("Too many background pollers, serving the scrape live")
+
+		return e.liveGatherer(ctx, collector), nil
+	}
+
+	poller = NewPoller(e.Name, collector.Labels, e.Interval, registry)
+	e.pollers[key] = poller
+	e.mutex.Unlock()
+
+	// the first poll is done synchronously, so the very first scrape already
+	// gets data, but outside the lock, so a slow target doesn't hold up the
+	// scrapes with other parameters. Scrapes with the same ones wait for it.
+	if err := poller.First(); err != nil {
+		e.remove(key, poller)
+		return nil, err
+	}
+
+	go poller.Run(func() {
+		e.remove(key, poller)
+	})
+
+	return poller, nil
+}
+
+// live returns a gatherer that collects the request right away.
+func (e *Endpoint) live(ctx context.Context, query url.Values) (prometheus.Gatherer, error) {
+	collector, err := e.Factory(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.liveGatherer(ctx, collector), nil
+}
+
+func (e *Endpoint) liveGatherer(ctx context.Context, collector Collector) prometheus.Gatherer {
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		return e.registries.gather(ctx, collector)
+	})
+}
+
+// wait returns poller once its first poll is done.
+func (e *Endpoint) wait(ctx context.Context, poller *Poller) (prometheus.Gatherer, error) {
+	if err := poller.Wait(ctx); err != nil {
+		if ctx.Err() != nil {
+			return nil, &RequestError{
+				StatusCode: http.StatusServiceUnavailable,
+				Err:        fmt.Errorf("the first background poll of %s didn't finish in time", e.Name),
+			}
+		}
+
+		return nil, err
+	}
+
+	return poller, nil
+}
+
+// pollerKey identifies the poller of query by the parameters the factory
+// uses, in a fixed order, with their first value as the factory reads them.
+func (e *Endpoint) pollerKey(query url.Values) string {
+	key := url.Values{}
+	for _, param := range e.Params {
+		if value := query.Get(param); value != "" {
+			key.Set(param, value)
+		}
+	}
+
+	return key.Encode()
+}
+
+func (e *Endpoint) remove(key string, poller *Poller) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.pollers[key] == poller {
+		delete(e.pollers, key)
+	}
+}
@@ -0,0 +1,241 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestEndpointPollerKey(t *testing.T) {
+	endpoint := NewEndpoint("wallet", time.Minute, nil, "chain", "network", "address")
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", ""},
+		{"address=cosmos1abc", "address=cosmos1abc"},
+		{"chain=cudos&address=cudos1abc", "address=cudos1abc&chain=cudos"},
+		{"address=cudos1abc&chain=cudos", "address=cudos1abc&chain=cudos"},
+		// parameters the factory doesn't read don't start pollers of their own
+		{"address=cosmos1abc&cache_buster=123", "address=cosmos1abc"},
+		{"address=cosmos1abc&address=cosmos1def", "address=cosmos1abc"},
+		{"address=cosmos1abc&network=", "address=cosmos1abc"},
+	}
+
+	for _, test := range tests {
+		query, err := url.ParseQuery(test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := endpoint.pollerKey(query); got != test.want {
+			t.Errorf("pollerKey(%q) = %q, want %q", test.query, got, test.want)
+		}
+	}
+}
+
+// testPolledEndpoint returns an endpoint polled every hour, whose collector
+// waits for a value on the channel of the address parameter, if it has one,
+// and fails if it's "failing".
+func testPolledEndpoint(waits map[string]chan struct{}, factoryCalls *int) *Endpoint {
+	var mutex sync.Mutex
+	failDesc := prometheus.NewDesc("cosmos_validator_failed", "Failed", nil, nil)
+
+	return NewEndpoint("validator", time.Hour, func(query url.Values) (Collector, error) {
+		mutex.Lock()
+		*factoryCalls++
+		mutex.Unlock()
+
+		address := query.Get("address")
+		if address == "" {
+			return nil, BadRequest("address is required")
+		}
+
+		return &fakeCollector{name: "validator", collect: func(ctx context.Context, ch chan<- prometheus.Metric) {
+			if wait, ok := waits[address]; ok {
+				<-wait
+			}
+
+			if address == "failing" {
+				ch <- prometheus.NewInvalidMetric(failDesc, errors.New("node is down"))
+				return
+			}
+
+			sendGauge(ch, "cosmos_validator_tokens", 1, "address", address)
+		}}, nil
+	}, "address")
+}
+
+func TestEndpointGathererSlowFirstPoll(t *testing.T) {
+	slow := make(chan struct{})
+	factoryCalls := 0
+	endpoint := testPolledEndpoint(map[string]chan struct{}{"slow": slow}, &factoryCalls)
+
+	slowDone := make(chan error)
+	go func() {
+		_, err := endpoint.gatherer(context.Background(), url.Values{"address": {"slow"}})
+		slowDone <- err
+	}()
+
+	// wait for the slow poller to be added
+	for {
+		endpoint.mutex.Lock()
+		_, ok := endpoint.pollers["address=slow"]
+		endpoint.mutex.Unlock()
+
+		if ok {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// other parameters aren't held up by the first poll of the slow target
+	if _, err := endpoint.gatherer(context.Background(), url.Values{"address": {"fast"}}); err != nil {
+		t.Fatalf("gatherer() of another target = %v", err)
+	}
+
+	// the same ones wait for it, as long as their scrape lasts
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := endpoint.gatherer(ctx, url.Values{"address": {"slow"}, "cache_buster": {"1"}})
+	var requestErr *RequestError
+	if !errors.As(err, &requestErr) || requestErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("gatherer() while the first poll runs = %v, want a 503", err)
+	}
+
+	close(slow)
+	if err := <-slowDone; err != nil {
+		t.Fatalf("gatherer() of the slow target = %v", err)
+	}
+
+	gatherer, err := endpoint.gatherer(context.Background(), url.Values{"address": {"slow"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := gatherer.(*Poller); !ok {
+		t.Errorf("gatherer() after the first poll = %T, want the poller", gatherer)
+	}
+
+	// the slow and fast pollers
+	if factoryCalls != 2 {
+		t.Errorf("factory called %d times, want 2", factoryCalls)
+	}
+}
+
+func TestEndpointGathererFailedFirstPoll(t *testing.T) {
+	factoryCalls := 0
+	endpoint := testPolledEndpoint(nil, &factoryCalls)
+
+	for i := 0; i < 2; i++ {
+		if _, err := endpoint.gatherer(context.Background(), url.Values{"address": {"failing"}}); err == nil {
+			t.Fatal("gatherer() with a failing first poll didn't fail")
+		}
+	}
+
+	// the poller is removed, so the next scrape tries again
+	if factoryCalls != 2 || len(endpoint.pollers) != 0 {
+		t.Errorf("factory called %d times with %d pollers left, want 2 and 0", factoryCalls, len(endpoint.pollers))
+	}
+
+	// invalid parameters never start a poller
+	_, err := endpoint.gatherer(context.Background(), url.Values{})
+	var requestErr *RequestError
+	if !errors.As(err, &requestErr) || requestErr.StatusCode != http.StatusBadRequest || len(endpoint.pollers) != 0 {
+		t.Errorf("gatherer() without an address = %v with %d pollers, want a 400 and none", err, len(endpoint.pollers))
+	}
+}
+
+func TestEndpointGathererLive(t *testing.T) {
+	factoryCalls := 0
+	endpoint := testPolledEndpoint(nil, &factoryCalls)
+
+	// the state at a past height doesn't change, so it's never polled
+	gatherer, err := endpoint.gatherer(withHeight(context.Background(), 100), url.Values{"address": {"cosmosvaloper1abc"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := gatherer.(*Poller); ok || len(endpoint.pollers) != 0 {
+		t.Errorf("gatherer() at a height = %T with %d pollers, want a live gatherer", gatherer, len(endpoint.pollers))
+	}
+}
+
+// gaugeCollector is a checked collector of a single gauge.
+type gaugeCollector struct {
+	fakeCollector
+	desc  *prometheus.Desc
+	value float64
+}
+
+func (c *gaugeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *gaugeCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, c.value, "test-1")
+}
+
+func TestEndpointLiveRegistriesReused(t *testing.T) {
+	descs := map[string]*prometheus.Desc{
+		"rank":   prometheus.NewDesc("cosmos_validator_rank", "Rank", []string{"chain_id"}, nil),
+		"tokens": prometheus.NewDesc("cosmos_validator_tokens", "Tokens", []string{"chain_id"}, nil),
+	}
+
+	endpoint := NewEndpoint("validator", 0, func(query url.Values) (Collector, error) {
+		value, err := strconv.ParseFloat(query.Get("value"), 64)
+		if err != nil {
+			return nil, err
+		}
+
+		return &gaugeCollector{fakeCollector: fakeCollector{name: "validator"}, desc: descs[query.Get("metric")], value: value}, nil
+	}, "metric", "value")
+
+	// concurrent scrapes of collectors with the same descriptors each get
+	// a registry of their own, which later scrapes reuse
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		metric := "rank"
+		if i%2 == 1 {
+			metric = "tokens"
+		}
+
+		wg.Add(1)
+		go func(metric string, value float64) {
+			defer wg.Done()
+
+			gatherer, err := endpoint.gatherer(context.Background(), url.Values{
+				"metric": {metric},
+				"value":  {strconv.FormatFloat(value, 'f', -1, 64)},
+			})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			families, err := gatherer.Gather()
+			if err != nil {
+				t.Errorf("Gather() = %v", err)
+				return
+			}
+
+			if len(families) != 1 || families[0].GetName() != "cosmos_validator_"+metric || families[0].GetMetric()[0].GetGauge().GetValue() != value {
+				t.Errorf("Gather() of %s %v = %v, want only its own metric", metric, value, families)
+			}
+		}(metric, float64(i))
+	}
+	wg.Wait()
+
+	if got := len(endpoint.registries.pools); got != len(descs) {
+		t.Errorf("live scrapes used %d registry pools, want one per set of descriptors, %d", got, len(descs))
+	}
+}
@@ -0,0 +1,373 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	querytypes "github.com/cosmos/cosmos-sdk/types/query"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distributiontypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type GeneralCollector struct {
+	chain *Chain
+}
+
+func NewGeneralCollector(chain *Chain) *GeneralCollector {
+	return &GeneralCollector{
+		chain: chain,
+	}
+}
+
+func (c *GeneralCollector) Name() string {
+	return "general"
+}
+
+func (c *GeneralCollector) Labels() prometheus.Labels {
+	return c.chain.Labels()
+}
+
+var describeGeneralMetrics = describeOnce(func() []prometheus.Collector {
+	return newGeneralMetrics("").collectors()
+})
+
+func (c *GeneralCollector) Describe(ch chan<- *prometheus.Desc) {
+	describeGeneralMetrics(ch)
+}
+
+func (c *GeneralCollector) Collect(ch chan<- prometheus.Metric) {
+	collectWithTimeout(c, ch)
+}
+
+func (c *GeneralCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	sublogger := log.With().
+		Str("request-id", uuid.New().String()).
+		Logger()
+
+	info, ok := c.chain.ScrapeInfo(ctx)
+	if !ok {
+		sublogger.Warn().
+			Str("chain", c.chain.Name).
+			Msg("Chain ID and denom are not resolved yet, skipping")
+		return
+	}
+
+	observer := NewScrapeObserver(ctx, info.ChainID, c.Name())
+	defer observer.Finish(ch)
+
+	metrics := newGeneralMetrics(info.ChainID)
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer observer.Recover("Pool")
+		sublogger.Debug().Msg("Started querying staking pool")
+		queryStart := time.Now()
+
+		stakingClient := stakingtypes.NewQueryClient(c.chain.GRPC)
+		response, err := stakingClient.Pool(
+			ctx,
+			&stakingtypes.QueryPoolRequest{},
+		)
+		observer.Observe("Pool", queryStart, err)
+		if err != nil {
+			sublogger.Error().Err(err).Msg("Could not get staking pool")
+			return
+		}
+
+		sublogger.Debug().
+			Float64("request-time", time.Since(queryStart).Seconds()).
+			Msg("Finished querying staking pool")
+
+		metrics.generalBondedTokensGauge.Set(nil, IntAmount(response.Pool.BondedTokens))
+		metrics.generalNotBondedTokensGauge.Set(nil, IntAmount(response.Pool.NotBondedTokens))
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer observer.Recover("CommunityPool")
+		sublogger.Debug().Msg("Started querying distribution community pool")
+		queryStart := time.Now()
+
+		distributionClient := distributiontypes.NewQueryClient(c.chain.GRPC)
+		response, err := distributionClient.CommunityPool(
+			ctx,
+			&distributiontypes.QueryCommunityPoolRequest{},
+		)
+		observer.Observe("CommunityPool", queryStart, err)
+		if err != nil {
+			sublogger.Error().Err(err).Msg("Could not get distribution community pool")
+			return
+		}
+
+		sublogger.Debug().
+			Float64("request-time", time.Since(queryStart).Seconds()).
+			Msg("Finished querying distribution community pool")
+
+		for _, coin := range response.Pool {
+			denom, amount := info.DecCoin(coin)
+			metrics.generalCommunityPoolGauge.Set(prometheus.Labels{
+				"denom":      denom.Display,
+				"base_denom": denom.Base,
+			}, amount)
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer observer.Recover("TotalSupply")
+		sublogger.Debug().Msg("Started querying bank total supply")
+		queryStart := time.Now()
+
+		bankClient := banktypes.NewQueryClient(c.chain.GRPC)
+		var supply sdk.Coins
+		err := paginate(observer, "TotalSupply", func(pageRequest *querytypes.PageRequest) (*querytypes.PageResponse, error) {
+			response, err := bankClient.TotalSupply(
+				ctx,
+				&banktypes.QueryTotalSupplyRequest{Pagination: pageRequest},
+			)
+			if err != nil {
+				return nil, err
+			}
+
+			supply = append(supply, response.Supply...)
+			return response.Pagination, nil
+		})
+		observer.Observe("TotalSupply", queryStart, err)
+		if err != nil {
+			sublogger.Error().Err(err).Msg("Could not get bank total supply")
+			return
+		}
+
+		sublogger.Debug().
+			Float64("request-time", time.Since(queryStart).Seconds()).
+			Msg("Finished querying bank total supply")
+
+		for _, coin := range supply {
+			denom, amount := info.Coin(coin)
+			metrics.generalSupplyTotalGauge.Set(prometheus.Labels{
+				"denom":      denom.Display,
+				"base_denom": denom.Base,
+			}, amount)
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer observer.Recover("CoinGecko")
+
+		// CoinGecko only has the current prices
+		if _, historical := requestedHeight(ctx); historical {
+			return
+		}
+
+		sublogger.Debug().Msg("Started querying token prices")
+		queryStart := time.Now()
+
+		for _, token := range CurrentLiveConfig().TokenPrices {
+			println(token)
+			tokenQueryStart := time.Now()
+			response, err := httpGet(ctx, "https://api.coingecko.com/api/v3/coins/"+token)
+			observer.Observe("CoinGecko", tokenQueryStart, err)
+
+			if err != nil {
+				sublogger.Error().Err(err).Str("Token", token).Msg("Could not get token price")
+				return
+			}
+
+			var coinGeckoResponse struct {
+				MarketData struct {
+					CurrentPrice struct {
+						Usd float64 `json:"usd"`
+						Gbp float64 `json:"gbp"`
+					} `json:"current_price"`
+				} `json:"market_data"`
+			}
+			responseBytes, err := io.ReadAll(response.Body)
+
+			if err != nil {
+				sublogger.Error().Err(err).Str("Token", token).Msg("Could not read response body")
+				return
+			}
+			err = json.Unmarshal(responseBytes, &coinGeckoResponse)
+			if err != nil {
+				sublogger.Error().Err(err).Str("Token", token).Msg("Could not umarshal json")
+				return
+			}
+
+			metrics.generalTokenPriceGauge.With(prometheus.Labels{
+				"token":    token,
+				"currency": "usd",
+			}).Set(coinGeckoResponse.MarketData.CurrentPrice.Usd)
+
+			metrics.generalTokenPriceGauge.With(prometheus.Labels{
+				"token":    token,
+				"currency": "gbp",
+			}).Set(coinGeckoResponse.MarketData.CurrentPrice.Gbp)
+		}
+		sublogger.Debug().
+			Float64("request-time", time.Since(queryStart).Seconds()).
+			Msg("Finished querying token prices")
+	}()
+	// go func() {
+	// 	defer wg.Done()
+	// 	sublogger.Debug().Msg("Started querying inflation")
+	// 	queryStart := time.Now()
+
+	// 	mintClient := minttypes.NewQueryClient(c.chain.GRPC)
+	// 	response, err := mintClient.Inflation(
+	// 		context.Background(),
+	// 		&minttypes.QueryInflationRequest{},
+	// 	)
+	// 	if err != nil {
+	// 		sublogger.Error().Err(err).Msg("Could not get inflation")
+	// 		return
+	// 	}
+
+	// 	sublogger.Debug().
+	// 		Float64("request-time", time.Since(queryStart).Seconds()).
+	// 		Msg("Finished querying inflation")
+
+	// 	if value, err := strconv.ParseFloat(response.Inflation.String(), 64); err != nil {
+	// 		sublogger.Error().
+	// 			Err(err).
+	// 			Msg("Could not get inflation")
+	// 	} else {
+	// 		generalInflationGauge.Set(value)
+	// 	}
+	// }()
+	// wg.Add(1)
+
+	// go func() {
+	// 	defer wg.Done()
+	// 	sublogger.Debug().Msg("Started querying annual provisions")
+	// 	queryStart := time.Now()
+
+	// 	mintClient := minttypes.NewQueryClient(c.chain.GRPC)
+	// 	response, err := mintClient.AnnualProvisions(
+	// 		context.Background(),
+	// 		&minttypes.QueryAnnualProvisionsRequest{},
+	// 	)
+	// 	if err != nil {
+	// 		sublogger.Error().Err(err).Msg("Could not get annual provisions")
+	// 		return
+	// 	}
+
+	// 	sublogger.Debug().
+	// 		Float64("request-time", time.Since(queryStart).Seconds()).
+	// 		Msg("Finished querying annual provisions")
+
+	// 	if value, err := strconv.ParseFloat(response.AnnualProvisions.String(), 64); err != nil {
+	// 		sublogger.Error().
+	// 			Err(err).
+	// 			Msg("Could not get annual provisions")
+	// 	} else {
+	// 		generalAnnualProvisions.With(prometheus.Labels{
+	// 			"denom": info.Denom,
+	// 		}).Set(value / info.DenomCoefficient)
+	// 	}
+	// }()
+	// wg.Add(1)
+
+	wg.Wait()
+
+	collectAll(ch, metrics.collectors())
+}
+
+type generalMetrics struct {
+	generalBondedTokensGauge    *AmountGaugeVec
+	generalNotBondedTokensGauge *AmountGaugeVec
+	generalCommunityPoolGauge   *AmountGaugeVec
+	generalSupplyTotalGauge     *AmountGaugeVec
+	generalTokenPriceGauge      *prometheus.GaugeVec
+}
+
+func newGeneralMetrics(chainID string) *generalMetrics {
+	return &generalMetrics{
+		generalBondedTokensGauge: newChainAmountGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cosmos_general_bonded_tokens",
+				Help: "Bonded tokens",
+			},
+			chainID,
+			[]string{},
+		),
+
+		generalNotBondedTokensGauge: newChainAmountGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cosmos_general_not_bonded_tokens",
+				Help: "Not bonded tokens",
+			},
+			chainID,
+			[]string{},
+		),
+
+		generalCommunityPoolGauge: newChainAmountGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cosmos_general_community_pool",
+				Help: "Community pool",
+			},
+			chainID,
+			[]string{"denom", "base_denom"},
+		),
+
+		generalSupplyTotalGauge: newChainAmountGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cosmos_general_supply_total",
+				Help: "Total supply",
+			},
+			chainID,
+			[]string{"denom", "base_denom"},
+		),
+
+		generalTokenPriceGauge: newChainGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cosmos_token_price",
+				Help: "Token Price",
+			},
+			chainID,
+			[]string{"token", "currency"},
+		),
+
+		// generalInflationGauge: prometheus.NewGauge(
+		// 	prometheus.GaugeOpts{
+		// 		Name:        "cosmos_general_inflation",
+		// 		Help:        "Total supply",
+		// 		ConstLabels: constLabels,
+		// 	},
+		// ),
+
+		// generalAnnualProvisions: prometheus.NewGaugeVec(
+		// 	prometheus.GaugeOpts{
+		// 		Name:        "cosmos_general_annual_provisions",
+		// 		Help:        "Annual provisions",
+		// 		ConstLabels: constLabels,
+		// 	},
+		// 	[]string{"denom"},
+		// ),
+	}
+}
+
+func (m *generalMetrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		m.generalBondedTokensGauge,
+		m.generalNotBondedTokensGauge,
+		m.generalCommunityPoolGauge,
+		m.generalSupplyTotalGauge,
+		m.generalTokenPriceGauge,
+		// m.generalInflationGauge,
+		// m.generalAnnualProvisions,
+	}
+}
@@ -0,0 +1,380 @@
+package exporter
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	querytypes "github.com/cosmos/cosmos-sdk/types/query"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type GravityBridgeWalletCollector struct {
+	chain                    *Chain
+	ethConn                  *ethclient.Client
+	cudosOrchestratorAddress string
+	ethOrchestratorAddress   common.Address
+}
+
+func NewGravityBridgeWalletCollector(
+	chain *Chain,
+	ethConn *ethclient.Client,
+	cudosOrchestratorAddressParam string,
+	ethOrchestratorAddressParam string,
+) (*GravityBridgeWalletCollector, error) {
+	if cudosOrchestratorAddressParam == "" || ethOrchestratorAddressParam == "" {
+		return nil, BadRequest("cudos_orchestrator_address and ethereum_orchestrator_address parameters are required")
+	}
+
+	if _, err := chain.ParseAccAddress(cudosOrchestratorAddressParam); err != nil {
+		return nil, BadRequest("invalid cudos orchestrator address %q: %s", cudosOrchestratorAddressParam, err)
+	}
+
+	if !common.IsHexAddress(ethOrchestratorAddressParam) {
+		return nil, BadRequest("invalid ethereum orchestrator address %q", ethOrchestratorAddressParam)
+	}
+
+	return &GravityBridgeWalletCollector{
+		chain:                    chain,
+		ethConn:                  ethConn,
+		cudosOrchestratorAddress: cudosOrchestratorAddressParam,
+		ethOrchestratorAddress:   common.HexToAddress(ethOrchestratorAddressParam),
+	}, nil
+}
+
+func (c *GravityBridgeWalletCollector) Name() string {
+	return "gravity-bridge/wallet"
+}
+
+func (c *GravityBridgeWalletCollector) Labels() prometheus.Labels {
+	return c.chain.Labels()
+}
+
+var describeGravityBridgeWalletMetrics = describeOnce(func() []prometheus.Collector {
+	return newGravityBridgeWalletMetrics("").collectors()
+})
+
+func (c *GravityBridgeWalletCollector) Describe(ch chan<- *prometheus.Desc) {
+	describeGravityBridgeWalletMetrics(ch)
+}
+
+func (c *GravityBridgeWalletCollector) Collect(ch chan<- prometheus.Metric) {
+	collectWithTimeout(c, ch)
+}
+
+func (c *GravityBridgeWalletCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	sublogger := log.With().
+		Str("request_id", uuid.New().String()).
+		Logger()
+
+	info, ok := c.chain.ScrapeInfo(ctx)
+	if !ok {
+		sublogger.Warn().
+			Str("chain", c.chain.Name).
+			Msg("Chain ID and denom are not resolved yet, skipping")
+		return
+	}
+
+	observer := NewScrapeObserver(ctx, info.ChainID, c.Name())
+	defer observer.Finish(ch)
+
+	cudosOrchestratorAddress := c.cudosOrchestratorAddress
+	ethOrchestratorAddress := c.ethOrchestratorAddress
+
+	metrics := newGravityBridgeWalletMetrics(info.ChainID)
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer observer.Recover("AllBalances")
+		sublogger.Debug().
+			Str("cudos_orchestrator_address", cudosOrchestratorAddress).
+			Msg("Started querying orchestrator wallet balance")
+		queryStart := time.Now()
+
+		bankClient := banktypes.NewQueryClient(c.chain.GRPC)
+		var balances sdk.Coins
+		err := paginate(observer, "AllBalances", func(pageRequest *querytypes.PageRequest) (*querytypes.PageResponse, error) {
+			bankRes, err := bankClient.AllBalances(
+				ctx,
+				&banktypes.QueryAllBalancesRequest{Address: cudosOrchestratorAddress, Pagination: pageRequest},
+			)
+			if err != nil {
+				return nil, err
+			}
+
+			balances = append(balances, bankRes.Balances...)
+			return bankRes.Pagination, nil
+		})
+		observer.Observe("AllBalances", queryStart, err)
+		if err != nil {
+			sublogger.Error().
+				Str("cudos_orchestrator_address", cudosOrchestratorAddress).
+				Err(err).
+				Msg("Could not get orchestrator balance")
+			observer.Fail(err)
+			return
+		}
+
+		sublogger.Debug().
+			Str("cudos_orchestrator_address", cudosOrchestratorAddress).
+			Float64("request_time", time.Since(queryStart).Seconds()).
+			Msg("Finished querying orchestrator balance")
+
+		// the metric has no denom label, so it's the balance of the bond
+		// denom only
+		for _, balance := range balances {
+			if balance.Denom != info.Denom.Base {
+				continue
+			}
+
+			metrics.gravCudoOrchBalanceGauge.Set(prometheus.Labels{
+				"cudos_orchestrator_address":    cudosOrchestratorAddress,
+				"ethereum_orchestrator_address": ethOrchestratorAddress.String(),
+			}, info.Amount(balance.Amount))
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer observer.Recover("BalanceAt")
+		sublogger.Debug().
+			Str("ethereum_orchestrator_address", ethOrchestratorAddress.String()).
+			Msg("Started querying ethereum wallet balance")
+		queryStart := time.Now()
+
+		ethBal, err := c.ethConn.BalanceAt(ctx, ethOrchestratorAddress, nil)
+		observer.Observe("BalanceAt", queryStart, err)
+		if err != nil {
+			sublogger.Error().
+				Str("ethereum_orchestrator_address", ethOrchestratorAddress.String()).
+				Err(err).
+				Msg("Could not get ethereum balance")
+			return
+		}
+
+		sublogger.Debug().
+			Str("ethereum_orchestrator_address", ethOrchestratorAddress.String()).
+			Float64("request_time", time.Since(queryStart).Seconds()).
+			Uint64("balance", ethBal.Uint64()).
+			Msg("Finished querying balance")
+
+		metrics.gravEthOrchBalanceGauge.Set(prometheus.Labels{
+			"cudos_orchestrator_address":    cudosOrchestratorAddress,
+			"ethereum_orchestrator_address": ethOrchestratorAddress.String(),
+		}, BigAmount(ethBal).Shift(info.Denom.Exponent))
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer observer.Recover("BalanceOf")
+		sublogger.Debug().
+			Str("ethereum_orchestrator_address", ethOrchestratorAddress.String()).
+			Msg("Started querying ethereum erc20 wallet balance")
+		queryStart := time.Now()
+
+		ethTokenAddress := common.HexToAddress(CurrentLiveConfig().EthTokenContract)
+		instance, err := NewMain(ethTokenAddress, c.ethConn)
+
+		if err != nil {
+			sublogger.Error().
+				Err(err).
+				Msg("Could not retrieve token contract")
+			return
+		}
+
+		ethBal, err := instance.BalanceOf(&bind.CallOpts{Context: ctx}, ethOrchestratorAddress)
+		observer.Observe("BalanceOf", queryStart, err)
+		if err != nil {
+			sublogger.Error().
+				Str("ethereum_token_address", ethTokenAddress.String()).
+				Err(err).
+				Msg("Could not get ethereum token balance")
+			return
+		}
+
+		sublogger.Debug().
+			Str("ethereum_orchestrator_address", ethOrchestratorAddress.String()).
+			Float64("request_time", time.Since(queryStart).Seconds()).
+			Uint64("balance", ethBal.Uint64()).
+			Msg("Finished querying erc20 balance")
+
+		metrics.gravEthOrchERC20BalanceGauge.Set(prometheus.Labels{
+			"cudos_orchestrator_address":    cudosOrchestratorAddress,
+			"ethereum_orchestrator_address": ethOrchestratorAddress.String(),
+		}, BigAmount(ethBal).Shift(info.Denom.Exponent))
+	}()
+
+	wg.Wait()
+
+	collectAll(ch, metrics.collectors())
+}
+
+type gravityBridgeWalletMetrics struct {
+	gravCudoOrchBalanceGauge     *AmountGaugeVec
+	gravEthOrchBalanceGauge      *AmountGaugeVec
+	gravEthOrchERC20BalanceGauge *AmountGaugeVec
+}
+
+func newGravityBridgeWalletMetrics(chainID string) *gravityBridgeWalletMetrics {
+	return &gravityBridgeWalletMetrics{
+		gravCudoOrchBalanceGauge: newChainAmountGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "gravity_cudos_orchestrator_balance",
+				Help: "Balance of the cudos orchestrator wallet",
+			},
+			chainID,
+			[]string{"cudos_orchestrator_address", "ethereum_orchestrator_address"},
+		),
+
+		gravEthOrchBalanceGauge: newChainAmountGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "gravity_ethereum_orchestrator_balance",
+				Help: "Balance of the ethereum orchestrator wallet",
+			},
+			chainID,
+			[]string{"cudos_orchestrator_address", "ethereum_orchestrator_address"},
+		),
+
+		gravEthOrchERC20BalanceGauge: newChainAmountGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "gravity_ethereum_orchestrator_erc20_balance",
+				Help: "ERC20 balance of the ethereum orchestrator wallet",
+			},
+			chainID,
+			[]string{"cudos_orchestrator_address", "ethereum_orchestrator_address"},
+		),
+	}
+}
+
+func (m *gravityBridgeWalletMetrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		m.gravCudoOrchBalanceGauge,
+		m.gravEthOrchBalanceGauge,
+		m.gravEthOrchERC20BalanceGauge,
+	}
+}
+
+// GravityBridgeContractCollector reads the contract addresses of the live
+// config on every collection, so they can change on reload.
+type GravityBridgeContractCollector struct {
+	chain   *Chain
+	ethConn *ethclient.Client
+}
+
+func NewGravityBridgeContractCollector(chain *Chain, ethConn *ethclient.Client) *GravityBridgeContractCollector {
+	return &GravityBridgeContractCollector{
+		chain:   chain,
+		ethConn: ethConn,
+	}
+}
+
+func (c *GravityBridgeContractCollector) Name() string {
+	return "gravity-bridge/contract"
+}
+
+func (c *GravityBridgeContractCollector) Labels() prometheus.Labels {
+	return c.chain.Labels()
+}
+
+var describeGravityBridgeContractMetrics = describeOnce(func() []prometheus.Collector {
+	return newGravityBridgeContractMetrics("").collectors()
+})
+
+func (c *GravityBridgeContractCollector) Describe(ch chan<- *prometheus.Desc) {
+	describeGravityBridgeContractMetrics(ch)
+}
+
+func (c *GravityBridgeContractCollector) Collect(ch chan<- prometheus.Metric) {
+	collectWithTimeout(c, ch)
+}
+
+func (c *GravityBridgeContractCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	sublogger := log.With().
+		Str("request_id", uuid.New().String()).
+		Logger()
+
+	config := CurrentLiveConfig()
+	if !config.GravityBridgeConfigured() {
+		sublogger.Debug().Msg("Gravity bridge contracts are not configured, skipping")
+		return
+	}
+
+	info, ok := c.chain.ScrapeInfo(ctx)
+	if !ok {
+		sublogger.Warn().
+			Str("chain", c.chain.Name).
+			Msg("Chain ID and denom are not resolved yet, skipping")
+		return
+	}
+
+	observer := NewScrapeObserver(ctx, info.ChainID, c.Name())
+	defer observer.Finish(ch)
+
+	ethTokenAddress := common.HexToAddress(config.EthTokenContract)
+	token, err := NewMain(ethTokenAddress, c.ethConn)
+	if err != nil {
+		sublogger.Error().
+			Err(err).
+			Msg("Could not retrieve token contract")
+		return
+	}
+
+	metrics := newGravityBridgeContractMetrics(info.ChainID)
+
+	sublogger.Debug().
+		Str("ethereum_gravity_contract", ethTokenAddress.String()).
+		Msg("Started querying gravity ethereum gravity contract balance")
+	queryStart := time.Now()
+	ethBal, err := token.BalanceOf(&bind.CallOpts{Context: ctx}, common.HexToAddress(config.EthGravityContract))
+	observer.Observe("BalanceOf", queryStart, err)
+	if err != nil {
+		sublogger.Error().
+			Str("ethereum_token_address", ethTokenAddress.String()).
+			Err(err).
+			Msg("Could not get ethereum token balance")
+		return
+	}
+
+	sublogger.Debug().
+		Str("ethereum_gravity_contract", ethTokenAddress.String()).
+		Float64("request_time", time.Since(queryStart).Seconds()).
+		Msg("Finished querying gravity ethereum contract token balance")
+
+	metrics.gravEthContractBalanceGauge.Set(nil, BigAmount(ethBal).Shift(info.Denom.Exponent))
+
+	collectAll(ch, metrics.collectors())
+}
+
+type gravityBridgeContractMetrics struct {
+	gravEthContractBalanceGauge *AmountGaugeVec
+}
+
+func newGravityBridgeContractMetrics(chainID string) *gravityBridgeContractMetrics {
+	return &gravityBridgeContractMetrics{
+		gravEthContractBalanceGauge: newChainAmountGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "gravity_ethereum_contract_balance",
+				Help: "Balance of the ethereum gravity contract",
+			},
+			chainID,
+			[]string{},
+		),
+	}
+}
+
+func (m *gravityBridgeContractMetrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		m.gravEthContractBalanceGauge,
+	}
+}
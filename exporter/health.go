@@ -0,0 +1,205 @@
+package exporter
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// healthStaleIntervals is how many --health-check-interval intervals the
+// latest check of a backend can be old before the background checks are
+// considered stuck. With an interval shorter than the timeout of a check, a
+// check that times out takes longer than an interval, so it's counted in
+// check timeouts instead.
+const healthStaleIntervals = 3
+
+// healthStaleAfter is how old the latest check of a backend can be before the
+// background checks are considered stuck.
+func healthStaleAfter() time.Duration {
+	interval := HealthCheckInterval
+	if interval < backendCheckTimeout {
+		interval = backendCheckTimeout
+	}
+
+	return healthStaleIntervals * interval
+}
+
+// Health serves /healthz and /readyz from the background health checks of
+// every backend, so probing them never queries the nodes itself.
+type Health struct {
+	chains   *Chains
+	networks *OptionalNetworkPool
+	eth      *BackendPool
+}
+
+// HealthReport is the JSON body of /healthz and /readyz.
+type HealthReport struct {
+	Status       string             `json:"status"`
+	Dependencies []DependencyStatus `json:"dependencies"`
+}
+
+// DependencyStatus is the state of a single backend, or of the chain ID and
+// denom resolution of a chain. Required dependencies are the ones the
+// exporter isn't ready without.
+type DependencyStatus struct {
+	Name           string    `json:"name"`
+	Type           string    `json:"type"`
+	Address        string    `json:"address,omitempty"`
+	Status         string    `json:"status"`
+	Required       bool      `json:"required"`
+	Serving        bool      `json:"serving,omitempty"`
+	Height         int64     `json:"height,omitempty"`
+	LatencySeconds float64   `json:"latency_seconds"`
+	LastCheck      time.Time `json:"last_check"`
+	LastError      string    `json:"last_error,omitempty"`
+}
+
+func NewHealth(chains *Chains, networks *OptionalNetworkPool, eth *BackendPool) *Health {
+	return &Health{
+		chains:   chains,
+		networks: networks,
+		eth:      eth,
+	}
+}
+
+// Live reports whether the background checks are still running. It doesn't
+// depend on the state of the backends, as restarting the exporter wouldn't
+// bring them back.
+func (h *Health) Live(w http.ResponseWriter, r *http.Request) {
+	live := true
+	for _, pool := range h.pools() {
+		for _, backend := range pool.Backends() {
+			if time.Since(backend.LastCheck) > healthStaleAfter() {
+				live = false
+			}
+		}
+	}
+
+	h.serve(w, live, h.dependencies())
+}
+
+// Ready reports whether every chain has its chain ID and denom resolved and
+// at least one healthy gRPC and Tendermint RPC node. Optional networks and
+// the Ethereum node are listed, but don't affect readiness.
+func (h *Health) Ready(w http.ResponseWriter, r *http.Request) {
+	// the same dependencies decide the status and are listed, so the body
+	// never contradicts the status code
+	dependencies := h.dependencies()
+
+	ready := true
+	for _, dependency := range dependencies {
+		if dependency.Required && dependency.Status != "up" {
+			ready = false
+		}
+	}
+
+	h.serve(w, ready, dependencies)
+}
+
+func (h *Health) serve(w http.ResponseWriter, ok bool, dependencies []DependencyStatus) {
+	report := HealthReport{
+		Status:       "ok",
+		Dependencies: dependencies,
+	}
+
+	statusCode := http.StatusOK
+	if !ok {
+		report.Status = "unavailable"
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Error().Err(err).Msg("Could not write health report")
+	}
+}
+
+func (h *Health) pools() []*BackendPool {
+	var pools []*BackendPool
+	for _, chain := range h.chains.All() {
+		pools = append(pools, chain.GRPC, chain.RPC)
+	}
+
+	names, networks := h.networks.all()
+	for _, name := range names {
+		pools = append(pools, networks[name])
+	}
+
+	if h.eth != nil {
+		pools = append(pools, h.eth)
+	}
+
+	return pools
+}
+
+func (h *Health) dependencies() []DependencyStatus {
+	var dependencies []DependencyStatus
+
+	for _, chain := range h.chains.All() {
+		info, ready := chain.Info()
+		lastCheck, latency, err := chain.LastCheck()
+
+		dependency := DependencyStatus{
+			Name:           "chain/" + chain.Name,
+			Type:           "chain",
+			Address:        info.ChainID,
+			Status:         "down",
+			Required:       true,
+			LatencySeconds: latency.Seconds(),
+			LastCheck:      lastCheck,
+		}
+		if ready {
+			dependency.Status = "up"
+		}
+		if err != nil {
+			dependency.LastError = err.Error()
+		}
+
+		dependencies = append(dependencies, dependency)
+		dependencies = append(dependencies, poolStatus("chain/"+chain.Name, chain.GRPC, true)...)
+		dependencies = append(dependencies, poolStatus("chain/"+chain.Name, chain.RPC, true)...)
+	}
+
+	names, networks := h.networks.all()
+	for _, name := range names {
+		dependencies = append(dependencies, poolStatus("network/"+name, networks[name], false)...)
+	}
+
+	if h.eth != nil {
+		dependencies = append(dependencies, poolStatus("ethereum", h.eth, false)...)
+	}
+
+	return dependencies
+}
+
+// poolStatus lists every backend of a pool. For a required pool, only the
+// backend it serves from is required, as one healthy backend is enough.
+func poolStatus(name string, pool *BackendPool, required bool) []DependencyStatus {
+	serving := pool.Address()
+	backends := pool.Backends()
+
+	dependencies := make([]DependencyStatus, len(backends))
+	for i, backend := range backends {
+		dependencies[i] = DependencyStatus{
+			Name:           name + "/" + pool.kind,
+			Type:           pool.kind,
+			Address:        backend.Address,
+			Status:         "down",
+			Required:       required && backend.Address == serving,
+			Serving:        backend.Address == serving,
+			Height:         backend.Height,
+			LatencySeconds: backend.Latency.Seconds(),
+			LastCheck:      backend.LastCheck,
+		}
+
+		if backend.Healthy {
+			dependencies[i].Status = "up"
+		}
+		if backend.LastError != nil {
+			dependencies[i].LastError = backend.LastError.Error()
+		}
+	}
+
+	return dependencies
+}
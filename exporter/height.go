@@ -0,0 +1,146 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	grpctypes "github.com/cosmos/cosmos-sdk/types/grpc"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc/metadata"
+)
+
+// queryHeightDesc is sent along with the collector's own metrics, so every
+// response shows the block height its gRPC queries were evaluated at.
+var queryHeightDesc = prometheus.NewDesc(
+	"cosmos_exporter_query_height",
+	"Block height the gRPC queries of this collection were evaluated at",
+	[]string{"chain_id", "endpoint"},
+	nil,
+)
+
+// latestHeightPattern matches the latest height the SDK reports when it can't
+// load the state at the requested one, because it's pruned or in the future.
+var latestHeightPattern = regexp.MustCompile(`failed to load state at height \d+; .* \(latest height: (\d+)\)`)
+
+// parseHeight returns the height parameter of a request, if any.
+func parseHeight(query url.Values) (int64, bool, error) {
+	value := query.Get("height")
+	if value == "" {
+		return 0, false, nil
+	}
+
+	height, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || height <= 0 {
+		return 0, false, BadRequest("invalid height %q, expected a positive block number", value)
+	}
+
+	return height, true, nil
+}
+
+type requestedHeightKey struct{}
+
+// withHeight makes the gRPC queries of ctx query the state at a past block,
+// by sending the height in the x-cosmos-block-height metadata header.
+func withHeight(ctx context.Context, height int64) context.Context {
+	ctx = metadata.AppendToOutgoingContext(ctx, grpctypes.GRPCBlockHeightHeader, strconv.FormatInt(height, 10))
+	return context.WithValue(ctx, requestedHeightKey{}, height)
+}
+
+// requestedHeight returns the height the queries of ctx are made at, if it's
+// not the latest one.
+func requestedHeight(ctx context.Context) (int64, bool) {
+	height, ok := ctx.Value(requestedHeightKey{}).(int64)
+	return height, ok
+}
+
+// heightRecorder keeps the highest block height the node returned in the
+// x-cosmos-block-height response header during a collection. Queries of a
+// collection run concurrently, so blocks can be produced in between.
+type heightRecorder struct {
+	height int64
+}
+
+type heightRecorderKey struct{}
+
+func withHeightRecorder(ctx context.Context) context.Context {
+	return context.WithValue(ctx, heightRecorderKey{}, &heightRecorder{})
+}
+
+// recordHeight records the height of a gRPC response header in the recorder
+// of ctx, if it has one.
+func recordHeight(ctx context.Context, header metadata.MD) {
+	recorder, ok := ctx.Value(heightRecorderKey{}).(*heightRecorder)
+	if !ok {
+		return
+	}
+
+	values := header.Get(grpctypes.GRPCBlockHeightHeader)
+	if len(values) == 0 {
+		return
+	}
+
+	height, err := strconv.ParseInt(values[0], 10, 64)
+	if err != nil {
+		return
+	}
+
+	for {
+		current := atomic.LoadInt64(&recorder.height)
+		if height <= current || atomic.CompareAndSwapInt64(&recorder.height, current, height) {
+			return
+		}
+	}
+}
+
+// recordedHeight returns the height recorded in ctx, if any query returned
+// one.
+func recordedHeight(ctx context.Context) (int64, bool) {
+	recorder, ok := ctx.Value(heightRecorderKey{}).(*heightRecorder)
+	if !ok {
+		return 0, false
+	}
+
+	height := atomic.LoadInt64(&recorder.height)
+	return height, height > 0
+}
+
+// heightError explains a query that failed because the node doesn't have the
+// state at the requested height: 410 if it's pruned, 400 if it's in the
+// future. It returns nil for other errors.
+func heightError(ctx context.Context, err error) *RequestError {
+	height, ok := requestedHeight(ctx)
+	if err == nil || !ok {
+		return nil
+	}
+
+	if strings.Contains(err.Error(), "cannot query with height in the future") {
+		return &RequestError{
+			StatusCode: http.StatusBadRequest,
+			Err:        fmt.Errorf("height %d is after the latest block of the node", height),
+		}
+	}
+
+	match := latestHeightPattern.FindStringSubmatch(err.Error())
+	if match == nil {
+		return nil
+	}
+
+	latest, _ := strconv.ParseInt(match[1], 10, 64)
+	if height > latest {
+		return &RequestError{
+			StatusCode: http.StatusBadRequest,
+			Err:        fmt.Errorf("height %d is after the latest block %d of the node", height, latest),
+		}
+	}
+
+	return &RequestError{
+		StatusCode: http.StatusGone,
+		Err:        fmt.Errorf("the node has pruned the state at height %d, query an archive node or a later height (latest height: %d)", height, latest),
+	}
+}
@@ -0,0 +1,135 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	grpctypes "github.com/cosmos/cosmos-sdk/types/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestParseHeight(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    int64
+		ok      bool
+		wantErr bool
+	}{
+		{query: "", want: 0},
+		{query: "height=", want: 0},
+		{query: "height=12345", want: 12345, ok: true},
+		{query: "height=1", want: 1, ok: true},
+		{query: "height=0", wantErr: true},
+		{query: "height=-5", wantErr: true},
+		{query: "height=latest", wantErr: true},
+		{query: "height=1.5", wantErr: true},
+	}
+
+	for _, test := range tests {
+		query, err := url.ParseQuery(test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		height, ok, err := parseHeight(query)
+		if (err != nil) != test.wantErr {
+			t.Errorf("parseHeight(%q) error = %v, want error %t", test.query, err, test.wantErr)
+			continue
+		}
+
+		var requestErr *RequestError
+		if err != nil && (!errors.As(err, &requestErr) || requestErr.StatusCode != http.StatusBadRequest) {
+			t.Errorf("parseHeight(%q) error = %v, want a bad request", test.query, err)
+		}
+
+		if height != test.want || ok != test.ok {
+			t.Errorf("parseHeight(%q) = %d, %t, want %d, %t", test.query, height, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestHeightError(t *testing.T) {
+	atHeight := withHeight(context.Background(), 100)
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		err        error
+		wantStatus int
+	}{
+		{
+			name: "no error",
+			ctx:  atHeight,
+		},
+		{
+			name: "latest height",
+			ctx:  context.Background(),
+			err:  errors.New("failed to load state at height 100; version does not exist (latest height: 5000)"),
+		},
+		{
+			name:       "pruned",
+			ctx:        atHeight,
+			err:        errors.New("rpc error: code = InvalidArgument desc = failed to load state at height 100; version does not exist (latest height: 5000): invalid request"),
+			wantStatus: http.StatusGone,
+		},
+		{
+			name:       "after the latest block",
+			ctx:        atHeight,
+			err:        errors.New("rpc error: code = InvalidArgument desc = failed to load state at height 100; version does not exist (latest height: 50): invalid request"),
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "in the future",
+			ctx:        atHeight,
+			err:        errors.New("rpc error: code = InvalidArgument desc = cannot query with height in the future; please provide a valid height: invalid height"),
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "other error",
+			ctx:  atHeight,
+			err:  errors.New("rpc error: code = Unavailable desc = connection refused"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := heightError(test.ctx, test.err)
+
+			if test.wantStatus == 0 {
+				if got != nil {
+					t.Errorf("heightError() = %v, want nil", got)
+				}
+				return
+			}
+
+			if got == nil || got.StatusCode != test.wantStatus {
+				t.Errorf("heightError() = %+v, want status %d", got, test.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRecordHeight(t *testing.T) {
+	ctx := withHeightRecorder(context.Background())
+
+	if _, ok := recordedHeight(ctx); ok {
+		t.Error("recordedHeight() before any response = ok")
+	}
+
+	// the highest height is kept, whatever order the responses come in
+	for _, height := range []string{"100", "102", "invalid", "101"} {
+		recordHeight(ctx, metadata.Pairs(grpctypes.GRPCBlockHeightHeader, height))
+	}
+	recordHeight(ctx, metadata.MD{})
+
+	if height, ok := recordedHeight(ctx); !ok || height != 102 {
+		t.Errorf("recordedHeight() = %d, %t, want 102, true", height, ok)
+	}
+
+	if _, ok := recordedHeight(context.Background()); ok {
+		t.Error("recordedHeight() without a recorder = ok")
+	}
+}
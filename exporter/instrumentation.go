@@ -0,0 +1,279 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	queryDurationHistogram = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "cosmos_exporter_query_duration_seconds",
+			Help:    "Duration of the queries the exporter makes to the node and other backends",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"chain_id", "endpoint", "query"},
+	)
+
+	queryErrorsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cosmos_exporter_query_errors_total",
+			Help: "Failed queries to the node and other backends, by gRPC status code",
+		},
+		[]string{"chain_id", "endpoint", "query", "code"},
+	)
+
+	panicsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cosmos_exporter_panics_total",
+			Help: "Panics recovered while collecting, e.g. on malformed node responses",
+		},
+		[]string{"chain_id", "endpoint", "query"},
+	)
+)
+
+// upDesc is sent along with the collector's own metrics, so a target that
+// doesn't exist or a node that doesn't answer shows as down in background
+// polled snapshots too, where the scrape itself doesn't fail.
+var upDesc = prometheus.NewDesc(
+	"cosmos_exporter_up",
+	"1 if the target of the collection was found and the node answered, 0 if no",
+	[]string{"chain_id", "endpoint"},
+	nil,
+)
+
+// scrapeSuccessDesc and queryPagesDesc are sent along with the collector's
+// own metrics too, so they belong to the target of the collection, like the
+// address of /metrics/wallet, rather than to whichever target ran last.
+var scrapeSuccessDesc = prometheus.NewDesc(
+	"cosmos_exporter_scrape_success",
+	"1 if all the queries of the collection succeeded, 0 if no",
+	[]string{"chain_id", "endpoint"},
+	nil,
+)
+
+var queryPagesDesc = prometheus.NewDesc(
+	"cosmos_exporter_query_pages",
+	"Number of pages fetched by a paginated query during this collection",
+	[]string{"chain_id", "endpoint", "query"},
+	nil,
+)
+
+// queryTimeoutDesc is sent along with the collector's own metrics, so a scrape
+// that returns partial results shows which queries didn't make it in time.
+var queryTimeoutDesc = prometheus.NewDesc(
+	"cosmos_exporter_query_timeout",
+	"1 if the query ran into the scrape deadline during this collection, 0 if no",
+	[]string{"chain_id", "endpoint", "query"},
+	nil,
+)
+
+func init() {
+	prometheus.MustRegister(queryDurationHistogram)
+	prometheus.MustRegister(queryErrorsCounter)
+	prometheus.MustRegister(panicsCounter)
+}
+
+// ScrapeObserver records the outcome of every query made during a single
+// collection into the exporter's own metrics.
+type ScrapeObserver struct {
+	ctx       context.Context
+	chainID   string
+	endpoint  string
+	failed    int32
+	succeeded int32
+	down      int32
+
+	mutex    sync.Mutex
+	timeouts map[string]bool
+	pages    map[string]int
+}
+
+func NewScrapeObserver(ctx context.Context, chainID, endpoint string) *ScrapeObserver {
+	return &ScrapeObserver{
+		ctx:      ctx,
+		chainID:  chainID,
+		endpoint: endpoint,
+		timeouts: make(map[string]bool),
+		pages:    make(map[string]int),
+	}
+}
+
+// Observe records the duration of a query started at queryStart, and counts
+// it as failed if err is not nil.
+func (o *ScrapeObserver) Observe(query string, queryStart time.Time, err error) {
+	queryDurationHistogram.
+		WithLabelValues(o.chainID, o.endpoint, query).
+		Observe(time.Since(queryStart).Seconds())
+
+	o.mutex.Lock()
+	o.timeouts[query] = o.timeouts[query] || isTimeout(err)
+	o.mutex.Unlock()
+
+	if err == nil {
+		atomic.AddInt32(&o.succeeded, 1)
+		return
+	}
+
+	// without the state at the requested height, the whole response would
+	// be wrong, not just this query
+	if requestError := heightError(o.ctx, err); requestError != nil {
+		failScrape(o.ctx, requestError)
+	}
+
+	atomic.StoreInt32(&o.failed, 1)
+	queryErrorsCounter.
+		WithLabelValues(o.chainID, o.endpoint, query, errorCode(err)).
+		Inc()
+}
+
+// Recover is deferred at the top of every query goroutine, so a panic on a
+// malformed response fails the query instead of crashing the exporter.
+func (o *ScrapeObserver) Recover(query string) {
+	if r := recover(); r != nil {
+		logPanic(o.chainID, o.endpoint, query, r)
+		atomic.StoreInt32(&o.failed, 1)
+	}
+}
+
+// logPanic logs a recovered panic with its stack trace and counts it.
+func logPanic(chainID, endpoint, query string, r interface{}) {
+	log.Error().
+		Str("chain_id", chainID).
+		Str("endpoint", endpoint).
+		Str("query", query).
+		Str("panic", fmt.Sprint(r)).
+		Str("stack", string(debug.Stack())).
+		Msg("Recovered from panic")
+
+	panicsCounter.WithLabelValues(chainID, endpoint, query).Inc()
+}
+
+// ObservePages records how many pages a paginated query fetched.
+func (o *ScrapeObserver) ObservePages(query string, pages int) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.pages[query] = pages
+}
+
+// Fail marks the target of the collection as down after its main query
+// failed, e.g. the validator of /metrics/validator. A live scrape then fails
+// with 404 if the node doesn't know the target, or 502 otherwise.
+func (o *ScrapeObserver) Fail(err error) {
+	atomic.StoreInt32(&o.down, 1)
+	failScrape(o.ctx, targetError(err))
+}
+
+// Done is called once all queries of a collection that isn't exported as
+// metrics, like an API request, are done. A collection where every query
+// failed means the node isn't answering, and fails the target.
+func (o *ScrapeObserver) Done() (success, up bool) {
+	success = atomic.LoadInt32(&o.failed) == 0
+
+	if !success && atomic.LoadInt32(&o.succeeded) == 0 {
+		o.Fail(fmt.Errorf("all queries of %s failed, see the exporter logs", o.endpoint))
+	}
+
+	return success, atomic.LoadInt32(&o.down) == 0
+}
+
+// Finish is Done for collections exported as metrics, and sends the scrape
+// success, up, timeout, pages and height indicators to ch.
+func (o *ScrapeObserver) Finish(ch chan<- prometheus.Metric) {
+	success, up := o.Done()
+
+	ch <- prometheus.MustNewConstMetric(scrapeSuccessDesc, prometheus.GaugeValue, boolValue(success), o.chainID, o.endpoint)
+	ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, boolValue(up), o.chainID, o.endpoint)
+
+	if height, ok := recordedHeight(o.ctx); ok {
+		ch <- prometheus.MustNewConstMetric(queryHeightDesc, prometheus.GaugeValue, float64(height), o.chainID, o.endpoint)
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	for query, timedOut := range o.timeouts {
+		var value float64
+		if timedOut {
+			value = 1
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			queryTimeoutDesc,
+			prometheus.GaugeValue,
+			value,
+			o.chainID,
+			o.endpoint,
+			query,
+		)
+	}
+	for query, pages := range o.pages {
+		ch <- prometheus.MustNewConstMetric(
+			queryPagesDesc,
+			prometheus.GaugeValue,
+			float64(pages),
+			o.chainID,
+			o.endpoint,
+			query,
+		)
+	}
+}
+
+// targetError maps the error of the main query of a collection to the status
+// code of the scrape: 404 if the node doesn't know the target, 400 if it
+// rejected the request, and 502 for all other failures.
+func targetError(err error) *RequestError {
+	statusCode := http.StatusBadGateway
+
+	switch status.Code(err) {
+	case codes.NotFound:
+		statusCode = http.StatusNotFound
+	case codes.InvalidArgument:
+		statusCode = http.StatusBadRequest
+	}
+
+	var restError *restStatusError
+	if errors.As(err, &restError) {
+		switch restError.StatusCode {
+		case http.StatusNotFound, http.StatusBadRequest:
+			statusCode = restError.StatusCode
+		}
+	}
+
+	return &RequestError{StatusCode: statusCode, Err: err}
+}
+
+// errorCode returns the gRPC status code of err, or Unknown for errors that
+// don't come from gRPC.
+func errorCode(err error) string {
+	if s, ok := status.FromError(err); ok {
+		return s.Code().String()
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return codes.DeadlineExceeded.String()
+	}
+
+	return "Unknown"
+}
+
+// isTimeout reports whether err means the query ran out of time, either as a
+// gRPC DeadlineExceeded status or a context deadline from HTTP clients.
+func isTimeout(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	return errors.Is(err, context.DeadlineExceeded) || status.Code(err) == codes.DeadlineExceeded
+}
@@ -0,0 +1,133 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestTargetError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"gRPC not found", status.Error(codes.NotFound, "validator cosmosvaloper1abc not found"), http.StatusNotFound},
+		{"gRPC invalid argument", status.Error(codes.InvalidArgument, "invalid address"), http.StatusBadRequest},
+		{"gRPC unavailable", status.Error(codes.Unavailable, "connection refused"), http.StatusBadGateway},
+		{"gRPC deadline exceeded", status.Error(codes.DeadlineExceeded, "context deadline exceeded"), http.StatusBadGateway},
+		{"gRPC internal", status.Error(codes.Internal, "panic in the node"), http.StatusBadGateway},
+		{"REST not found", &restStatusError{StatusCode: http.StatusNotFound, Err: errors.New("pool not found")}, http.StatusNotFound},
+		{"REST bad request", &restStatusError{StatusCode: http.StatusBadRequest, Err: errors.New("invalid pool id")}, http.StatusBadRequest},
+		{"REST server error", &restStatusError{StatusCode: http.StatusInternalServerError, Err: errors.New("internal error")}, http.StatusBadGateway},
+		{"REST unavailable", &restStatusError{StatusCode: http.StatusServiceUnavailable, Err: errors.New("maintenance")}, http.StatusBadGateway},
+		{"wrapped REST error", fmt.Errorf("could not get pool: %w", &restStatusError{StatusCode: http.StatusNotFound, Err: errors.New("pool not found")}), http.StatusNotFound},
+		{"context deadline", context.DeadlineExceeded, http.StatusBadGateway},
+		{"other error", errors.New("connection reset by peer"), http.StatusBadGateway},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := targetError(test.err)
+
+			if got.StatusCode != test.want {
+				t.Errorf("targetError() status = %d, want %d", got.StatusCode, test.want)
+			}
+
+			if !errors.Is(got, test.err) {
+				t.Errorf("targetError() = %v, doesn't wrap %v", got, test.err)
+			}
+		})
+	}
+}
+
+func TestEndpointServeHTTPStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		// fail fails the collection through the context of the scrape
+		fail func(ctx context.Context)
+		want int
+	}{
+		{
+			name: "success",
+			fail: func(ctx context.Context) {},
+			want: http.StatusOK,
+		},
+		{
+			name: "gRPC not found",
+			fail: func(ctx context.Context) {
+				NewScrapeObserver(ctx, "test-1", "validator").Fail(status.Error(codes.NotFound, "validator not found"))
+			},
+			want: http.StatusNotFound,
+		},
+		{
+			name: "gRPC invalid argument",
+			fail: func(ctx context.Context) {
+				NewScrapeObserver(ctx, "test-1", "validator").Fail(status.Error(codes.InvalidArgument, "invalid address"))
+			},
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "gRPC unavailable",
+			fail: func(ctx context.Context) {
+				NewScrapeObserver(ctx, "test-1", "validator").Fail(status.Error(codes.Unavailable, "connection refused"))
+			},
+			want: http.StatusBadGateway,
+		},
+		{
+			name: "REST not found",
+			fail: func(ctx context.Context) {
+				NewScrapeObserver(ctx, "test-1", "validator").Fail(&restStatusError{StatusCode: http.StatusNotFound, Err: errors.New("pool not found")})
+			},
+			want: http.StatusNotFound,
+		},
+		{
+			name: "REST server error",
+			fail: func(ctx context.Context) {
+				NewScrapeObserver(ctx, "test-1", "validator").Fail(&restStatusError{StatusCode: http.StatusInternalServerError, Err: errors.New("internal error")})
+			},
+			want: http.StatusBadGateway,
+		},
+		{
+			name: "chain not ready",
+			fail: func(ctx context.Context) {
+				(&Chain{Name: "test"}).ScrapeInfo(ctx)
+			},
+			want: http.StatusServiceUnavailable,
+		},
+		{
+			// the first failure of a scrape wins
+			name: "several failures",
+			fail: func(ctx context.Context) {
+				observer := NewScrapeObserver(ctx, "test-1", "validator")
+				observer.Fail(status.Error(codes.NotFound, "validator not found"))
+				observer.Fail(status.Error(codes.Unavailable, "connection refused"))
+			},
+			want: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			collector := &fakeCollector{name: "validator", collect: func(ctx context.Context, ch chan<- prometheus.Metric) {
+				test.fail(ctx)
+				sendGauge(ch, "cosmos_validator_tokens", 1, "address", "cosmosvaloper1abc")
+			}}
+			endpoint := NewEndpoint("validator", 0, StaticCollector(collector))
+
+			w := httptest.NewRecorder()
+			endpoint.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics/validator?address=cosmosvaloper1abc", nil))
+
+			if w.Code != test.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, test.want, w.Body)
+			}
+		})
+	}
+}
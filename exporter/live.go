@@ -0,0 +1,67 @@
+package exporter
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rs/zerolog"
+	"github.com/spf13/pflag"
+)
+
+// LiveConfig is the part of the configuration that can change without a
+// restart, on SIGHUP or POST /-/reload.
+type LiveConfig struct {
+	LogLevel           string
+	TokenPrices        []string
+	OptionalNetworks   map[string]string
+	EthTokenContract   string
+	EthGravityContract string
+}
+
+var currentLiveConfig atomic.Value
+
+func init() {
+	currentLiveConfig.Store(&LiveConfig{})
+}
+
+// CurrentLiveConfig returns the live config in effect, which is never
+// modified in place, so it can be read without locking.
+func CurrentLiveConfig() *LiveConfig {
+	return currentLiveConfig.Load().(*LiveConfig)
+}
+
+// SetLiveConfig swaps in the live config. The config must not be modified
+// after that.
+func SetLiveConfig(config *LiveConfig) {
+	currentLiveConfig.Store(config)
+}
+
+func (c *LiveConfig) AddFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&c.LogLevel, "log-level", "info", "Logging level")
+	flags.StringSliceVar(&c.TokenPrices, "token-prices", nil, "List of CoinGecko token ids to retrieve current prices")
+	flags.StringToStringVar(&c.OptionalNetworks, "optional-networks", nil, "Optional grpc networks")
+	flags.StringVar(&c.EthTokenContract, "eth-token-contract", "", "Ethereum token contract")
+	flags.StringVar(&c.EthGravityContract, "eth-gravity-contract", "", "Ethereum gravity contract")
+}
+
+func (c *LiveConfig) Validate() error {
+	if _, err := zerolog.ParseLevel(c.LogLevel); err != nil {
+		return fmt.Errorf("log-level: %w", err)
+	}
+
+	for name, contract := range map[string]string{
+		"eth-token-contract":   c.EthTokenContract,
+		"eth-gravity-contract": c.EthGravityContract,
+	} {
+		if contract != "" && !common.IsHexAddress(contract) {
+			return fmt.Errorf("%s: invalid Ethereum address %q", name, contract)
+		}
+	}
+
+	return nil
+}
+
+func (c *LiveConfig) GravityBridgeConfigured() bool {
+	return c.EthTokenContract != "" && c.EthGravityContract != ""
+}
@@ -0,0 +1,210 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+var (
+	optionalNetworkUpDesc = prometheus.NewDesc(
+		"cosmos_exporter_optional_network_up",
+		"1 if the latest health check of the optional network succeeded, 0 if no",
+		[]string{"network"},
+		nil,
+	)
+
+	optionalNetworkStateDesc = prometheus.NewDesc(
+		"cosmos_exporter_optional_network_connection_state",
+		"1 for the current gRPC connectivity state of the optional network connection, 0 for the others",
+		[]string{"network", "state"},
+		nil,
+	)
+
+	connectivityStates = []connectivity.State{
+		connectivity.Idle,
+		connectivity.Connecting,
+		connectivity.Ready,
+		connectivity.TransientFailure,
+		connectivity.Shutdown,
+	}
+)
+
+// OptionalNetworkPool keeps a connection to every optional network, created
+// at startup and shared by all the requests for that network, until a reload
+// swaps in new ones. It's also a prometheus.Collector of the state of the
+// connections.
+type OptionalNetworkPool struct {
+	mutex    sync.RWMutex
+	networks map[string]*BackendPool
+}
+
+// NewOptionalNetworkPool connects to every optional network. Invalid addresses
+// or settings are an error, while networks that are down at startup are only
+// reported, as they're health-checked like the main nodes.
+func NewOptionalNetworkPool(addresses map[string]string, settings map[string]NodeSettings) (*OptionalNetworkPool, error) {
+	pool := &OptionalNetworkPool{networks: make(map[string]*BackendPool)}
+
+	for name, address := range addresses {
+		key := strings.ToLower(name)
+		if address == "" {
+			pool.Close()
+			return nil, fmt.Errorf("optional network %s has no address", name)
+		}
+
+		if _, ok := pool.networks[key]; ok {
+			pool.Close()
+			return nil, fmt.Errorf("duplicate optional network %s", name)
+		}
+
+		network, err := NewGRPCPool(PoolOwner{Network: key}, []string{address}, settings[key])
+		if err != nil {
+			pool.Close()
+			return nil, fmt.Errorf("optional network %s: %w", name, err)
+		}
+
+		network.Start(HealthCheckInterval)
+		if backend := network.Backends()[0]; !backend.Healthy {
+			log.Warn().
+				Err(backend.LastError).
+				Str("network", name).
+				Str("address", address).
+				Msg("Optional network is not healthy at startup")
+		}
+
+		pool.networks[key] = network
+	}
+
+	return pool, nil
+}
+
+// Get returns the connection to the optional network, or a bad request error
+// for unknown networks.
+func (p *OptionalNetworkPool) Get(name string) (*BackendPool, error) {
+	p.mutex.RLock()
+	network, ok := p.networks[strings.ToLower(name)]
+	p.mutex.RUnlock()
+
+	if !ok {
+		return nil, BadRequest("unknown optional network %s", name)
+	}
+
+	return network, nil
+}
+
+// Conn returns a connection that sends every call to the optional network as
+// it is at the time of the call. Collectors of background pollers outlive
+// reloads, so they must not keep a connection a reload closes.
+func (p *OptionalNetworkPool) Conn(name string) (grpc.ClientConnInterface, error) {
+	if _, err := p.Get(name); err != nil {
+		return nil, err
+	}
+
+	return optionalNetworkConn{pool: p, name: name}, nil
+}
+
+type optionalNetworkConn struct {
+	pool *OptionalNetworkPool
+	name string
+}
+
+func (c optionalNetworkConn) Invoke(ctx context.Context, method string, args interface{}, reply interface{}, opts ...grpc.CallOption) error {
+	network, err := c.pool.Get(c.name)
+	if err != nil {
+		return err
+	}
+
+	return network.Invoke(ctx, method, args, reply, opts...)
+}
+
+func (c optionalNetworkConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	network, err := c.pool.Get(c.name)
+	if err != nil {
+		return nil, err
+	}
+
+	return network.NewStream(ctx, desc, method, opts...)
+}
+
+// Close closes the connections to all optional networks.
+func (p *OptionalNetworkPool) Close() {
+	_, networks := p.all()
+	for _, network := range networks {
+		network.Close()
+	}
+}
+
+// Swap serves the networks of next from now on. The replaced connections are
+// closed after gracePeriod, so in-flight requests can still finish with them.
+func (p *OptionalNetworkPool) Swap(next *OptionalNetworkPool, gracePeriod time.Duration) {
+	_, networks := next.all()
+
+	p.mutex.Lock()
+	replaced := p.networks
+	p.networks = networks
+	p.mutex.Unlock()
+
+	time.AfterFunc(gracePeriod, func() {
+		for _, network := range replaced {
+			network.Close()
+		}
+	})
+}
+
+// all returns the sorted network names and the connections, which are never
+// modified in place.
+func (p *OptionalNetworkPool) all() ([]string, map[string]*BackendPool) {
+	p.mutex.RLock()
+	networks := p.networks
+	p.mutex.RUnlock()
+
+	names := make([]string, 0, len(networks))
+	for name := range networks {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names, networks
+}
+
+func (p *OptionalNetworkPool) Describe(ch chan<- *prometheus.Desc) {
+	ch <- optionalNetworkUpDesc
+	ch <- optionalNetworkStateDesc
+}
+
+func (p *OptionalNetworkPool) Collect(ch chan<- prometheus.Metric) {
+	names, networks := p.all()
+	for _, name := range names {
+		network := networks[name]
+
+		var up float64
+		if network.Backends()[0].Healthy {
+			up = 1
+		}
+
+		ch <- prometheus.MustNewConstMetric(optionalNetworkUpDesc, prometheus.GaugeValue, up, name)
+
+		current := network.State()
+		for _, state := range connectivityStates {
+			var value float64
+			if state == current {
+				value = 1
+			}
+
+			ch <- prometheus.MustNewConstMetric(
+				optionalNetworkStateDesc,
+				prometheus.GaugeValue,
+				value,
+				name,
+				strings.ToLower(state.String()),
+			)
+		}
+	}
+}
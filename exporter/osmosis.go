@@ -0,0 +1,408 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type OsmosisCollector struct {
+	chain       *Chain
+	poolId      string
+	priceDenoms string
+}
+
+func NewOsmosisCollector(chain *Chain, poolId string, priceDenoms string) (*OsmosisCollector, error) {
+	if poolId == "" {
+		return nil, BadRequest("pool_id parameter is required")
+	}
+
+	if _, err := strconv.ParseUint(poolId, 10, 64); err != nil {
+		return nil, BadRequest("invalid pool_id %q, expected a pool number", poolId)
+	}
+
+	return &OsmosisCollector{
+		chain:       chain,
+		poolId:      poolId,
+		priceDenoms: priceDenoms,
+	}, nil
+}
+
+func (c *OsmosisCollector) Name() string {
+	return "osmosis"
+}
+
+func (c *OsmosisCollector) Labels() prometheus.Labels {
+	return c.chain.Labels()
+}
+
+var describeOsmosisMetrics = describeOnce(func() []prometheus.Collector {
+	return newOsmosisMetrics("").collectors()
+})
+
+func (c *OsmosisCollector) Describe(ch chan<- *prometheus.Desc) {
+	describeOsmosisMetrics(ch)
+}
+
+func (c *OsmosisCollector) Collect(ch chan<- prometheus.Metric) {
+	collectWithTimeout(c, ch)
+}
+
+func (c *OsmosisCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	sublogger := log.With().
+		Str("request_id", uuid.New().String()).
+		Logger()
+
+	info, ok := c.chain.ScrapeInfo(ctx)
+	if !ok {
+		sublogger.Warn().
+			Str("chain", c.chain.Name).
+			Msg("Chain ID and denom are not resolved yet, skipping")
+		return
+	}
+
+	observer := NewScrapeObserver(ctx, info.ChainID, c.Name())
+	defer observer.Finish(ch)
+
+	// Get osmosis data
+	client := newRestClient("lcd-osmosis.blockapsis.com")
+
+	wg := new(sync.WaitGroup)
+
+	osmosisPoolRes := poolResponse{}
+	osmosisTotalLiquidityRes := totalLiquidityResponse{}
+
+	var poolErr error
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer observer.Recover("Pool")
+		queryStart := time.Now()
+		res, err := client.getPool(ctx, c.poolId)
+		observer.Observe("Pool", queryStart, err)
+		if err != nil {
+			sublogger.Error().
+				Err(err).
+				Msg("Issue retreiving the pool")
+			poolErr = err
+		}
+		osmosisPoolRes = res
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer observer.Recover("TotalLiquidity")
+		queryStart := time.Now()
+		res, err := client.getTotalLiquidity(ctx, c.poolId)
+		observer.Observe("TotalLiquidity", queryStart, err)
+		if err != nil {
+			sublogger.Error().
+				Err(err).
+				Msg("Could not retrieve pools total liquidity")
+		}
+		osmosisTotalLiquidityRes = res
+	}()
+
+	wg.Wait()
+
+	// the pool metrics would all be zeros
+	if poolErr != nil {
+		observer.Fail(poolErr)
+		return
+	}
+
+	metrics := newOsmosisMetrics(info.ChainID)
+
+	// Set metric values
+	swapFee, err := strconv.ParseFloat(osmosisPoolRes.Pool.PoolParams.SwapFee, 64)
+	if err != nil {
+		sublogger.Error().
+			Err(err).
+			Str("pool_id", c.poolId).
+			Float64("swap_fee", swapFee).
+			Msg("Could not set the osmosis swap fee")
+	}
+	metrics.osmosisSwapFee.Set(swapFee)
+
+	exitFee, err := strconv.ParseFloat(osmosisPoolRes.Pool.PoolParams.ExitFee, 64)
+	if err != nil {
+		sublogger.Error().
+			Err(err).
+			Str("pool_id", c.poolId).
+			Float64("exit_fee", exitFee).
+			Msg("Could not set the osmosis exit fee")
+	}
+	metrics.osmosisExitFee.Set(exitFee)
+
+	poolWeight, err := strconv.ParseFloat(osmosisPoolRes.Pool.TotalWeight, 64)
+	if err != nil {
+		sublogger.Error().
+			Err(err).
+			Str("pool_id", c.poolId).
+			Float64("pool_weight", poolWeight).
+			Msg("Could not set the osmosis pool weight")
+	}
+	metrics.osmosisPoolWeight.Set(poolWeight)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer observer.Recover("TotalLiquidity")
+		for _, liquidity := range osmosisTotalLiquidityRes.Liquidity {
+			if strings.Contains(c.priceDenoms, liquidity.Denom) || c.priceDenoms == "" {
+				totalShares, err := ParseAmount(liquidity.Amount)
+				if err != nil {
+					sublogger.Error().
+						Err(err).
+						Str("pool_id", c.poolId).
+						Str("total_shares", liquidity.Amount).
+						Msg("Could not set the osmosis total shares")
+					continue
+				}
+				metrics.osmosisTotalPoolShares.Set(prometheus.Labels{"denom": liquidity.Denom}, totalShares)
+			}
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer observer.Recover("Pool")
+		for _, asset := range osmosisPoolRes.Pool.PoolAssets {
+			assetWeight, err := strconv.ParseFloat(asset.Weight, 64)
+			if err != nil {
+				sublogger.Error().
+					Err(err).
+					Str("pool_id", c.poolId).
+					Str("denom", asset.Token.Denom).
+					Float64("asset_weight", assetWeight).
+					Msg("Could not set the osmosis asset weight")
+			}
+			metrics.osmosisAssetWeight.With(prometheus.Labels{"denom": asset.Token.Denom}).Set(assetWeight)
+
+			assetAmount, err := ParseAmount(asset.Token.Amount)
+			if err != nil {
+				sublogger.Error().
+					Err(err).
+					Str("pool_id", c.poolId).
+					Str("denom", asset.Token.Denom).
+					Str("asset_amount", asset.Token.Amount).
+					Msg("Could not set the osmosis asset amount")
+				continue
+			}
+			metrics.osmosisAssetAmount.Set(prometheus.Labels{"denom": asset.Token.Denom}, assetAmount)
+		}
+	}()
+
+	wg.Wait()
+
+	collectAll(ch, metrics.collectors())
+}
+
+type restClient struct {
+	url        url.URL
+	httpClient *http.Client
+}
+
+func newRestClient(host string) *restClient {
+	var c restClient
+	c.url = url.URL{Host: host, Scheme: "https"}
+	c.httpClient = &http.Client{}
+	return &c
+}
+
+// request makes http request bound to ctx with specified path and optional query
+func (client *restClient) request(ctx context.Context, path string, query string) ([]byte, error) {
+	// avoid race condition with concurrent overwrites: work with copy of restClient's url object for each request!
+	ref := client.url
+	ref.Path = path
+	ref.RawQuery = query
+	url := ref.ResolveReference(&ref).String()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request %s: %v", url, err)
+	}
+
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("Content-Type", "application/json")
+
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &restStatusError{
+			StatusCode: resp.StatusCode,
+			Err:        fmt.Errorf("error making request %s: %s: %s", url, resp.Status, strings.ReplaceAll(strings.ReplaceAll(string(body), "\n", ""), "  ", " ")),
+		}
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// restStatusError is a non-200 response of the REST API.
+type restStatusError struct {
+	StatusCode int
+	Err        error
+}
+
+func (e *restStatusError) Error() string {
+	return e.Err.Error()
+}
+
+func (client *restClient) getPool(ctx context.Context, id string) (poolResponse, error) {
+	pool := poolResponse{}
+
+	res, err := client.request(ctx, "/osmosis/gamm/v1beta1/pools/"+id, "")
+	if err != nil {
+		return pool, err
+	}
+
+	err = json.Unmarshal(res, &pool)
+	if err != nil {
+		return pool, err
+	}
+
+	return pool, nil
+}
+
+func (client *restClient) getTotalLiquidity(ctx context.Context, id string) (totalLiquidityResponse, error) {
+	totalLiquidity := totalLiquidityResponse{}
+
+	res, err := client.request(ctx, "/osmosis/gamm/v1beta1/total_liquidity", "")
+
+	if err != nil {
+		return totalLiquidity, err
+	}
+
+	err = json.Unmarshal(res, &totalLiquidity)
+	if err != nil {
+		return totalLiquidity, err
+	}
+
+	return totalLiquidity, nil
+}
+
+type poolResponse struct {
+	Pool struct {
+		Type       string `json:"@type"`
+		Address    string `json:"address"`
+		ID         string `json:"id"`
+		PoolParams struct {
+			SwapFee                  string      `json:"swap_fee"`
+			ExitFee                  string      `json:"exit_fee"`
+			SmoothWeightChangeParams interface{} `json:"smooth_weight_change_params"`
+		} `json:"pool_params"`
+		FuturePoolGovernor string `json:"future_pool_governor"`
+		TotalShares        struct {
+			Denom  string `json:"denom"`
+			Amount string `json:"amount"`
+		} `json:"total_shares"`
+		PoolAssets []struct {
+			Token struct {
+				Denom  string `json:"denom"`
+				Amount string `json:"amount"`
+			} `json:"token"`
+			Weight string `json:"weight"`
+		} `json:"pool_assets"`
+		TotalWeight string `json:"total_weight"`
+	} `json:"pool"`
+}
+
+type totalLiquidityResponse struct {
+	Liquidity []struct {
+		Denom  string `json:"denom"`
+		Amount string `json:"amount"`
+	} `json:"liquidity"`
+}
+
+type osmosisMetrics struct {
+	osmosisSwapFee         prometheus.Gauge
+	osmosisExitFee         prometheus.Gauge
+	osmosisPoolWeight      prometheus.Gauge
+	osmosisAssetWeight     *prometheus.GaugeVec
+	osmosisAssetAmount     *AmountGaugeVec
+	osmosisTotalPoolShares *AmountGaugeVec
+}
+
+func newOsmosisMetrics(chainID string) *osmosisMetrics {
+	return &osmosisMetrics{
+		osmosisSwapFee: newChainGauge(
+			prometheus.GaugeOpts{
+				Name: "osmosis_swap_fee",
+				Help: "",
+			},
+			chainID,
+		),
+
+		osmosisExitFee: newChainGauge(
+			prometheus.GaugeOpts{
+				Name: "osmosis_exit_fee",
+				Help: "",
+			},
+			chainID,
+		),
+
+		osmosisPoolWeight: newChainGauge(
+			prometheus.GaugeOpts{
+				Name: "osmosis_pool_weight",
+				Help: "",
+			},
+			chainID,
+		),
+
+		osmosisAssetWeight: newChainGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "osmosis_pool_asset_weight",
+				Help: "",
+			},
+			chainID,
+			[]string{"denom"},
+		),
+
+		osmosisAssetAmount: newChainAmountGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "osmosis_pool_asset_amount",
+				Help: "",
+			},
+			chainID,
+			[]string{"denom"},
+		),
+
+		osmosisTotalPoolShares: newChainAmountGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "osmosis_total_pool_shares",
+				Help: "",
+			},
+			chainID,
+			[]string{"denom"},
+		),
+	}
+}
+
+func (m *osmosisMetrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		m.osmosisSwapFee,
+		m.osmosisExitFee,
+		m.osmosisPoolWeight,
+		m.osmosisAssetWeight,
+		m.osmosisAssetAmount,
+		m.osmosisTotalPoolShares,
+	}
+}
@@ -0,0 +1,403 @@
+package exporter
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	otlpProtocolGRPC = "grpc"
+	otlpProtocolHTTP = "http/protobuf"
+
+	otlpExportMethod = "/opentelemetry.proto.collector.metrics.v1.MetricsService/Export"
+
+	// otlpGRPCPort is the port collectors receive OTLP over gRPC on.
+	otlpGRPCPort = "4317"
+)
+
+// otlpResourceLabels are the labels of the chain, which are the same for all
+// the metrics of a chain, so they're sent as resource attributes instead of
+// data point attributes.
+var otlpResourceLabels = []string{"chain_id"}
+
+// OTLPReceiver sends the metrics to an OpenTelemetry collector over OTLP, with
+// gRPC or HTTP. The metrics of every chain are a resource with its chain_id
+// and the external labels as attributes. Counters, histograms and summaries
+// are cumulative since the exporter started.
+type OTLPReceiver struct {
+	url            string
+	externalLabels map[string]string
+	startTime      time.Time
+
+	client *http.Client
+	conn   *grpc.ClientConn
+}
+
+// NewOTLPReceiver sends to address, an http:// or https:// URL. The
+// http/protobuf protocol posts to its /v1/metrics path unless it has one,
+// gRPC connects to its host, on port 4317 unless it has one, with TLS for
+// https://.
+func NewOTLPReceiver(address, protocol string, externalLabels map[string]string) (*OTLPReceiver, error) {
+	parsed, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &OTLPReceiver{
+		externalLabels: externalLabels,
+		startTime:      time.Now(),
+	}
+
+	switch protocol {
+	case otlpProtocolHTTP:
+		if strings.Trim(parsed.Path, "/") == "" {
+			parsed.Path = "/v1/metrics"
+		}
+
+		r.url = parsed.String()
+		r.client = &http.Client{}
+	case otlpProtocolGRPC:
+		var options []grpc.DialOption
+		if parsed.Scheme == "https" {
+			options = append(options, grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})))
+		} else {
+			options = append(options, grpc.WithInsecure())
+		}
+
+		if len(PushSettings.Headers) > 0 {
+			options = append(options, grpc.WithPerRPCCredentials(staticHeaders(PushSettings.Headers)))
+		}
+
+		r.url = parsed.Host
+		if parsed.Port() == "" {
+			r.url = net.JoinHostPort(parsed.Hostname(), otlpGRPCPort)
+		}
+
+		r.conn, err = grpc.Dial(r.url, options...)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown OTLP protocol %q", protocol)
+	}
+
+	return r, nil
+}
+
+func (r *OTLPReceiver) Name() string {
+	return "otlp"
+}
+
+func (r *OTLPReceiver) Buffered() bool {
+	return true
+}
+
+func (r *OTLPReceiver) Send(ctx context.Context, body []byte) error {
+	if r.conn == nil {
+		return sendPush(ctx, r.client, http.MethodPost, r.url, body, map[string]string{
+			"Content-Type": "application/x-protobuf",
+		})
+	}
+
+	// the ExportMetricsServiceResponse is only a partial success report,
+	// which doesn't change whether the push is retried
+	var reply []byte
+	return r.conn.Invoke(ctx, otlpExportMethod, body, &reply, grpc.ForceCodec(otlpCodec{}))
+}
+
+// Close closes the gRPC connection, if any.
+func (r *OTLPReceiver) Close() error {
+	if r.conn == nil {
+		return nil
+	}
+
+	return r.conn.Close()
+}
+
+// otlpCodec passes the already encoded request and the raw response through
+// gRPC as they are.
+type otlpCodec struct{}
+
+func (otlpCodec) Name() string {
+	return "proto"
+}
+
+func (otlpCodec) Marshal(v interface{}) ([]byte, error) {
+	body, ok := v.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("cannot marshal %T", v)
+	}
+
+	return body, nil
+}
+
+func (otlpCodec) Unmarshal(data []byte, v interface{}) error {
+	body, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("cannot unmarshal into %T", v)
+	}
+
+	*body = append((*body)[:0], data...)
+	return nil
+}
+
+// Encode returns the ExportMetricsServiceRequest of families. The data points
+// of a family are split into one Metric per resource, so every resource only
+// has the data points of its own chain. The request is encoded as a
+// MetricsData, which has the same fields, so the collector package and the
+// gRPC gateway it depends on aren't needed.
+func (r *OTLPReceiver) Encode(families []*dto.MetricFamily, timestamp time.Time) ([]byte, error) {
+	request := &metricspb.MetricsData{}
+	byKey := make(map[string]*metricspb.ScopeMetrics)
+
+	for _, family := range families {
+		var keys []string
+		groups := make(map[string][]*dto.Metric)
+
+		for _, metric := range family.Metric {
+			key := r.resourceKey(metric)
+			if _, ok := groups[key]; !ok {
+				keys = append(keys, key)
+			}
+
+			groups[key] = append(groups[key], metric)
+		}
+
+		for _, key := range keys {
+			scope, ok := byKey[key]
+			if !ok {
+				scope = &metricspb.ScopeMetrics{
+					Scope: &commonpb.InstrumentationScope{Name: "cosmos-exporter"},
+				}
+				byKey[key] = scope
+
+				request.ResourceMetrics = append(request.ResourceMetrics, &metricspb.ResourceMetrics{
+					Resource:     r.resource(groups[key][0]),
+					ScopeMetrics: []*metricspb.ScopeMetrics{scope},
+				})
+			}
+
+			if metric := r.metric(family, groups[key], timestamp); metric != nil {
+				scope.Metrics = append(scope.Metrics, metric)
+			}
+		}
+	}
+
+	return proto.Marshal(request)
+}
+
+// resourceKey identifies the resource of metric by its resource labels.
+func (r *OTLPReceiver) resourceKey(metric *dto.Metric) string {
+	var key strings.Builder
+
+	for _, name := range otlpResourceLabels {
+		for _, pair := range metric.Label {
+			if pair.GetName() == name {
+				fmt.Fprintf(&key, "%s=%q,", name, pair.GetValue())
+			}
+		}
+	}
+
+	return key.String()
+}
+
+// resource returns the resource of metric, with the service name, the
+// external labels and its resource labels as attributes, sorted by name.
+func (r *OTLPReceiver) resource(metric *dto.Metric) *resourcepb.Resource {
+	attributes := map[string]string{"service.name": "cosmos-exporter"}
+
+	for name, value := range r.externalLabels {
+		attributes[name] = value
+	}
+
+	for _, pair := range metric.Label {
+		if isResourceLabel(pair.GetName()) {
+			attributes[pair.GetName()] = pair.GetValue()
+		}
+	}
+
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	resource := &resourcepb.Resource{}
+	for _, name := range names {
+		resource.Attributes = append(resource.Attributes, otlpKeyValue(name, attributes[name]))
+	}
+
+	return resource
+}
+
+func isResourceLabel(name string) bool {
+	for _, label := range otlpResourceLabels {
+		if name == label {
+			return true
+		}
+	}
+
+	return false
+}
+
+// metric returns the Metric of the data points of family in metrics, or nil
+// for a type OTLP has no equivalent of. Gauges and summaries only have data
+// points, sums and histograms are cumulative, and counters are monotonic.
+func (r *OTLPReceiver) metric(family *dto.MetricFamily, metrics []*dto.Metric, timestamp time.Time) *metricspb.Metric {
+	encoded := &metricspb.Metric{
+		Name:        family.GetName(),
+		Description: family.GetHelp(),
+	}
+
+	start := uint64(r.startTime.UnixNano())
+
+	switch family.GetType() {
+	case dto.MetricType_GAUGE, dto.MetricType_UNTYPED:
+		gauge := &metricspb.Gauge{}
+		for _, metric := range metrics {
+			value := metric.GetGauge().GetValue()
+			if family.GetType() == dto.MetricType_UNTYPED {
+				value = metric.GetUntyped().GetValue()
+			}
+
+			gauge.DataPoints = append(gauge.DataPoints, r.numberPoint(metric, 0, pointTime(metric, timestamp), value))
+		}
+		encoded.Data = &metricspb.Metric_Gauge{Gauge: gauge}
+	case dto.MetricType_COUNTER:
+		sum := &metricspb.Sum{
+			AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+			IsMonotonic:            true,
+		}
+		for _, metric := range metrics {
+			sum.DataPoints = append(sum.DataPoints, r.numberPoint(metric, start, pointTime(metric, timestamp), metric.GetCounter().GetValue()))
+		}
+		encoded.Data = &metricspb.Metric_Sum{Sum: sum}
+	case dto.MetricType_HISTOGRAM:
+		histogram := &metricspb.Histogram{
+			AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+		}
+		for _, metric := range metrics {
+			histogram.DataPoints = append(histogram.DataPoints, r.histogramPoint(metric, start, pointTime(metric, timestamp)))
+		}
+		encoded.Data = &metricspb.Metric_Histogram{Histogram: histogram}
+	case dto.MetricType_SUMMARY:
+		summary := &metricspb.Summary{}
+		for _, metric := range metrics {
+			summary.DataPoints = append(summary.DataPoints, r.summaryPoint(metric, start, pointTime(metric, timestamp)))
+		}
+		encoded.Data = &metricspb.Metric_Summary{Summary: summary}
+	default:
+		return nil
+	}
+
+	return encoded
+}
+
+// pointTime is the time of the data point of metric in nanoseconds, its own
+// timestamp if it has one, or the time of the push.
+func pointTime(metric *dto.Metric, timestamp time.Time) uint64 {
+	if metric.TimestampMs != nil {
+		return uint64(metric.GetTimestampMs()) * uint64(time.Millisecond)
+	}
+
+	return uint64(timestamp.UnixNano())
+}
+
+// numberPoint returns a NumberDataPoint. Gauges have no start time.
+func (r *OTLPReceiver) numberPoint(metric *dto.Metric, start, nanoseconds uint64, value float64) *metricspb.NumberDataPoint {
+	return &metricspb.NumberDataPoint{
+		Attributes:        r.attributes(metric),
+		StartTimeUnixNano: start,
+		TimeUnixNano:      nanoseconds,
+		Value:             &metricspb.NumberDataPoint_AsDouble{AsDouble: value},
+	}
+}
+
+// histogramPoint returns a HistogramDataPoint. Prometheus buckets count all
+// the samples up to their bound, OTLP buckets only those above the previous
+// bound, and the +Inf bucket is implied by the bounds.
+func (r *OTLPReceiver) histogramPoint(metric *dto.Metric, start, nanoseconds uint64) *metricspb.HistogramDataPoint {
+	histogram := metric.GetHistogram()
+	sum := histogram.GetSampleSum()
+
+	point := &metricspb.HistogramDataPoint{
+		Attributes:        r.attributes(metric),
+		StartTimeUnixNano: start,
+		TimeUnixNano:      nanoseconds,
+		Count:             histogram.GetSampleCount(),
+		Sum:               &sum,
+	}
+
+	var previous uint64
+	for _, bucket := range histogram.Bucket {
+		if math.IsInf(bucket.GetUpperBound(), 1) {
+			break
+		}
+
+		point.ExplicitBounds = append(point.ExplicitBounds, bucket.GetUpperBound())
+		point.BucketCounts = append(point.BucketCounts, bucket.GetCumulativeCount()-previous)
+		previous = bucket.GetCumulativeCount()
+	}
+	point.BucketCounts = append(point.BucketCounts, histogram.GetSampleCount()-previous)
+
+	return point
+}
+
+// summaryPoint returns a SummaryDataPoint.
+func (r *OTLPReceiver) summaryPoint(metric *dto.Metric, start, nanoseconds uint64) *metricspb.SummaryDataPoint {
+	summary := metric.GetSummary()
+
+	point := &metricspb.SummaryDataPoint{
+		Attributes:        r.attributes(metric),
+		StartTimeUnixNano: start,
+		TimeUnixNano:      nanoseconds,
+		Count:             summary.GetSampleCount(),
+		Sum:               summary.GetSampleSum(),
+	}
+
+	for _, quantile := range summary.Quantile {
+		point.QuantileValues = append(point.QuantileValues, &metricspb.SummaryDataPoint_ValueAtQuantile{
+			Quantile: quantile.GetQuantile(),
+			Value:    quantile.GetValue(),
+		})
+	}
+
+	return point
+}
+
+// attributes returns the labels of metric, but the resource labels, as the
+// attributes of a data point.
+func (r *OTLPReceiver) attributes(metric *dto.Metric) []*commonpb.KeyValue {
+	var attributes []*commonpb.KeyValue
+	for _, pair := range metric.Label {
+		if !isResourceLabel(pair.GetName()) {
+			attributes = append(attributes, otlpKeyValue(pair.GetName(), pair.GetValue()))
+		}
+	}
+
+	return attributes
+}
+
+// otlpKeyValue returns a KeyValue with a string value.
+func otlpKeyValue(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   key,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}},
+	}
+}
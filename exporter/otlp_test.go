@@ -0,0 +1,213 @@
+package exporter
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestOTLPReceiverEncode(t *testing.T) {
+	start := time.Unix(1500000000, 0)
+	timestamp := time.Unix(1600000000, 0)
+
+	chainLabels := func(chainID string, pairs ...string) []*dto.LabelPair {
+		labels := []*dto.LabelPair{{Name: proto.String("chain_id"), Value: proto.String(chainID)}}
+		for i := 0; i < len(pairs); i += 2 {
+			labels = append(labels, &dto.LabelPair{Name: proto.String(pairs[i]), Value: proto.String(pairs[i+1])})
+		}
+		return labels
+	}
+
+	families := []*dto.MetricFamily{
+		{
+			Name: proto.String("cosmos_validator_tokens"),
+			Help: proto.String("Tokens of the validator"),
+			Type: dto.MetricType_GAUGE.Enum(),
+			Metric: []*dto.Metric{
+				{Label: chainLabels("cosmoshub-4", "address", "cosmosvaloper1abc"), Gauge: &dto.Gauge{Value: proto.Float64(1.5)}},
+				{Label: chainLabels("cudos-1", "address", "cudosvaloper1abc"), Gauge: &dto.Gauge{Value: proto.Float64(2)}},
+			},
+		},
+		{
+			Name: proto.String("cosmos_exporter_pushes_total"),
+			Help: proto.String("Pushes"),
+			Type: dto.MetricType_COUNTER.Enum(),
+			Metric: []*dto.Metric{{
+				Label:       chainLabels("cosmoshub-4"),
+				Counter:     &dto.Counter{Value: proto.Float64(3)},
+				TimestampMs: proto.Int64(1550000000000),
+			}},
+		},
+		{
+			Name: proto.String("request_seconds"),
+			Help: proto.String("Requests"),
+			Type: dto.MetricType_HISTOGRAM.Enum(),
+			Metric: []*dto.Metric{{
+				Label: chainLabels("cudos-1", "method", "Validators"),
+				Histogram: &dto.Histogram{
+					SampleCount: proto.Uint64(6),
+					SampleSum:   proto.Float64(2.5),
+					Bucket: []*dto.Bucket{
+						{UpperBound: proto.Float64(0.5), CumulativeCount: proto.Uint64(1)},
+						{UpperBound: proto.Float64(1), CumulativeCount: proto.Uint64(4)},
+					},
+				},
+			}},
+		},
+		{
+			Name: proto.String("gc_seconds"),
+			Help: proto.String("GC"),
+			Type: dto.MetricType_SUMMARY.Enum(),
+			Metric: []*dto.Metric{{
+				Summary: &dto.Summary{
+					SampleCount: proto.Uint64(10),
+					SampleSum:   proto.Float64(0.25),
+					Quantile: []*dto.Quantile{
+						{Quantile: proto.Float64(0.5), Value: proto.Float64(0.01)},
+						{Quantile: proto.Float64(1), Value: proto.Float64(0.1)},
+					},
+				},
+			}},
+		},
+	}
+
+	receiver := &OTLPReceiver{
+		externalLabels: map[string]string{"instance": "validator-1"},
+		startTime:      start,
+	}
+
+	body, err := receiver.Encode(families, timestamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// an ExportMetricsServiceRequest has the same fields as MetricsData
+	var request metricspb.MetricsData
+	if err := proto.Unmarshal(body, &request); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, resource := range request.ResourceMetrics {
+		got = append(got, "resource "+formatOTLPAttributes(resource.Resource.Attributes))
+
+		for _, scope := range resource.ScopeMetrics {
+			if scope.Scope.Name != "cosmos-exporter" {
+				t.Errorf("scope name = %q, want cosmos-exporter", scope.Scope.Name)
+			}
+
+			for _, metric := range scope.Metrics {
+				got = append(got, formatOTLPMetric(t, metric)...)
+			}
+		}
+	}
+
+	want := []string{
+		`resource {chain_id="cosmoshub-4",instance="validator-1",service.name="cosmos-exporter"}`,
+		`gauge cosmos_validator_tokens "Tokens of the validator" {address="cosmosvaloper1abc"} 1.5 @0-1600000000`,
+		`sum cumulative monotonic cosmos_exporter_pushes_total "Pushes" {} 3 @1500000000-1550000000`,
+		`resource {chain_id="cudos-1",instance="validator-1",service.name="cosmos-exporter"}`,
+		`gauge cosmos_validator_tokens "Tokens of the validator" {address="cudosvaloper1abc"} 2 @0-1600000000`,
+		`histogram cumulative request_seconds "Requests" {method="Validators"} count 6 sum 2.5 bounds [0.5 1] counts [1 3 2] @1500000000-1600000000`,
+		`resource {instance="validator-1",service.name="cosmos-exporter"}`,
+		`summary gc_seconds "GC" {} count 10 sum 0.25 quantiles 0.5=0.01 1=0.1 @1500000000-1600000000`,
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded request:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func formatOTLPAttributes(attributes []*commonpb.KeyValue) string {
+	pairs := make([]string, len(attributes))
+	for i, attribute := range attributes {
+		pairs[i] = fmt.Sprintf("%s=%q", attribute.Key, attribute.Value.GetStringValue())
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// formatOTLPTimes formats the start and time of a data point in seconds.
+func formatOTLPTimes(start, end uint64) string {
+	return fmt.Sprintf("@%d-%d", start/1e9, end/1e9)
+}
+
+// formatOTLPMetric formats every data point of metric on its own line.
+func formatOTLPMetric(t *testing.T, metric *metricspb.Metric) []string {
+	t.Helper()
+
+	var lines []string
+	header := fmt.Sprintf("%s %q", metric.Name, metric.Description)
+
+	switch data := metric.Data.(type) {
+	case *metricspb.Metric_Gauge:
+		for _, point := range data.Gauge.DataPoints {
+			lines = append(lines, fmt.Sprintf("gauge %s %s %v %s", header, formatOTLPAttributes(point.Attributes), point.GetAsDouble(), formatOTLPTimes(point.StartTimeUnixNano, point.TimeUnixNano)))
+		}
+	case *metricspb.Metric_Sum:
+		if data.Sum.AggregationTemporality != metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE || !data.Sum.IsMonotonic {
+			t.Errorf("sum %s isn't cumulative and monotonic", metric.Name)
+		}
+		for _, point := range data.Sum.DataPoints {
+			lines = append(lines, fmt.Sprintf("sum cumulative monotonic %s %s %v %s", header, formatOTLPAttributes(point.Attributes), point.GetAsDouble(), formatOTLPTimes(point.StartTimeUnixNano, point.TimeUnixNano)))
+		}
+	case *metricspb.Metric_Histogram:
+		if data.Histogram.AggregationTemporality != metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE {
+			t.Errorf("histogram %s isn't cumulative", metric.Name)
+		}
+		for _, point := range data.Histogram.DataPoints {
+			lines = append(lines, fmt.Sprintf("histogram cumulative %s %s count %d sum %v bounds %v counts %v %s", header, formatOTLPAttributes(point.Attributes), point.Count, point.GetSum(), point.ExplicitBounds, point.BucketCounts, formatOTLPTimes(point.StartTimeUnixNano, point.TimeUnixNano)))
+		}
+	case *metricspb.Metric_Summary:
+		for _, point := range data.Summary.DataPoints {
+			quantiles := make([]string, len(point.QuantileValues))
+			for i, quantile := range point.QuantileValues {
+				quantiles[i] = fmt.Sprintf("%v=%v", quantile.Quantile, quantile.Value)
+			}
+			lines = append(lines, fmt.Sprintf("summary %s %s count %d sum %v quantiles %s %s", header, formatOTLPAttributes(point.Attributes), point.Count, point.Sum, strings.Join(quantiles, " "), formatOTLPTimes(point.StartTimeUnixNano, point.TimeUnixNano)))
+		}
+	default:
+		t.Errorf("metric %s has unexpected data %T", metric.Name, data)
+	}
+
+	return lines
+}
+
+func TestNewOTLPReceiver(t *testing.T) {
+	tests := []struct {
+		address  string
+		protocol string
+		want     string
+	}{
+		{"http://otel-collector", otlpProtocolGRPC, "otel-collector:4317"},
+		{"https://otel-collector:443", otlpProtocolGRPC, "otel-collector:443"},
+		{"http://[::1]", otlpProtocolGRPC, "[::1]:4317"},
+		{"http://otel-collector:4318", otlpProtocolHTTP, "http://otel-collector:4318/v1/metrics"},
+		{"https://otlp.example.com/otlp/v1/metrics", otlpProtocolHTTP, "https://otlp.example.com/otlp/v1/metrics"},
+	}
+
+	for _, test := range tests {
+		receiver, err := NewOTLPReceiver(test.address, test.protocol, nil)
+		if err != nil {
+			t.Errorf("NewOTLPReceiver(%q, %q) error = %v", test.address, test.protocol, err)
+			continue
+		}
+
+		if receiver.url != test.want {
+			t.Errorf("NewOTLPReceiver(%q, %q) sends to %q, want %q", test.address, test.protocol, receiver.url, test.want)
+		}
+
+		receiver.Close()
+	}
+
+	if _, err := NewOTLPReceiver("http://otel-collector", "json", nil); err == nil {
+		t.Error("NewOTLPReceiver() with an unknown protocol didn't fail")
+	}
+}
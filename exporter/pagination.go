@@ -0,0 +1,43 @@
+package exporter
+
+import (
+	"fmt"
+
+	querytypes "github.com/cosmos/cosmos-sdk/types/query"
+)
+
+// pageFetcher queries a single page of a list query and returns the
+// pagination of the response.
+type pageFetcher func(pageRequest *querytypes.PageRequest) (*querytypes.PageResponse, error)
+
+// paginate calls fetch for every page of a list query, --limit items at a
+// time, following NextKey until the node returns the last page. It fails
+// rather than returning truncated results if there are more than --max-pages
+// pages, and records how many pages were fetched. observer is nil for queries
+// outside of scrapes.
+func paginate(observer *ScrapeObserver, query string, fetch pageFetcher) error {
+	pageRequest := &querytypes.PageRequest{Limit: Limit}
+
+	for pages := 1; ; pages++ {
+		pageResponse, err := fetch(pageRequest)
+		if err != nil {
+			return err
+		}
+
+		if pageResponse == nil || len(pageResponse.NextKey) == 0 {
+			if observer != nil {
+				observer.ObservePages(query, pages)
+			}
+			return nil
+		}
+
+		if pages >= MaxPages {
+			if observer != nil {
+				observer.ObservePages(query, pages)
+			}
+			return fmt.Errorf("%s has more than %d pages of %d items, increase --max-pages or --limit", query, MaxPages, Limit)
+		}
+
+		pageRequest = &querytypes.PageRequest{Key: pageResponse.NextKey, Limit: Limit}
+	}
+}
@@ -0,0 +1,106 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	querytypes "github.com/cosmos/cosmos-sdk/types/query"
+)
+
+func TestPaginate(t *testing.T) {
+	defer func(limit uint64, maxPages int) {
+		Limit, MaxPages = limit, maxPages
+	}(Limit, MaxPages)
+
+	Limit, MaxPages = 100, 3
+
+	fetchErr := errors.New("node is down")
+
+	tests := []struct {
+		name string
+		// pages is how many pages the node has, failAt the page that
+		// fails, if any
+		pages     int
+		failAt    int
+		nilResult bool
+
+		wantFetches int
+		wantPages   int
+		wantErr     bool
+	}{
+		{name: "single page", pages: 1, wantFetches: 1, wantPages: 1},
+		{name: "last page", pages: 3, wantFetches: 3, wantPages: 3},
+		{name: "nil pagination", pages: 5, nilResult: true, wantFetches: 1, wantPages: 1},
+		{name: "more than max pages", pages: 4, wantFetches: 3, wantPages: 3, wantErr: true},
+		{name: "failed page", pages: 3, failAt: 2, wantFetches: 2, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			observer := NewScrapeObserver(context.Background(), "chain", "validators")
+
+			var keys [][]byte
+			err := paginate(observer, "Validators", func(pageRequest *querytypes.PageRequest) (*querytypes.PageResponse, error) {
+				keys = append(keys, pageRequest.Key)
+				page := len(keys)
+
+				if pageRequest.Limit != Limit {
+					t.Errorf("page %d has limit %d, want %d", page, pageRequest.Limit, Limit)
+				}
+
+				if page == test.failAt {
+					return nil, fetchErr
+				}
+
+				if test.nilResult {
+					return nil, nil
+				}
+
+				response := &querytypes.PageResponse{}
+				if page < test.pages {
+					response.NextKey = []byte(fmt.Sprintf("key-%d", page))
+				}
+
+				return response, nil
+			})
+
+			if (err != nil) != test.wantErr {
+				t.Fatalf("paginate() error = %v, want error %t", err, test.wantErr)
+			}
+
+			if len(keys) != test.wantFetches {
+				t.Fatalf("fetched %d pages, want %d", len(keys), test.wantFetches)
+			}
+
+			// every page after the first asks for the NextKey of the one
+			// before
+			for i, key := range keys {
+				var want []byte
+				if i > 0 {
+					want = []byte(fmt.Sprintf("key-%d", i))
+				}
+
+				if !bytes.Equal(key, want) {
+					t.Errorf("page %d has key %q, want %q", i+1, key, want)
+				}
+			}
+
+			if got := observer.pages["Validators"]; got != test.wantPages {
+				t.Errorf("observed %d pages, want %d", got, test.wantPages)
+			}
+		})
+	}
+}
+
+func TestPaginateWithoutObserver(t *testing.T) {
+	err := paginate(nil, "Validators", func(pageRequest *querytypes.PageRequest) (*querytypes.PageResponse, error) {
+		return &querytypes.PageResponse{}, nil
+	})
+
+	if err != nil {
+		t.Errorf("paginate() error = %v", err)
+	}
+}
@@ -0,0 +1,477 @@
+package exporter
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	distributiontypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	minttypes "github.com/cosmos/cosmos-sdk/x/mint/types"
+	slashingtypes "github.com/cosmos/cosmos-sdk/x/slashing/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type ParamsCollector struct {
+	chain *Chain
+}
+
+func NewParamsCollector(chain *Chain) *ParamsCollector {
+	return &ParamsCollector{
+		chain: chain,
+	}
+}
+
+func (c *ParamsCollector) Name() string {
+	return "params"
+}
+
+func (c *ParamsCollector) Labels() prometheus.Labels {
+	return c.chain.Labels()
+}
+
+var describeParamsMetrics = describeOnce(func() []prometheus.Collector {
+	return newParamsMetrics("").collectors()
+})
+
+func (c *ParamsCollector) Describe(ch chan<- *prometheus.Desc) {
+	describeParamsMetrics(ch)
+}
+
+func (c *ParamsCollector) Collect(ch chan<- prometheus.Metric) {
+	collectWithTimeout(c, ch)
+}
+
+func (c *ParamsCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	info, ok := c.chain.ScrapeInfo(ctx)
+	if !ok {
+		log.Warn().
+			Str("chain", c.chain.Name).
+			Msg("Chain ID and denom are not resolved yet, skipping")
+		return
+	}
+
+	observer := NewScrapeObserver(ctx, info.ChainID, c.Name())
+	defer observer.Finish(ch)
+
+	metrics := newParamsMetrics(info.ChainID)
+	metrics.set(c.query(ctx, observer))
+	collectAll(ch, metrics.collectors())
+}
+
+// Query returns the params CollectContext exports, for the API.
+func (c *ParamsCollector) Query(ctx context.Context) interface{} {
+	info, ok := c.chain.ScrapeInfo(ctx)
+	if !ok {
+		return nil
+	}
+
+	observer := NewScrapeObserver(ctx, info.ChainID, c.Name())
+	defer observer.Done()
+
+	return c.query(ctx, observer)
+}
+
+// query queries the params, leaving out the ones whose query failed.
+func (c *ParamsCollector) query(ctx context.Context, observer *ScrapeObserver) APIParams {
+	sublogger := log.With().
+		Str("request-id", uuid.New().String()).
+		Logger()
+
+	var params APIParams
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer observer.Recover("StakingParams")
+		sublogger.Debug().Msg("Started querying global staking params")
+		queryStart := time.Now()
+
+		stakingClient := stakingtypes.NewQueryClient(c.chain.GRPC)
+		paramsResponse, err := stakingClient.Params(
+			ctx,
+			&stakingtypes.QueryParamsRequest{},
+		)
+		observer.Observe("StakingParams", queryStart, err)
+		if err != nil {
+			sublogger.Error().
+				Err(err).
+				Msg("Could not get global staking params")
+			return
+		}
+
+		sublogger.Debug().
+			Float64("request-time", time.Since(queryStart).Seconds()).
+			Msg("Finished querying global staking params")
+
+		maxValidators := int64(paramsResponse.Params.MaxValidators)
+		unbondingTime := paramsResponse.Params.UnbondingTime.Seconds()
+		params.MaxValidators = &maxValidators
+		params.UnbondingTime = &unbondingTime
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer observer.Recover("MintParams")
+		sublogger.Debug().Msg("Started querying global mint params")
+		queryStart := time.Now()
+
+		mintClient := minttypes.NewQueryClient(c.chain.GRPC)
+		paramsResponse, err := mintClient.Params(
+			ctx,
+			&minttypes.QueryParamsRequest{},
+		)
+		observer.Observe("MintParams", queryStart, err)
+		if err != nil {
+			sublogger.Error().
+				Err(err).
+				Msg("Could not get global mint params")
+			return
+		}
+
+		sublogger.Debug().
+			Float64("request-time", time.Since(queryStart).Seconds()).
+			Msg("Finished querying global mint params")
+
+		blocksPerYear := int64(paramsResponse.Params.BlocksPerYear)
+		params.BlocksPerYear = &blocksPerYear
+
+		// because cosmos's dec doesn't have .toFloat64() method or whatever and returns everything as int
+		if value, err := strconv.ParseFloat(paramsResponse.Params.GoalBonded.String(), 64); err != nil {
+			sublogger.Error().
+				Err(err).
+				Msg("Could not parse goal bonded")
+		} else {
+			params.GoalBonded = &value
+		}
+
+		if value, err := strconv.ParseFloat(paramsResponse.Params.InflationMin.String(), 64); err != nil {
+			sublogger.Error().
+				Err(err).
+				Msg("Could not parse inflation min")
+		} else {
+			params.InflationMin = &value
+		}
+
+		if value, err := strconv.ParseFloat(paramsResponse.Params.InflationMax.String(), 64); err != nil {
+			sublogger.Error().
+				Err(err).
+				Msg("Could not parse inflation min")
+		} else {
+			params.InflationMax = &value
+		}
+
+		if value, err := strconv.ParseFloat(paramsResponse.Params.InflationRateChange.String(), 64); err != nil {
+			sublogger.Error().
+				Err(err).
+				Msg("Could not parse inflation rate change")
+		} else {
+			params.InflationRateChange = &value
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer observer.Recover("SlashingParams")
+		sublogger.Debug().Msg("Started querying global slashing params")
+		queryStart := time.Now()
+
+		slashingClient := slashingtypes.NewQueryClient(c.chain.GRPC)
+		paramsResponse, err := slashingClient.Params(
+			ctx,
+			&slashingtypes.QueryParamsRequest{},
+		)
+		observer.Observe("SlashingParams", queryStart, err)
+		if err != nil {
+			sublogger.Error().
+				Err(err).
+				Msg("Could not get global slashing params")
+			return
+		}
+
+		sublogger.Debug().
+			Float64("request-time", time.Since(queryStart).Seconds()).
+			Msg("Finished querying global slashing params")
+
+		downtimeJailDuration := paramsResponse.Params.DowntimeJailDuration.Seconds()
+		signedBlocksWindow := paramsResponse.Params.SignedBlocksWindow
+		params.DowntimeJailDuration = &downtimeJailDuration
+		params.SignedBlocksWindow = &signedBlocksWindow
+
+		if value, err := strconv.ParseFloat(paramsResponse.Params.MinSignedPerWindow.String(), 64); err != nil {
+			sublogger.Error().
+				Err(err).
+				Msg("Could not parse min signed per window")
+		} else {
+			params.MinSignedPerWindow = &value
+		}
+
+		if value, err := strconv.ParseFloat(paramsResponse.Params.SlashFractionDoubleSign.String(), 64); err != nil {
+			sublogger.Error().
+				Err(err).
+				Msg("Could not parse slash fraction double sign")
+		} else {
+			params.SlashFractionDoubleSign = &value
+		}
+
+		if value, err := strconv.ParseFloat(paramsResponse.Params.SlashFractionDowntime.String(), 64); err != nil {
+			sublogger.Error().
+				Err(err).
+				Msg("Could not parse slash fraction downtime")
+		} else {
+			params.SlashFractionDowntime = &value
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer observer.Recover("DistributionParams")
+		sublogger.Debug().Msg("Started querying global distribution params")
+		queryStart := time.Now()
+
+		distributionClient := distributiontypes.NewQueryClient(c.chain.GRPC)
+		paramsResponse, err := distributionClient.Params(
+			ctx,
+			&distributiontypes.QueryParamsRequest{},
+		)
+		observer.Observe("DistributionParams", queryStart, err)
+		if err != nil {
+			sublogger.Error().
+				Err(err).
+				Msg("Could not get global distribution params")
+			return
+		}
+
+		sublogger.Debug().
+			Float64("request-time", time.Since(queryStart).Seconds()).
+			Msg("Finished querying global distribution params")
+
+		// because cosmos's dec doesn't have .toFloat64() method or whatever and returns everything as int
+		if value, err := strconv.ParseFloat(paramsResponse.Params.BaseProposerReward.String(), 64); err != nil {
+			sublogger.Error().
+				Err(err).
+				Msg("Could not parse base proposer reward")
+		} else {
+			params.BaseProposerReward = &value
+		}
+
+		if value, err := strconv.ParseFloat(paramsResponse.Params.BonusProposerReward.String(), 64); err != nil {
+			sublogger.Error().
+				Err(err).
+				Msg("Could not parse bonus proposer reward")
+		} else {
+			params.BonusProposerReward = &value
+		}
+
+		if value, err := strconv.ParseFloat(paramsResponse.Params.CommunityTax.String(), 64); err != nil {
+			sublogger.Error().
+				Err(err).
+				Msg("Could not parse community rate")
+		} else {
+			params.CommunityTax = &value
+		}
+	}()
+
+	wg.Wait()
+
+	return params
+}
+
+type paramsMetrics struct {
+	paramsMaxValidatorsGauge        prometheus.Gauge
+	paramsUnbondingTimeGauge        prometheus.Gauge
+	paramsBlocksPerYearGauge        prometheus.Gauge
+	paramsGoalBondedGauge           prometheus.Gauge
+	paramsInflationMinGauge         prometheus.Gauge
+	paramsInflationMaxGauge         prometheus.Gauge
+	paramsInflationRateChangeGauge  prometheus.Gauge
+	paramsDowntailJailDurationGauge prometheus.Gauge
+	paramsMinSignedPerWindowGauge   prometheus.Gauge
+	paramsSignedBlocksWindowGauge   prometheus.Gauge
+	paramsSlashFractionDoubleSign   prometheus.Gauge
+	paramsSlashFractionDowntime     prometheus.Gauge
+	paramsBaseProposerRewardGauge   prometheus.Gauge
+	paramsBonusProposerRewardGauge  prometheus.Gauge
+	paramsCommunityTaxGauge         prometheus.Gauge
+}
+
+func newParamsMetrics(chainID string) *paramsMetrics {
+	return &paramsMetrics{
+		paramsMaxValidatorsGauge: newChainGauge(
+			prometheus.GaugeOpts{
+				Name: "cosmos_params_max_validators",
+				Help: "Active set length",
+			},
+			chainID,
+		),
+
+		paramsUnbondingTimeGauge: newChainGauge(
+			prometheus.GaugeOpts{
+				Name: "cosmos_params_unbonding_time",
+				Help: "Unbonding time, in seconds",
+			},
+			chainID,
+		),
+
+		paramsBlocksPerYearGauge: newChainGauge(
+			prometheus.GaugeOpts{
+				Name: "cosmos_params_blocks_per_year",
+				Help: "Block per year",
+			},
+			chainID,
+		),
+
+		paramsGoalBondedGauge: newChainGauge(
+			prometheus.GaugeOpts{
+				Name: "cosmos_params_goal_bonded",
+				Help: "Goal bonded",
+			},
+			chainID,
+		),
+
+		paramsInflationMinGauge: newChainGauge(
+			prometheus.GaugeOpts{
+				Name: "cosmos_params_inflation_min",
+				Help: "Min inflation",
+			},
+			chainID,
+		),
+
+		paramsInflationMaxGauge: newChainGauge(
+			prometheus.GaugeOpts{
+				Name: "cosmos_params_inflation_max",
+				Help: "Max inflation",
+			},
+			chainID,
+		),
+
+		paramsInflationRateChangeGauge: newChainGauge(
+			prometheus.GaugeOpts{
+				Name: "cosmos_params_inflation_rate_change",
+				Help: "Inflation rate change",
+			},
+			chainID,
+		),
+
+		paramsDowntailJailDurationGauge: newChainGauge(
+			prometheus.GaugeOpts{
+				Name: "cosmos_params_downtail_jail_duration",
+				Help: "Downtime jail duration, in seconds",
+			},
+			chainID,
+		),
+
+		paramsMinSignedPerWindowGauge: newChainGauge(
+			prometheus.GaugeOpts{
+				Name: "cosmos_params_min_signed_per_window",
+				Help: "Minimal amount of blocks to sign per window to avoid slashing",
+			},
+			chainID,
+		),
+
+		paramsSignedBlocksWindowGauge: newChainGauge(
+			prometheus.GaugeOpts{
+				Name: "cosmos_params_signed_blocks_window",
+				Help: "Signed blocks window",
+			},
+			chainID,
+		),
+
+		paramsSlashFractionDoubleSign: newChainGauge(
+			prometheus.GaugeOpts{
+				Name: "cosmos_params_slash_fraction_double_sign",
+				Help: "% of tokens to be slashed if double signing",
+			},
+			chainID,
+		),
+
+		paramsSlashFractionDowntime: newChainGauge(
+			prometheus.GaugeOpts{
+				Name: "cosmos_params_slash_fraction_downtime",
+				Help: "% of tokens to be slashed if downtime",
+			},
+			chainID,
+		),
+
+		paramsBaseProposerRewardGauge: newChainGauge(
+			prometheus.GaugeOpts{
+				Name: "cosmos_params_base_proposer_reward",
+				Help: "Base proposer reward",
+			},
+			chainID,
+		),
+
+		paramsBonusProposerRewardGauge: newChainGauge(
+			prometheus.GaugeOpts{
+				Name: "cosmos_params_bonus_proposer_reward",
+				Help: "Bonus proposer reward",
+			},
+			chainID,
+		),
+
+		paramsCommunityTaxGauge: newChainGauge(
+			prometheus.GaugeOpts{
+				Name: "cosmos_params_community_tax",
+				Help: "Community tax",
+			},
+			chainID,
+		),
+	}
+}
+
+func (m *paramsMetrics) set(params APIParams) {
+	for gauge, value := range map[prometheus.Gauge]*float64{
+		m.paramsUnbondingTimeGauge:        params.UnbondingTime,
+		m.paramsGoalBondedGauge:           params.GoalBonded,
+		m.paramsInflationMinGauge:         params.InflationMin,
+		m.paramsInflationMaxGauge:         params.InflationMax,
+		m.paramsInflationRateChangeGauge:  params.InflationRateChange,
+		m.paramsDowntailJailDurationGauge: params.DowntimeJailDuration,
+		m.paramsMinSignedPerWindowGauge:   params.MinSignedPerWindow,
+		m.paramsSlashFractionDoubleSign:   params.SlashFractionDoubleSign,
+		m.paramsSlashFractionDowntime:     params.SlashFractionDowntime,
+		m.paramsBaseProposerRewardGauge:   params.BaseProposerReward,
+		m.paramsBonusProposerRewardGauge:  params.BonusProposerReward,
+		m.paramsCommunityTaxGauge:         params.CommunityTax,
+	} {
+		if value != nil {
+			gauge.Set(*value)
+		}
+	}
+
+	for gauge, value := range map[prometheus.Gauge]*int64{
+		m.paramsMaxValidatorsGauge:      params.MaxValidators,
+		m.paramsBlocksPerYearGauge:      params.BlocksPerYear,
+		m.paramsSignedBlocksWindowGauge: params.SignedBlocksWindow,
+	} {
+		if value != nil {
+			gauge.Set(float64(*value))
+		}
+	}
+}
+
+func (m *paramsMetrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		m.paramsMaxValidatorsGauge,
+		m.paramsUnbondingTimeGauge,
+		m.paramsBlocksPerYearGauge,
+		m.paramsInflationMinGauge,
+		m.paramsInflationMaxGauge,
+		m.paramsInflationRateChangeGauge,
+		m.paramsDowntailJailDurationGauge,
+		m.paramsMinSignedPerWindowGauge,
+		m.paramsSignedBlocksWindowGauge,
+		m.paramsSlashFractionDoubleSign,
+		m.paramsSlashFractionDowntime,
+		m.paramsBaseProposerRewardGauge,
+		m.paramsBonusProposerRewardGauge,
+		m.paramsCommunityTaxGauge,
+	}
+}
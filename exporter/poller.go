@@ -0,0 +1,161 @@
+package exporter
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// pollerIdleIntervals is how many poll intervals a poller keeps running
+// without being scraped before it stops itself.
+const pollerIdleIntervals = 10
+
+// Poller periodically queries the node in the background and keeps the latest
+// snapshot of the gathered metrics in memory. It implements prometheus.Gatherer,
+// so scrapes can be served from the snapshot directly.
+type Poller struct {
+	endpoint string
+	labels   func() prometheus.Labels
+	interval time.Duration
+	source   prometheus.Gatherer
+
+	mutex      sync.RWMutex
+	families   []*dto.MetricFamily
+	lastAccess time.Time
+	lastPoll   time.Time
+
+	// ready is closed once the first poll is done, with its error in
+	// firstErr
+	ready    chan struct{}
+	firstErr error
+
+	staleness *prometheus.Registry
+}
+
+func NewPoller(endpoint string, labels func() prometheus.Labels, interval time.Duration, source prometheus.Gatherer) *Poller {
+	poller := &Poller{
+		endpoint:   endpoint,
+		labels:     labels,
+		interval:   interval,
+		source:     source,
+		lastAccess: time.Now(),
+		ready:      make(chan struct{}),
+		staleness:  prometheus.NewRegistry(),
+	}
+
+	poller.staleness.MustRegister(snapshotTimestampCollector{poller: poller})
+
+	return poller
+}
+
+// Poll fetches fresh metrics and replaces the snapshot. On error the previous
+// snapshot is kept, so the staleness timestamp stops advancing.
+func (p *Poller) Poll() error {
+	pollStart := time.Now()
+
+	families, err := p.source.Gather()
+	if err != nil {
+		return err
+	}
+
+	p.mutex.Lock()
+	p.families = families
+	p.lastPoll = time.Now()
+	p.mutex.Unlock()
+
+	log.Debug().
+		Str("endpoint", p.endpoint).
+		Float64("request-time", time.Since(pollStart).Seconds()).
+		Msg("Finished background poll")
+
+	return nil
+}
+
+// First does the first poll, which Wait waits for.
+func (p *Poller) First() error {
+	p.firstErr = p.Poll()
+	close(p.ready)
+
+	return p.firstErr
+}
+
+// Wait waits for the first poll, as long as ctx isn't done, and returns its
+// error.
+func (p *Poller) Wait(ctx context.Context) error {
+	select {
+	case <-p.ready:
+		return p.firstErr
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Run polls every interval until the poller hasn't been scraped for
+// pollerIdleIntervals intervals, then calls onIdle and returns.
+func (p *Poller) Run(onIdle func()) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		p.mutex.RLock()
+		idle := time.Since(p.lastAccess) > pollerIdleIntervals*p.interval
+		p.mutex.RUnlock()
+
+		if idle {
+			log.Debug().
+				Str("endpoint", p.endpoint).
+				Msg("Stopping idle background poller")
+			onIdle()
+			return
+		}
+
+		if err := p.Poll(); err != nil {
+			log.Error().
+				Err(err).
+				Str("endpoint", p.endpoint).
+				Msg("Background poll failed, serving previous snapshot")
+		}
+	}
+}
+
+func (p *Poller) Gather() ([]*dto.MetricFamily, error) {
+	p.mutex.Lock()
+	p.lastAccess = time.Now()
+	families := p.families
+	p.mutex.Unlock()
+
+	snapshot := prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		return families, nil
+	})
+
+	return prometheus.Gatherers{snapshot, p.staleness}.Gather()
+}
+
+// snapshotTimestampCollector exports the time of the latest poll with the
+// current labels of the collector, as the chain_id can change while the
+// poller runs. It doesn't describe its metric, so the registry doesn't check
+// the labels against it.
+type snapshotTimestampCollector struct {
+	poller *Poller
+}
+
+func (c snapshotTimestampCollector) Describe(ch chan<- *prometheus.Desc) {
+}
+
+func (c snapshotTimestampCollector) Collect(ch chan<- prometheus.Metric) {
+	c.poller.mutex.RLock()
+	lastPoll := c.poller.lastPoll
+	c.poller.mutex.RUnlock()
+
+	desc := prometheus.NewDesc(
+		"cosmos_exporter_snapshot_timestamp_seconds",
+		"Unix timestamp of the latest successful background poll",
+		nil,
+		mergeLabels(c.poller.labels(), prometheus.Labels{"endpoint": c.poller.endpoint}),
+	)
+
+	ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(lastPoll.Unix()))
+}
@@ -0,0 +1,422 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/client/grpc/tmservice"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// backendMaxLag is how many blocks a backend can be behind the highest one
+	// and still be picked. Backends earlier in the list are preferred, so
+	// small height differences between checks don't make the pool flap.
+	backendMaxLag = 3
+
+	backendCheckTimeout = 5 * time.Second
+)
+
+var (
+	backendUpGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cosmos_exporter_backend_up",
+			Help: "1 if the latest health check of the backend succeeded and it's not catching up, 0 if no",
+		},
+		[]string{"chain", "network", "endpoint", "type"},
+	)
+
+	backendServingGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cosmos_exporter_backend_serving",
+			Help: "1 for the backend the queries are currently sent to, 0 for the others",
+		},
+		[]string{"chain", "network", "endpoint", "type"},
+	)
+
+	backendHeightGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cosmos_exporter_backend_height",
+			Help: "Latest block height of the backend as of its latest health check",
+		},
+		[]string{"chain", "network", "endpoint", "type"},
+	)
+)
+
+// backendMetricOwners is the pool that last set the metrics of every set of
+// backend labels. A reload replaces optional networks with new pools of the
+// same name and address, and closing the old pool must not delete the metrics
+// of the new one.
+var (
+	backendMetricMutex  sync.Mutex
+	backendMetricOwners = make(map[string]*BackendPool)
+)
+
+func init() {
+	prometheus.MustRegister(backendUpGauge)
+	prometheus.MustRegister(backendServingGauge)
+	prometheus.MustRegister(backendHeightGauge)
+}
+
+// PoolOwner is what a pool connects to, the chain of its nodes or the optional
+// network, as the chain and network labels of the backend metrics.
+type PoolOwner struct {
+	Chain   string
+	Network string
+}
+
+// Backend is a single node endpoint of a pool, with the result of its latest
+// health check.
+type Backend struct {
+	Address string
+
+	Healthy    bool
+	Height     int64
+	CatchingUp bool
+	Latency    time.Duration
+	LastCheck  time.Time
+	LastError  error
+
+	conn *grpc.ClientConn
+}
+
+type healthCheck func(ctx context.Context, backend *Backend) (height int64, catchingUp bool, err error)
+
+// BackendPool is a list of equivalent endpoints of the same node type, checked
+// in the background. Queries go to the first healthy backend within
+// backendMaxLag blocks of the highest one. A gRPC pool implements
+// grpc.ClientConnInterface, so it can be used wherever a connection is.
+type BackendPool struct {
+	owner PoolOwner
+	kind  string
+	check healthCheck
+
+	mutex    sync.RWMutex
+	backends []*Backend
+	current  *Backend
+	closed   bool
+
+	done chan struct{}
+}
+
+func NewGRPCPool(owner PoolOwner, addresses []string, settings NodeSettings) (*BackendPool, error) {
+	pool := &BackendPool{owner: owner, kind: "grpc", check: checkGRPCBackend}
+
+	for _, address := range addresses {
+		conn, err := dialNode(address, settings)
+		if err != nil {
+			// the pool is never returned, so nothing else would close the
+			// connections dialed so far
+			for _, backend := range pool.backends {
+				backend.conn.Close()
+			}
+
+			return nil, err
+		}
+
+		pool.backends = append(pool.backends, &Backend{Address: address, conn: conn})
+	}
+
+	return pool, pool.init()
+}
+
+func NewRPCPool(owner PoolOwner, addresses []string) (*BackendPool, error) {
+	pool := &BackendPool{owner: owner, kind: "rpc", check: checkRPCBackend}
+
+	for _, address := range addresses {
+		pool.backends = append(pool.backends, &Backend{Address: strings.TrimSuffix(address, "/")})
+	}
+
+	return pool, pool.init()
+}
+
+// NewEthPool checks an Ethereum node by its latest block number. The gravity
+// bridge collectors query the client directly, so the pool only reports the
+// state of the node.
+func NewEthPool(owner PoolOwner, address string, client *ethclient.Client) (*BackendPool, error) {
+	check := func(ctx context.Context, backend *Backend) (int64, bool, error) {
+		height, err := client.BlockNumber(ctx)
+		return int64(height), false, err
+	}
+
+	pool := &BackendPool{owner: owner, kind: "eth", check: check}
+	pool.backends = append(pool.backends, &Backend{Address: address})
+
+	return pool, pool.init()
+}
+
+func (p *BackendPool) init() error {
+	if len(p.backends) == 0 {
+		return fmt.Errorf("no %s endpoints configured", p.kind)
+	}
+
+	p.current = p.backends[0]
+	p.done = make(chan struct{})
+	return nil
+}
+
+// Start checks all backends once, so the first queries already go to a
+// healthy one, and then keeps checking them every interval.
+func (p *BackendPool) Start(interval time.Duration) {
+	p.CheckAll()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				p.CheckAll()
+			case <-p.done:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops the health checks, deletes the metrics of the backends and
+// closes the gRPC connections of the pool.
+func (p *BackendPool) Close() {
+	close(p.done)
+
+	p.mutex.Lock()
+	p.closed = true
+	p.mutex.Unlock()
+
+	backendMetricMutex.Lock()
+	for _, backend := range p.backends {
+		labels := p.labels(backend)
+		key := strings.Join(labels, "\x00")
+		if backendMetricOwners[key] != p {
+			continue
+		}
+
+		delete(backendMetricOwners, key)
+		backendUpGauge.DeleteLabelValues(labels...)
+		backendServingGauge.DeleteLabelValues(labels...)
+		backendHeightGauge.DeleteLabelValues(labels...)
+	}
+	backendMetricMutex.Unlock()
+
+	for _, backend := range p.backends {
+		if backend.conn == nil {
+			continue
+		}
+
+		if err := backend.conn.Close(); err != nil {
+			log.Warn().
+				Err(err).
+				Str("type", p.kind).
+				Str("endpoint", backend.Address).
+				Msg("Could not close connection")
+		}
+	}
+}
+
+// CheckAll runs the health checks of all backends concurrently and picks the
+// backend to serve from.
+func (p *BackendPool) CheckAll() {
+	var wg sync.WaitGroup
+
+	for _, backend := range p.backends {
+		wg.Add(1)
+		go func(backend *Backend) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), backendCheckTimeout)
+			defer cancel()
+
+			checkStart := time.Now()
+			height, catchingUp, err := p.check(ctx, backend)
+
+			p.mutex.Lock()
+			backend.Latency = time.Since(checkStart)
+			backend.LastCheck = time.Now()
+			backend.LastError = err
+			backend.Healthy = err == nil && !catchingUp
+			if err == nil {
+				backend.Height = height
+				backend.CatchingUp = catchingUp
+			}
+			p.mutex.Unlock()
+
+			if err != nil {
+				log.Warn().
+					Err(err).
+					Str("type", p.kind).
+					Str("endpoint", backend.Address).
+					Msg("Backend health check failed")
+			}
+		}(backend)
+	}
+
+	wg.Wait()
+
+	p.selectBackend()
+}
+
+func (p *BackendPool) selectBackend() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var maxHeight int64
+	for _, backend := range p.backends {
+		if backend.Healthy && backend.Height > maxHeight {
+			maxHeight = backend.Height
+		}
+	}
+
+	// if none is healthy, keep the current one rather than jumping around
+	selected := p.current
+	for _, backend := range p.backends {
+		if backend.Healthy && backend.Height >= maxHeight-backendMaxLag {
+			selected = backend
+			break
+		}
+	}
+
+	if selected != p.current {
+		log.Info().
+			Str("type", p.kind).
+			Str("from", p.current.Address).
+			Str("to", selected.Address).
+			Int64("height", selected.Height).
+			Msg("Switching backend")
+		p.current = selected
+	}
+
+	// a check still running when the pool is closed must not bring back
+	// the metrics Close deleted
+	if p.closed {
+		return
+	}
+
+	backendMetricMutex.Lock()
+	defer backendMetricMutex.Unlock()
+
+	for _, backend := range p.backends {
+		var up, serving float64
+		if backend.Healthy {
+			up = 1
+		}
+		if backend == p.current {
+			serving = 1
+		}
+
+		labels := p.labels(backend)
+		backendMetricOwners[strings.Join(labels, "\x00")] = p
+		backendUpGauge.WithLabelValues(labels...).Set(up)
+		backendServingGauge.WithLabelValues(labels...).Set(serving)
+		backendHeightGauge.WithLabelValues(labels...).Set(float64(backend.Height))
+	}
+}
+
+// labels are the label values of the metrics of backend.
+func (p *BackendPool) labels(backend *Backend) []string {
+	return []string{p.owner.Chain, p.owner.Network, backend.Address, p.kind}
+}
+
+func (p *BackendPool) currentBackend() *Backend {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.current
+}
+
+// Address returns the address of the backend currently serving.
+func (p *BackendPool) Address() string {
+	return p.currentBackend().Address
+}
+
+// Backends returns a copy of the state of every backend.
+func (p *BackendPool) Backends() []Backend {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	backends := make([]Backend, len(p.backends))
+	for i, backend := range p.backends {
+		backends[i] = *backend
+	}
+
+	return backends
+}
+
+// State returns the connectivity state of the gRPC connection of the backend
+// currently serving.
+func (p *BackendPool) State() connectivity.State {
+	return p.currentBackend().conn.GetState()
+}
+
+// Invoke also records the block height the node evaluated the query at, from
+// the x-cosmos-block-height response header.
+func (p *BackendPool) Invoke(ctx context.Context, method string, args interface{}, reply interface{}, opts ...grpc.CallOption) error {
+	var header metadata.MD
+	err := p.currentBackend().conn.Invoke(ctx, method, args, reply, append(opts, grpc.Header(&header))...)
+	recordHeight(ctx, header)
+	return err
+}
+
+func (p *BackendPool) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return p.currentBackend().conn.NewStream(ctx, desc, method, opts...)
+}
+
+func checkGRPCBackend(ctx context.Context, backend *Backend) (int64, bool, error) {
+	client := tmservice.NewServiceClient(backend.conn)
+
+	syncing, err := client.GetSyncing(ctx, &tmservice.GetSyncingRequest{})
+	if err != nil {
+		return 0, false, err
+	}
+
+	block, err := client.GetLatestBlock(ctx, &tmservice.GetLatestBlockRequest{})
+	if err != nil {
+		return 0, false, err
+	}
+
+	if block.Block == nil {
+		return 0, false, fmt.Errorf("empty latest block")
+	}
+
+	return block.Block.Header.Height, syncing.Syncing, nil
+}
+
+func checkRPCBackend(ctx context.Context, backend *Backend) (int64, bool, error) {
+	resp, err := httpGet(ctx, backend.Address+"/status")
+	if err != nil {
+		return 0, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, false, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, false, err
+	}
+
+	statusResponse := StatusResponse{}
+	if err := json.Unmarshal(body, &statusResponse); err != nil {
+		return 0, false, err
+	}
+
+	height, err := strconv.ParseInt(statusResponse.Result.SyncInfo.LatestBlockHeight, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid latest block height: %w", err)
+	}
+
+	return height, statusResponse.Result.SyncInfo.CatchingUp, nil
+}
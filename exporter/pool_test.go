@@ -0,0 +1,84 @@
+package exporter
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBackendPoolSelectBackend(t *testing.T) {
+	type backendState struct {
+		healthy bool
+		height  int64
+	}
+
+	tests := []struct {
+		name     string
+		backends []backendState
+		current  int
+		want     int
+	}{
+		{
+			name:     "first healthy",
+			backends: []backendState{{true, 100}, {true, 101}},
+			want:     0,
+		},
+		{
+			name:     "failover",
+			backends: []backendState{{false, 100}, {true, 100}},
+			want:     1,
+		},
+		{
+			name:     "back to the first once it's healthy",
+			backends: []backendState{{true, 100}, {true, 100}},
+			current:  1,
+			want:     0,
+		},
+		{
+			name:     "lagging within the max lag",
+			backends: []backendState{{true, 100 - backendMaxLag}, {true, 100}},
+			want:     0,
+		},
+		{
+			name:     "lagging behind the max lag",
+			backends: []backendState{{true, 100 - backendMaxLag - 1}, {true, 100}},
+			want:     1,
+		},
+		{
+			// an unhealthy backend ahead of the others doesn't make them lag
+			name:     "higher but unhealthy",
+			backends: []backendState{{true, 100}, {false, 200}},
+			want:     0,
+		},
+		{
+			name:     "none healthy",
+			backends: []backendState{{false, 100}, {false, 100}},
+			current:  1,
+			want:     1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pool := &BackendPool{owner: PoolOwner{Chain: "test"}, kind: "grpc"}
+			for i, state := range test.backends {
+				pool.backends = append(pool.backends, &Backend{
+					Address: fmt.Sprintf("node-%d:9090", i),
+					Healthy: state.healthy,
+					Height:  state.height,
+				})
+			}
+
+			if err := pool.init(); err != nil {
+				t.Fatal(err)
+			}
+			defer pool.Close()
+
+			pool.current = pool.backends[test.current]
+			pool.selectBackend()
+
+			if got, want := pool.Address(), pool.backends[test.want].Address; got != want {
+				t.Errorf("selected backend %s, want %s", got, want)
+			}
+		})
+	}
+}
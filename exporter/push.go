@@ -0,0 +1,630 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
+	"github.com/spf13/pflag"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	pushesCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cosmos_exporter_pushes_total",
+			Help: "Push requests to the Pushgateway, remote_write or OTLP receiver, by result",
+		},
+		[]string{"receiver", "result"},
+	)
+
+	lastPushGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cosmos_exporter_last_push_timestamp_seconds",
+			Help: "Unix timestamp of the latest successful push to the receiver",
+		},
+		[]string{"receiver"},
+	)
+
+	pushBufferGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cosmos_exporter_push_buffer_batches",
+			Help: "Number of failed pushes kept in the on-disk buffer, to be sent once the receiver is back",
+		},
+		[]string{"receiver"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(pushesCounter)
+	prometheus.MustRegister(lastPushGauge)
+	prometheus.MustRegister(pushBufferGauge)
+}
+
+// PushConfig is the push mode, for exporters Prometheus can't reach: every
+// interval, the metrics of /metrics of every chain are collected and pushed
+// to a Pushgateway, a remote_write receiver like Mimir or Cortex, or both.
+type PushConfig struct {
+	PushgatewayURL string
+	PushgatewayJob string
+	RemoteWriteURL string
+	OTLPEndpoint   string
+	OTLPProtocol   string
+
+	Interval       time.Duration
+	Timeout        time.Duration
+	ExternalLabels map[string]string
+	Headers        map[string]string
+
+	// failed pushes are retried MaxRetries times, waiting from MinBackoff,
+	// doubled after every attempt, up to MaxBackoff
+	MaxRetries int
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// remote_write and OTLP pushes that still fail are kept in BufferDir, up to
+	// BufferSize of them, and sent once the receiver is back
+	BufferDir  string
+	BufferSize int
+}
+
+// PushSettings holds the push mode flags.
+var PushSettings PushConfig
+
+func (c *PushConfig) AddFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&c.PushgatewayURL, "pushgateway-url", "", "Pushgateway to push the metrics to, e.g. http://pushgateway:9091")
+	flags.StringVar(&c.PushgatewayJob, "pushgateway-job", "cosmos-exporter", "Job label of the metrics pushed to the Pushgateway")
+	flags.StringVar(&c.RemoteWriteURL, "remote-write-url", "", "Prometheus remote_write endpoint to push the metrics to, e.g. http://mimir:9009/api/v1/push")
+	flags.StringVar(&c.OTLPEndpoint, "otlp-endpoint", "", "OpenTelemetry collector to push the metrics to over OTLP, e.g. http://otel-collector:4317, https:// for TLS. gRPC defaults to port 4317")
+	flags.StringVar(&c.OTLPProtocol, "otlp-protocol", otlpProtocolGRPC, "OTLP protocol, grpc or http/protobuf")
+	flags.DurationVar(&c.Interval, "push-interval", time.Minute, "Interval to collect and push the metrics at")
+	flags.DurationVar(&c.Timeout, "push-timeout", 30*time.Second, "Timeout of a single push request")
+	flags.StringToStringVar(&c.ExternalLabels, "push-external-labels", nil, "Labels added to all the pushed metrics, e.g. instance=validator-1,region=eu")
+	flags.StringToStringVar(&c.Headers, "push-headers", nil, "HTTP headers sent with every push, e.g. X-Scope-OrgID=tenant")
+	flags.IntVar(&c.MaxRetries, "push-max-retries", 5, "Retries of a failed push before it's buffered or dropped")
+	flags.DurationVar(&c.MinBackoff, "push-min-backoff", time.Second, "Wait before the first retry of a failed push, doubled after every retry")
+	flags.DurationVar(&c.MaxBackoff, "push-max-backoff", 30*time.Second, "Maximum wait between retries of a failed push")
+	flags.StringVar(&c.BufferDir, "push-buffer-dir", "", "Directory to keep the remote_write and OTLP pushes that failed all retries in, until the receiver is back")
+	flags.IntVar(&c.BufferSize, "push-buffer-size", 1000, "Maximum number of pushes kept in --push-buffer-dir, the oldest are dropped first")
+}
+
+func (c *PushConfig) Enabled() bool {
+	return c.PushgatewayURL != "" || c.RemoteWriteURL != "" || c.OTLPEndpoint != ""
+}
+
+func (c *PushConfig) Check() []error {
+	var errs []error
+
+	if c.PushgatewayURL != "" {
+		if err := CheckURL("pushgateway-url", c.PushgatewayURL, "http", "https"); err != nil {
+			errs = append(errs, err)
+		}
+
+		if c.PushgatewayJob == "" {
+			errs = append(errs, fmt.Errorf("pushgateway-job: must not be empty"))
+		}
+	}
+
+	if c.RemoteWriteURL != "" {
+		if err := CheckURL("remote-write-url", c.RemoteWriteURL, "http", "https"); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if c.OTLPEndpoint != "" {
+		if err := CheckURL("otlp-endpoint", c.OTLPEndpoint, "http", "https"); err != nil {
+			errs = append(errs, err)
+		}
+
+		if c.OTLPProtocol != otlpProtocolGRPC && c.OTLPProtocol != otlpProtocolHTTP {
+			errs = append(errs, fmt.Errorf("otlp-protocol: must be %s or %s", otlpProtocolGRPC, otlpProtocolHTTP))
+		}
+	}
+
+	if c.Interval <= 0 {
+		errs = append(errs, fmt.Errorf("push-interval: must be positive"))
+	}
+
+	if c.Timeout <= 0 {
+		errs = append(errs, fmt.Errorf("push-timeout: must be positive"))
+	}
+
+	for name := range c.ExternalLabels {
+		if !model.LabelName(name).IsValid() || strings.HasPrefix(name, "__") {
+			errs = append(errs, fmt.Errorf("push-external-labels: invalid label name %q", name))
+		}
+	}
+
+	if c.MaxRetries < 0 {
+		errs = append(errs, fmt.Errorf("push-max-retries: must not be negative"))
+	}
+
+	if c.MinBackoff <= 0 || c.MaxBackoff < c.MinBackoff {
+		errs = append(errs, fmt.Errorf("push-min-backoff, push-max-backoff: must be positive, the maximum at least the minimum"))
+	}
+
+	if c.BufferDir != "" && c.BufferSize <= 0 {
+		errs = append(errs, fmt.Errorf("push-buffer-size: must be positive"))
+	}
+
+	return errs
+}
+
+// PushGatherer gathers what /metrics serves for every chain, each within its
+// own --scrape-timeout, along with the exporter's own metrics. With
+// --poll-interval, pushes are served from the same background collection as
+// the scrapes of /metrics?chain=<name>, so nothing is collected twice.
+func PushGatherer(ctx context.Context, chains *Chains, aggregate *Endpoint) prometheus.Gatherer {
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		gatherers := prometheus.Gatherers{prometheus.DefaultGatherer}
+
+		for _, chain := range chains.All() {
+			query := url.Values{"chain": {chain.Name}}
+
+			gatherers = append(gatherers, prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+				collectCtx, cancel := context.WithTimeout(ctx, ScrapeTimeout)
+				defer cancel()
+
+				gatherer, err := aggregate.gatherer(collectCtx, query)
+				if err != nil {
+					return nil, err
+				}
+
+				return gatherer.Gather()
+			}))
+		}
+
+		return gatherers.Gather()
+	})
+}
+
+// PushReceiver is where a Pusher sends the metrics to.
+type PushReceiver interface {
+	// Name is the receiver label of the push metrics.
+	Name() string
+
+	// Encode returns the body of the push of families collected at
+	// timestamp.
+	Encode(families []*dto.MetricFamily, timestamp time.Time) ([]byte, error)
+
+	Send(ctx context.Context, body []byte) error
+
+	// Buffered receivers keep every sample with its timestamp, so pushes
+	// that failed are still worth sending later. A Pushgateway only keeps
+	// the latest values, which the next push replaces anyway.
+	Buffered() bool
+}
+
+// PushStatusError is a push the receiver answered with an error status.
+// Only 429 and 5xx are worth retrying, other errors are the same every time.
+type PushStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *PushStatusError) Error() string {
+	return fmt.Sprintf("receiver answered with status %d: %s", e.StatusCode, e.Body)
+}
+
+func (e *PushStatusError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+func retryablePushError(err error) bool {
+	var statusError *PushStatusError
+	if errors.As(err, &statusError) {
+		return statusError.Retryable()
+	}
+
+	// gRPC receivers answer with a status instead, of which these are
+	// the ones the OTLP spec allows retrying
+	if s, ok := status.FromError(err); ok {
+		switch s.Code() {
+		case codes.Canceled, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted,
+			codes.OutOfRange, codes.Unavailable, codes.DataLoss:
+			return true
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// sendPush sends a push request with the --push-headers and returns a
+// PushStatusError if it fails with an error status.
+func sendPush(ctx context.Context, client *http.Client, method, url string, body []byte, headers map[string]string) error {
+	request, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	for name, value := range PushSettings.Headers {
+		request.Header.Set(name, value)
+	}
+
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode/100 != 2 {
+		message, _ := ioutil.ReadAll(io.LimitReader(response.Body, 1024))
+		return &PushStatusError{StatusCode: response.StatusCode, Body: strings.TrimSpace(string(message))}
+	}
+
+	return nil
+}
+
+// Pusher collects gatherer every interval and pushes the metrics to its
+// receivers, each retrying with backoff on its own. The metrics are collected
+// once for all the receivers.
+type Pusher struct {
+	gatherer prometheus.Gatherer
+	interval time.Duration
+	outputs  []*pushOutput
+}
+
+func NewPusher(receivers []PushReceiver, gatherer prometheus.Gatherer, config PushConfig) (*Pusher, error) {
+	pusher := &Pusher{
+		gatherer: gatherer,
+		interval: config.Interval,
+	}
+
+	for _, receiver := range receivers {
+		output := &pushOutput{receiver: receiver, config: config}
+
+		if config.BufferDir != "" && receiver.Buffered() {
+			buffer, err := newPushBuffer(filepath.Join(config.BufferDir, receiver.Name()), config.BufferSize)
+			if err != nil {
+				return nil, err
+			}
+
+			output.buffer = buffer
+			pushBufferGauge.WithLabelValues(receiver.Name()).Set(float64(buffer.Len()))
+		}
+
+		pusher.outputs = append(pusher.outputs, output)
+	}
+
+	return pusher, nil
+}
+
+// Run pushes right away and then every interval, until ctx is done.
+func (p *Pusher) Run(ctx context.Context) {
+	for _, output := range p.outputs {
+		log.Info().
+			Str("receiver", output.receiver.Name()).
+			Dur("interval", p.interval).
+			Msg("Pushing metrics")
+	}
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.Push(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Push collects the metrics and pushes them to all the receivers at once, so
+// a receiver that's down doesn't hold up the others.
+func (p *Pusher) Push(ctx context.Context) {
+	pushStart := time.Now()
+
+	families, err := p.gatherer.Gather()
+	if err != nil {
+		// the registry still returns what it could gather
+		log.Warn().
+			Err(err).
+			Msg("Could not gather all metrics, pushing the rest")
+	}
+
+	var wg sync.WaitGroup
+
+	for _, output := range p.outputs {
+		wg.Add(1)
+		go func(output *pushOutput) {
+			defer wg.Done()
+			output.push(ctx, families, pushStart)
+		}(output)
+	}
+
+	wg.Wait()
+}
+
+// pushOutput pushes to a single receiver. Pushes to buffered receivers that
+// still fail after the retries are kept on disk, and sent in order before the
+// next push once the receiver is back.
+type pushOutput struct {
+	receiver PushReceiver
+	config   PushConfig
+	buffer   *pushBuffer
+}
+
+// push encodes families and pushes them, after the buffered pushes.
+func (o *pushOutput) push(ctx context.Context, families []*dto.MetricFamily, timestamp time.Time) {
+	body, err := o.receiver.Encode(families, timestamp)
+	if err != nil {
+		log.Error().
+			Str("receiver", o.receiver.Name()).
+			Err(err).
+			Msg("Could not encode metrics")
+		return
+	}
+
+	if err := o.flush(ctx); err != nil {
+		o.store(timestamp, body, err)
+		return
+	}
+
+	if err := o.send(ctx, body); err != nil {
+		o.store(timestamp, body, err)
+		return
+	}
+
+	log.Debug().
+		Str("receiver", o.receiver.Name()).
+		Float64("request-time", time.Since(timestamp).Seconds()).
+		Msg("Finished push")
+}
+
+// send sends body, retrying with backoff as long as the error is worth it.
+func (o *pushOutput) send(ctx context.Context, body []byte) error {
+	backoff := o.config.MinBackoff
+
+	for attempt := 0; ; attempt++ {
+		err := o.sendOnce(ctx, body)
+		if err == nil {
+			return nil
+		}
+
+		if !retryablePushError(err) || attempt >= o.config.MaxRetries {
+			return err
+		}
+
+		log.Warn().
+			Str("receiver", o.receiver.Name()).
+			Err(err).
+			Dur("backoff", backoff).
+			Msg("Push failed, retrying")
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > o.config.MaxBackoff {
+			backoff = o.config.MaxBackoff
+		}
+	}
+}
+
+// sendOnce sends body a single time, within --push-timeout.
+func (o *pushOutput) sendOnce(ctx context.Context, body []byte) error {
+	sendCtx, cancel := context.WithTimeout(ctx, o.config.Timeout)
+	defer cancel()
+
+	if err := o.receiver.Send(sendCtx, body); err != nil {
+		pushesCounter.WithLabelValues(o.receiver.Name(), "failure").Inc()
+		return err
+	}
+
+	pushesCounter.WithLabelValues(o.receiver.Name(), "success").Inc()
+	lastPushGauge.WithLabelValues(o.receiver.Name()).SetToCurrentTime()
+	return nil
+}
+
+// flush sends the buffered pushes, oldest first, and stops at the first one
+// that fails for a reason worth retrying. Buffered pushes are only tried once
+// per interval, without retries, so a receiver that's down doesn't hold up
+// the next push. Pushes the receiver rejects are dropped, as they'd fail
+// every time, and so are the ones that can't be read back.
+func (o *pushOutput) flush(ctx context.Context) error {
+	if o.buffer == nil {
+		return nil
+	}
+	defer func() {
+		pushBufferGauge.WithLabelValues(o.receiver.Name()).Set(float64(o.buffer.Len()))
+	}()
+
+	for {
+		name, body, ok, err := o.buffer.Oldest()
+		if err != nil && name != "" {
+			log.Error().
+				Str("receiver", o.receiver.Name()).
+				Str("batch", name).
+				Err(err).
+				Msg("Could not read buffered push, moving it aside")
+
+			if err := o.buffer.Quarantine(name); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if err != nil || !ok {
+			return err
+		}
+
+		if err := o.sendOnce(ctx, body); err != nil {
+			if retryablePushError(err) {
+				return err
+			}
+
+			log.Error().
+				Str("receiver", o.receiver.Name()).
+				Str("batch", name).
+				Err(err).
+				Msg("Receiver rejected buffered push, dropping it")
+		}
+
+		if err := o.buffer.Remove(name); err != nil {
+			return err
+		}
+	}
+}
+
+// store keeps a push that failed with err in the buffer, or drops it.
+func (o *pushOutput) store(timestamp time.Time, body []byte, err error) {
+	if o.buffer == nil || !retryablePushError(err) {
+		log.Error().
+			Str("receiver", o.receiver.Name()).
+			Err(err).
+			Msg("Push failed, dropping it")
+		return
+	}
+
+	dropped, storeErr := o.buffer.Store(timestamp, body)
+	if storeErr != nil {
+		log.Error().
+			Str("receiver", o.receiver.Name()).
+			Err(storeErr).
+			Msg("Could not buffer failed push, dropping it")
+		return
+	}
+
+	log.Warn().
+		Str("receiver", o.receiver.Name()).
+		Err(err).
+		Int("buffered", o.buffer.Len()).
+		Int("dropped", dropped).
+		Msg("Push failed, buffered it until the receiver is back")
+
+	pushBufferGauge.WithLabelValues(o.receiver.Name()).Set(float64(o.buffer.Len()))
+}
+
+// pushBuffer keeps the bodies of failed pushes as files in dir, named after
+// the time they were collected at, so they're sent in order and survive a
+// restart. Beyond size files, the oldest are dropped.
+type pushBuffer struct {
+	dir  string
+	size int
+}
+
+const (
+	pushBufferSuffix           = ".push"
+	pushBufferQuarantineSuffix = ".corrupt"
+)
+
+func newPushBuffer(dir string, size int) (*pushBuffer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("could not create push buffer directory: %w", err)
+	}
+
+	return &pushBuffer{dir: dir, size: size}, nil
+}
+
+// files returns the names of the buffered pushes, oldest first.
+func (b *pushBuffer) files() ([]string, error) {
+	entries, err := os.ReadDir(b.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), pushBufferSuffix) {
+			names = append(names, entry.Name())
+		}
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+func (b *pushBuffer) Len() int {
+	names, _ := b.files()
+	return len(names)
+}
+
+// Store writes a push and returns how many old ones it dropped to make room.
+func (b *pushBuffer) Store(timestamp time.Time, body []byte) (int, error) {
+	// zero-padded, so the names sort by time
+	name := fmt.Sprintf("%020d%s", timestamp.UnixNano(), pushBufferSuffix)
+
+	// written under another name first, so a crash never leaves a partial
+	// push to send
+	temporary := filepath.Join(b.dir, name+".tmp")
+	if err := ioutil.WriteFile(temporary, body, 0o644); err != nil {
+		return 0, err
+	}
+
+	if err := os.Rename(temporary, filepath.Join(b.dir, name)); err != nil {
+		return 0, err
+	}
+
+	names, err := b.files()
+	if err != nil {
+		return 0, err
+	}
+
+	dropped := 0
+	for ; len(names)-dropped > b.size; dropped++ {
+		if err := b.Remove(names[dropped]); err != nil {
+			return dropped, err
+		}
+	}
+
+	return dropped, nil
+}
+
+// Oldest returns the oldest buffered push, if there's any.
+func (b *pushBuffer) Oldest() (string, []byte, bool, error) {
+	names, err := b.files()
+	if err != nil || len(names) == 0 {
+		return "", nil, false, err
+	}
+
+	// the name is returned along with a read error, so the caller can
+	// move the push aside instead of failing on it forever
+	body, err := ioutil.ReadFile(filepath.Join(b.dir, names[0]))
+	if err != nil {
+		return names[0], nil, false, err
+	}
+
+	return names[0], body, true, nil
+}
+
+func (b *pushBuffer) Remove(name string) error {
+	return os.Remove(filepath.Join(b.dir, name))
+}
+
+// Quarantine renames a push that can't be read, so it's no longer sent but is
+// still there to look into. It's removed if it can't even be renamed.
+func (b *pushBuffer) Quarantine(name string) error {
+	if err := os.Rename(filepath.Join(b.dir, name), filepath.Join(b.dir, name+pushBufferQuarantineSuffix)); err == nil {
+		return nil
+	}
+
+	return b.Remove(name)
+}
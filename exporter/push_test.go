@@ -0,0 +1,221 @@
+package exporter
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestPushBuffer(t *testing.T) {
+	buffer, err := newPushBuffer(filepath.Join(t.TempDir(), "remote-write"), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, ok, err := buffer.Oldest(); ok || err != nil {
+		t.Fatalf("Oldest() of an empty buffer = %t, %v", ok, err)
+	}
+
+	// stored out of order, they're still sent oldest first
+	start := time.Unix(1600000000, 0)
+	for i, offset := range []int{2, 1} {
+		dropped, err := buffer.Store(start.Add(time.Duration(offset)*time.Second), []byte{byte(offset)})
+		if err != nil || dropped != 0 {
+			t.Fatalf("Store() %d = %d, %v", i, dropped, err)
+		}
+	}
+
+	name, body, ok, err := buffer.Oldest()
+	if err != nil || !ok || !reflect.DeepEqual(body, []byte{1}) {
+		t.Fatalf("Oldest() = %s, %v, %t, %v, want the push of second 1", name, body, ok, err)
+	}
+
+	// beyond the size, the oldest is dropped
+	dropped, err := buffer.Store(start.Add(3*time.Second), []byte{3})
+	if err != nil || dropped != 1 {
+		t.Fatalf("Store() beyond the size = %d, %v, want 1 dropped", dropped, err)
+	}
+
+	if buffer.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", buffer.Len())
+	}
+
+	if _, body, _, _ := buffer.Oldest(); !reflect.DeepEqual(body, []byte{2}) {
+		t.Errorf("Oldest() after dropping = %v, want the push of second 2", body)
+	}
+
+	// a push from before a restart is read back by a new buffer
+	reopened, err := newPushBuffer(buffer.dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if reopened.Len() != 2 {
+		t.Errorf("Len() after reopening = %d, want 2", reopened.Len())
+	}
+}
+
+func TestPushBufferQuarantine(t *testing.T) {
+	buffer, err := newPushBuffer(t.TempDir(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a dangling link can be listed but not read
+	unreadable := "00000000000000000001" + pushBufferSuffix
+	if err := os.Symlink(filepath.Join(buffer.dir, "missing"), filepath.Join(buffer.dir, unreadable)); err != nil {
+		t.Fatal(err)
+	}
+
+	name, _, ok, err := buffer.Oldest()
+	if err == nil || ok || name != unreadable {
+		t.Fatalf("Oldest() = %s, %t, %v, want the name of the unreadable push and an error", name, ok, err)
+	}
+
+	if err := buffer.Quarantine(name); err != nil {
+		t.Fatal(err)
+	}
+
+	if buffer.Len() != 0 {
+		t.Errorf("Len() after Quarantine() = %d, want 0", buffer.Len())
+	}
+
+	if _, err := os.Lstat(filepath.Join(buffer.dir, unreadable+pushBufferQuarantineSuffix)); err != nil {
+		t.Errorf("quarantined push not kept: %v", err)
+	}
+}
+
+// fakeReceiver fails the sends with the errors of results, in order, and
+// succeeds once they run out.
+type fakeReceiver struct {
+	results []error
+	sent    [][]byte
+}
+
+func (r *fakeReceiver) Name() string {
+	return "fake"
+}
+
+func (r *fakeReceiver) Buffered() bool {
+	return true
+}
+
+func (r *fakeReceiver) Encode(families []*dto.MetricFamily, timestamp time.Time) ([]byte, error) {
+	return []byte(timestamp.Format(time.RFC3339)), nil
+}
+
+func (r *fakeReceiver) Send(ctx context.Context, body []byte) error {
+	r.sent = append(r.sent, body)
+
+	if len(r.results) == 0 {
+		return nil
+	}
+
+	err := r.results[0]
+	r.results = r.results[1:]
+	return err
+}
+
+func TestPushOutputFlush(t *testing.T) {
+	unavailable := &PushStatusError{StatusCode: http.StatusServiceUnavailable}
+	rejected := &PushStatusError{StatusCode: http.StatusBadRequest}
+
+	tests := []struct {
+		name    string
+		results []error
+
+		wantSent   int
+		wantLeft   int
+		wantErr    bool
+		wantOldest []byte
+	}{
+		{name: "all sent", wantSent: 3},
+		// a receiver that's down is tried once, not with retries
+		{name: "receiver down", results: []error{unavailable}, wantSent: 1, wantLeft: 3, wantErr: true, wantOldest: []byte{1}},
+		{name: "down after the first", results: []error{nil, unavailable}, wantSent: 2, wantLeft: 2, wantErr: true, wantOldest: []byte{2}},
+		{name: "rejected push dropped", results: []error{rejected}, wantSent: 3},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buffer, err := newPushBuffer(t.TempDir(), 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i := 1; i <= 3; i++ {
+				if _, err := buffer.Store(time.Unix(int64(i), 0), []byte{byte(i)}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			receiver := &fakeReceiver{results: test.results}
+			output := &pushOutput{
+				receiver: receiver,
+				config:   PushConfig{Timeout: time.Second, MaxRetries: 5, MinBackoff: time.Hour, MaxBackoff: time.Hour},
+				buffer:   buffer,
+			}
+
+			err = output.flush(context.Background())
+			if (err != nil) != test.wantErr {
+				t.Fatalf("flush() error = %v, want error %t", err, test.wantErr)
+			}
+
+			if len(receiver.sent) != test.wantSent {
+				t.Errorf("sent %d pushes, want %d", len(receiver.sent), test.wantSent)
+			}
+
+			if buffer.Len() != test.wantLeft {
+				t.Errorf("%d pushes left in the buffer, want %d", buffer.Len(), test.wantLeft)
+			}
+
+			if _, body, _, _ := buffer.Oldest(); !reflect.DeepEqual(body, test.wantOldest) {
+				t.Errorf("oldest push left = %v, want %v", body, test.wantOldest)
+			}
+		})
+	}
+}
+
+func TestPushOutputStoresFailedPush(t *testing.T) {
+	buffer, err := newPushBuffer(t.TempDir(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	receiver := &fakeReceiver{results: []error{&PushStatusError{StatusCode: http.StatusServiceUnavailable}}}
+	output := &pushOutput{
+		receiver: receiver,
+		config:   PushConfig{Timeout: time.Second, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+		buffer:   buffer,
+	}
+
+	first := time.Unix(1600000000, 0)
+	output.push(context.Background(), nil, first)
+
+	if buffer.Len() != 1 {
+		t.Fatalf("Len() after a failed push = %d, want 1", buffer.Len())
+	}
+
+	// the next push sends the buffered one first
+	second := first.Add(time.Minute)
+	output.push(context.Background(), nil, second)
+
+	want := [][]byte{
+		[]byte(first.Format(time.RFC3339)),
+		[]byte(first.Format(time.RFC3339)),
+		[]byte(second.Format(time.RFC3339)),
+	}
+	if !reflect.DeepEqual(receiver.sent, want) {
+		t.Errorf("sent %q, want %q", receiver.sent, want)
+	}
+
+	if buffer.Len() != 0 {
+		t.Errorf("Len() after the receiver is back = %d, want 0", buffer.Len())
+	}
+}
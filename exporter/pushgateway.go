@@ -0,0 +1,86 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// PushgatewayReceiver replaces the metrics of a group of a Pushgateway on
+// every push, so series that are gone from the exporter are gone from the
+// Pushgateway too. The group is the job and the external labels, which the
+// Pushgateway adds to all the metrics of the group.
+type PushgatewayReceiver struct {
+	url    string
+	client *http.Client
+}
+
+func NewPushgatewayReceiver(address, job string, groupingLabels map[string]string) *PushgatewayReceiver {
+	path := "/metrics" + pushgatewayLabel("job", job)
+
+	names := make([]string, 0, len(groupingLabels))
+	for name := range groupingLabels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		path += pushgatewayLabel(name, groupingLabels[name])
+	}
+
+	return &PushgatewayReceiver{
+		url:    strings.TrimSuffix(address, "/") + path,
+		client: &http.Client{},
+	}
+}
+
+// pushgatewayLabel is a label of the grouping key in the URL path. Values with
+// a slash, or empty ones, are base64-encoded, as the Pushgateway expects with
+// the @base64 suffix of the label name.
+func pushgatewayLabel(name, value string) string {
+	if value == "" || strings.Contains(value, "/") {
+		encoded := base64.RawURLEncoding.EncodeToString([]byte(value))
+		if encoded == "" {
+			encoded = "="
+		}
+
+		return "/" + name + "@base64/" + encoded
+	}
+
+	return "/" + name + "/" + url.PathEscape(value)
+}
+
+func (r *PushgatewayReceiver) Name() string {
+	return "pushgateway"
+}
+
+func (r *PushgatewayReceiver) Encode(families []*dto.MetricFamily, timestamp time.Time) ([]byte, error) {
+	var body bytes.Buffer
+
+	encoder := expfmt.NewEncoder(&body, expfmt.FmtProtoDelim)
+	for _, family := range families {
+		if err := encoder.Encode(family); err != nil {
+			return nil, err
+		}
+	}
+
+	return body.Bytes(), nil
+}
+
+func (r *PushgatewayReceiver) Send(ctx context.Context, body []byte) error {
+	return sendPush(ctx, r.client, http.MethodPut, r.url, body, map[string]string{
+		"Content-Type": string(expfmt.FmtProtoDelim),
+	})
+}
+
+func (r *PushgatewayReceiver) Buffered() bool {
+	return false
+}
@@ -0,0 +1,175 @@
+package exporter
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/golang/snappy"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// RemoteWriteReceiver sends the metrics as a Prometheus remote_write client,
+// to Mimir, Cortex, Thanos Receive or Prometheus itself with
+// --web.enable-remote-write-receiver. Every sample has the time it was
+// collected at, so buffered pushes can be sent later.
+type RemoteWriteReceiver struct {
+	url            string
+	externalLabels map[string]string
+	client         *http.Client
+}
+
+func NewRemoteWriteReceiver(url string, externalLabels map[string]string) *RemoteWriteReceiver {
+	return &RemoteWriteReceiver{
+		url:            url,
+		externalLabels: externalLabels,
+		client:         &http.Client{},
+	}
+}
+
+func (r *RemoteWriteReceiver) Name() string {
+	return "remote-write"
+}
+
+func (r *RemoteWriteReceiver) Buffered() bool {
+	return true
+}
+
+func (r *RemoteWriteReceiver) Send(ctx context.Context, body []byte) error {
+	return sendPush(ctx, r.client, http.MethodPost, r.url, body, map[string]string{
+		"Content-Type":                      "application/x-protobuf",
+		"Content-Encoding":                  "snappy",
+		"X-Prometheus-Remote-Write-Version": "0.1.0",
+	})
+}
+
+// remoteWriteLabel and remoteWriteSeries are the Label and TimeSeries
+// messages of the remote_write WriteRequest, with a single sample per series.
+type remoteWriteLabel struct {
+	name, value string
+}
+
+type remoteWriteSeries struct {
+	labels    []remoteWriteLabel
+	value     float64
+	timestamp int64
+}
+
+// Encode returns the snappy-compressed WriteRequest of families. Histograms
+// and summaries are split into their _bucket, _sum and _count series the way
+// Prometheus stores them.
+func (r *RemoteWriteReceiver) Encode(families []*dto.MetricFamily, timestamp time.Time) ([]byte, error) {
+	var request []byte
+
+	for _, family := range families {
+		for _, metric := range family.Metric {
+			milliseconds := timestamp.UnixNano() / int64(time.Millisecond)
+			if metric.TimestampMs != nil {
+				milliseconds = metric.GetTimestampMs()
+			}
+
+			add := func(suffix string, value float64, extra ...remoteWriteLabel) {
+				series := remoteWriteSeries{
+					labels:    r.labels(family.GetName()+suffix, metric, extra),
+					value:     value,
+					timestamp: milliseconds,
+				}
+				request = protowire.AppendTag(request, 1, protowire.BytesType)
+				request = protowire.AppendBytes(request, series.marshal())
+			}
+
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				add("", metric.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				add("", metric.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				add("", metric.GetUntyped().GetValue())
+			case dto.MetricType_SUMMARY:
+				summary := metric.GetSummary()
+				for _, quantile := range summary.Quantile {
+					add("", quantile.GetValue(), remoteWriteLabel{"quantile", formatFloat(quantile.GetQuantile())})
+				}
+				add("_sum", summary.GetSampleSum())
+				add("_count", float64(summary.GetSampleCount()))
+			case dto.MetricType_HISTOGRAM:
+				histogram := metric.GetHistogram()
+				infinite := false
+				for _, bucket := range histogram.Bucket {
+					add("_bucket", float64(bucket.GetCumulativeCount()), remoteWriteLabel{"le", formatFloat(bucket.GetUpperBound())})
+					infinite = infinite || math.IsInf(bucket.GetUpperBound(), 1)
+				}
+				if !infinite {
+					add("_bucket", float64(histogram.GetSampleCount()), remoteWriteLabel{"le", "+Inf"})
+				}
+				add("_sum", histogram.GetSampleSum())
+				add("_count", float64(histogram.GetSampleCount()))
+			}
+		}
+	}
+
+	return snappy.Encode(nil, request), nil
+}
+
+// labels returns the labels of a series sorted by name, as remote_write
+// requires, with the external labels the metric doesn't have itself.
+func (r *RemoteWriteReceiver) labels(name string, metric *dto.Metric, extra []remoteWriteLabel) []remoteWriteLabel {
+	labels := []remoteWriteLabel{{"__name__", name}}
+	own := make(map[string]bool)
+
+	for _, pair := range metric.Label {
+		labels = append(labels, remoteWriteLabel{pair.GetName(), pair.GetValue()})
+		own[pair.GetName()] = true
+	}
+
+	labels = append(labels, extra...)
+
+	for name, value := range r.externalLabels {
+		if !own[name] {
+			labels = append(labels, remoteWriteLabel{name, value})
+		}
+	}
+
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].name < labels[j].name
+	})
+
+	return labels
+}
+
+func (s remoteWriteSeries) marshal() []byte {
+	var series []byte
+
+	for _, label := range s.labels {
+		var encoded []byte
+		encoded = protowire.AppendTag(encoded, 1, protowire.BytesType)
+		encoded = protowire.AppendString(encoded, label.name)
+		encoded = protowire.AppendTag(encoded, 2, protowire.BytesType)
+		encoded = protowire.AppendString(encoded, label.value)
+
+		series = protowire.AppendTag(series, 1, protowire.BytesType)
+		series = protowire.AppendBytes(series, encoded)
+	}
+
+	var sample []byte
+	sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+	sample = protowire.AppendFixed64(sample, math.Float64bits(s.value))
+	sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+	sample = protowire.AppendVarint(sample, uint64(s.timestamp))
+
+	series = protowire.AppendTag(series, 2, protowire.BytesType)
+	return protowire.AppendBytes(series, sample)
+}
+
+// formatFloat formats le and quantile labels like Prometheus does.
+func formatFloat(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,225 @@
+package exporter
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestRemoteWriteReceiverEncode(t *testing.T) {
+	timestamp := time.Unix(1600000000, 0)
+
+	families := []*dto.MetricFamily{
+		{
+			Name: proto.String("cosmos_validator_tokens"),
+			Type: dto.MetricType_GAUGE.Enum(),
+			Metric: []*dto.Metric{{
+				Label: []*dto.LabelPair{
+					{Name: proto.String("chain_id"), Value: proto.String("cosmoshub-4")},
+					{Name: proto.String("address"), Value: proto.String("cosmosvaloper1abc")},
+				},
+				Gauge: &dto.Gauge{Value: proto.Float64(1.5)},
+			}},
+		},
+		{
+			Name: proto.String("cosmos_exporter_pushes_total"),
+			Type: dto.MetricType_COUNTER.Enum(),
+			Metric: []*dto.Metric{{
+				Counter:     &dto.Counter{Value: proto.Float64(3)},
+				TimestampMs: proto.Int64(1500000000000),
+			}},
+		},
+		{
+			Name: proto.String("request_seconds"),
+			Type: dto.MetricType_HISTOGRAM.Enum(),
+			Metric: []*dto.Metric{{
+				Histogram: &dto.Histogram{
+					SampleCount: proto.Uint64(4),
+					SampleSum:   proto.Float64(2.5),
+					Bucket: []*dto.Bucket{
+						{UpperBound: proto.Float64(0.5), CumulativeCount: proto.Uint64(1)},
+						{UpperBound: proto.Float64(1), CumulativeCount: proto.Uint64(3)},
+					},
+				},
+			}},
+		},
+		{
+			Name: proto.String("gc_seconds"),
+			Type: dto.MetricType_SUMMARY.Enum(),
+			Metric: []*dto.Metric{{
+				Summary: &dto.Summary{
+					SampleCount: proto.Uint64(10),
+					SampleSum:   proto.Float64(0.25),
+					Quantile: []*dto.Quantile{
+						{Quantile: proto.Float64(0.5), Value: proto.Float64(0.01)},
+						{Quantile: proto.Float64(1), Value: proto.Float64(0.1)},
+					},
+				},
+			}},
+		},
+	}
+
+	// the chain_id of the metric wins over the external label
+	receiver := NewRemoteWriteReceiver("http://localhost:9009/api/v1/push", map[string]string{
+		"instance": "validator-1",
+		"chain_id": "other",
+	})
+
+	body, err := receiver.Encode(families, timestamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := snappy.Decode(nil, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a WriteRequest only has repeated TimeSeries, field 1
+	var got []string
+	for _, field := range decodeMessage(t, decoded) {
+		if field.number != 1 {
+			t.Fatalf("unexpected field %d in the write request", field.number)
+		}
+		got = append(got, formatTimeSeries(t, field.bytes))
+	}
+
+	want := []string{
+		`{__name__="cosmos_validator_tokens",address="cosmosvaloper1abc",chain_id="cosmoshub-4",instance="validator-1"} 1.5 @1600000000000`,
+		`{__name__="cosmos_exporter_pushes_total",chain_id="other",instance="validator-1"} 3 @1500000000000`,
+		`{__name__="request_seconds_bucket",chain_id="other",instance="validator-1",le="0.5"} 1 @1600000000000`,
+		`{__name__="request_seconds_bucket",chain_id="other",instance="validator-1",le="1"} 3 @1600000000000`,
+		`{__name__="request_seconds_bucket",chain_id="other",instance="validator-1",le="+Inf"} 4 @1600000000000`,
+		`{__name__="request_seconds_sum",chain_id="other",instance="validator-1"} 2.5 @1600000000000`,
+		`{__name__="request_seconds_count",chain_id="other",instance="validator-1"} 4 @1600000000000`,
+		`{__name__="gc_seconds",chain_id="other",instance="validator-1",quantile="0.5"} 0.01 @1600000000000`,
+		`{__name__="gc_seconds",chain_id="other",instance="validator-1",quantile="1"} 0.1 @1600000000000`,
+		`{__name__="gc_seconds_sum",chain_id="other",instance="validator-1"} 0.25 @1600000000000`,
+		`{__name__="gc_seconds_count",chain_id="other",instance="validator-1"} 10 @1600000000000`,
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded series:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// wireField is a field of an encoded protobuf message, with the value of a
+// length-delimited field in bytes, and the others in number.
+type wireField struct {
+	number protowire.Number
+	bytes  []byte
+	value  uint64
+}
+
+// decodeMessage decodes the fields of an encoded protobuf message, so the
+// encoding is checked against the wire format without the generated types.
+func decodeMessage(t *testing.T, message []byte) []wireField {
+	t.Helper()
+
+	var fields []wireField
+	for len(message) > 0 {
+		number, wireType, n := protowire.ConsumeTag(message)
+		if n < 0 {
+			t.Fatal(protowire.ParseError(n))
+		}
+		message = message[n:]
+
+		field := wireField{number: number}
+		switch wireType {
+		case protowire.BytesType:
+			field.bytes, n = protowire.ConsumeBytes(message)
+		case protowire.VarintType:
+			field.value, n = protowire.ConsumeVarint(message)
+		case protowire.Fixed64Type:
+			field.value, n = protowire.ConsumeFixed64(message)
+		default:
+			t.Fatalf("unexpected wire type %d of field %d", wireType, number)
+		}
+		if n < 0 {
+			t.Fatal(protowire.ParseError(n))
+		}
+		message = message[n:]
+
+		fields = append(fields, field)
+	}
+
+	return fields
+}
+
+// formatTimeSeries formats the labels and the single sample of an encoded
+// TimeSeries, and checks the labels are sorted as remote_write requires.
+func formatTimeSeries(t *testing.T, series []byte) string {
+	t.Helper()
+
+	var labels, names []string
+	var samples []string
+	for _, field := range decodeMessage(t, series) {
+		switch field.number {
+		case 1:
+			// Label: name 1, value 2
+			var name, value string
+			for _, labelField := range decodeMessage(t, field.bytes) {
+				switch labelField.number {
+				case 1:
+					name = string(labelField.bytes)
+				case 2:
+					value = string(labelField.bytes)
+				}
+			}
+
+			if len(names) > 0 && names[len(names)-1] >= name {
+				t.Errorf("labels %v aren't sorted", append(names, name))
+			}
+			names = append(names, name)
+			labels = append(labels, fmt.Sprintf("%s=%q", name, value))
+		case 2:
+			// Sample: double value 1, int64 timestamp 2
+			var value float64
+			var timestamp int64
+			for _, sampleField := range decodeMessage(t, field.bytes) {
+				switch sampleField.number {
+				case 1:
+					value = math.Float64frombits(sampleField.value)
+				case 2:
+					timestamp = int64(sampleField.value)
+				}
+			}
+			samples = append(samples, fmt.Sprintf("%s @%d", formatFloat(value), timestamp))
+		default:
+			t.Fatalf("unexpected field %d in a time series", field.number)
+		}
+	}
+
+	if len(samples) != 1 {
+		t.Fatalf("series %v has %d samples, want 1", labels, len(samples))
+	}
+
+	return fmt.Sprintf("{%s} %s", strings.Join(labels, ","), samples[0])
+}
+
+func TestFormatFloat(t *testing.T) {
+	tests := []struct {
+		value float64
+		want  string
+	}{
+		{0.005, "0.005"},
+		{1, "1"},
+		{2.5, "2.5"},
+		{1e+06, "1e+06"},
+		{math.Inf(1), "+Inf"},
+	}
+
+	for _, test := range tests {
+		if got := formatFloat(test.value); got != test.want {
+			t.Errorf("formatFloat(%v) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
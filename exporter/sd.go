@@ -0,0 +1,206 @@
+package exporter
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cosmos/cosmos-sdk/simapp"
+	querytypes "github.com/cosmos/cosmos-sdk/types/query"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+)
+
+// TargetGroup is an entry of the Prometheus HTTP SD format. Targets point at
+// the exporter itself, and the __metrics_path__ and __param_* labels make
+// Prometheus scrape the endpoint of each validator or wallet without any
+// relabeling.
+type TargetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// ServiceDiscovery serves the validators of a chain and the wallets of the
+// targets config for Prometheus http_sd_configs.
+type ServiceDiscovery struct {
+	chains  *Chains
+	targets TargetsConfig
+}
+
+func NewServiceDiscovery(chains *Chains, targets TargetsConfig) *ServiceDiscovery {
+	return &ServiceDiscovery{chains: chains, targets: targets}
+}
+
+// sdEndpoints are the metrics endpoints the targets of each service discovery
+// path are scraped from.
+var sdEndpoints = map[string]string{
+	"/sd/validators": "/metrics/validator",
+	"/sd/wallets":    "/metrics/wallet",
+}
+
+// validatorStatuses are the values of the status parameter of /sd/validators.
+var validatorStatuses = map[string]stakingtypes.BondStatus{
+	"bonded":    stakingtypes.Bonded,
+	"unbonding": stakingtypes.Unbonding,
+	"unbonded":  stakingtypes.Unbonded,
+}
+
+// bondStatusName is the name of a validator status the status parameter takes,
+// e.g. bonded for BOND_STATUS_BONDED.
+func bondStatusName(status stakingtypes.BondStatus) string {
+	return strings.ToLower(strings.TrimPrefix(status.String(), "BOND_STATUS_"))
+}
+
+// Validators lists the validators of the chain parameter, optionally only
+// those with the status parameter and a moniker matching the moniker regex.
+func (sd *ServiceDiscovery) Validators(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	chain, err := sd.chains.Get(query.Get("chain"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	var status stakingtypes.BondStatus
+	if value := query.Get("status"); value != "" {
+		var ok bool
+		if status, ok = validatorStatuses[value]; !ok {
+			writeError(w, r, BadRequest("invalid status %q, expected bonded, unbonding or unbonded", value))
+			return
+		}
+	}
+
+	var moniker *regexp.Regexp
+	if value := query.Get("moniker"); value != "" {
+		if moniker, err = regexp.Compile(value); err != nil {
+			writeError(w, r, BadRequest("invalid moniker regex %q: %s", value, err))
+			return
+		}
+	}
+
+	ctx, cancel := scrapeContext(r)
+	defer cancel()
+
+	stakingClient := stakingtypes.NewQueryClient(chain.GRPC)
+	var validators []stakingtypes.Validator
+	err = paginate(nil, "Validators", func(pageRequest *querytypes.PageRequest) (*querytypes.PageResponse, error) {
+		request := &stakingtypes.QueryValidatorsRequest{Pagination: pageRequest}
+		if status != stakingtypes.Unspecified {
+			request.Status = status.String()
+		}
+
+		response, err := stakingClient.Validators(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+
+		validators = append(validators, response.Validators...)
+		return response.Pagination, nil
+	})
+	if err != nil {
+		writeError(w, r, targetError(err))
+		return
+	}
+
+	interfaceRegistry := simapp.MakeTestEncodingConfig().InterfaceRegistry
+
+	groups := []TargetGroup{}
+	for _, validator := range validators {
+		if moniker != nil && !moniker.MatchString(validator.Description.Moniker) {
+			continue
+		}
+
+		// moniker, status and consensus_address are target labels, so the
+		// scraped series get them without any relabeling. Prometheus drops
+		// the __meta_ copies after relabeling, they're only there to
+		// relabel from.
+		labels := sd.labels(sdEndpoints["/sd/validators"], query.Get("chain"), validator.OperatorAddress)
+		labels["moniker"] = validator.Description.Moniker
+		labels["status"] = bondStatusName(validator.Status)
+		labels["__meta_cosmos_validator_moniker"] = validator.Description.Moniker
+		labels["__meta_cosmos_validator_status"] = bondStatusName(validator.Status)
+		labels["__meta_cosmos_validator_jailed"] = strconv.FormatBool(validator.Jailed)
+
+		if err := validator.UnpackInterfaces(interfaceRegistry); err != nil {
+			log.Error().
+				Str("address", validator.OperatorAddress).
+				Err(err).
+				Msg("Could not unpack validator interfaces")
+		} else if consAddress, err := validator.GetConsAddr(); err != nil {
+			log.Error().
+				Str("address", validator.OperatorAddress).
+				Err(err).
+				Msg("Could not get validator consensus address")
+		} else if encoded, err := chain.ConsAddress(consAddress); err == nil {
+			labels["consensus_address"] = encoded
+			labels["__meta_cosmos_validator_consensus_address"] = encoded
+		}
+
+		groups = append(groups, TargetGroup{Targets: []string{r.Host}, Labels: labels})
+	}
+
+	sd.serve(w, r, groups)
+}
+
+// Wallets lists the wallets of the targets config, only those of the chain
+// parameter if it's set.
+func (sd *ServiceDiscovery) Wallets(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var chain *Chain
+	if name := query.Get("chain"); name != "" {
+		var err error
+		if chain, err = sd.chains.Get(name); err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+
+	groups := []TargetGroup{}
+	for _, target := range sd.targets.Wallets {
+		if targetChain, err := sd.chains.Get(target.Chain); err != nil || (chain != nil && targetChain != chain) {
+			continue
+		}
+
+		labels := sd.labels(sdEndpoints["/sd/wallets"], target.Chain, target.Address)
+		labels["__meta_cosmos_wallet_name"] = target.Name
+
+		groups = append(groups, TargetGroup{Targets: []string{r.Host}, Labels: labels})
+	}
+
+	sd.serve(w, r, groups)
+}
+
+// labels are the labels that make Prometheus scrape path with the address
+// and chain parameters.
+func (sd *ServiceDiscovery) labels(path, chain, address string) map[string]string {
+	labels := map[string]string{
+		"__metrics_path__":      path,
+		"__param_address":       address,
+		"__meta_cosmos_address": address,
+	}
+
+	if chain != "" {
+		labels["__param_chain"] = chain
+		labels["__meta_cosmos_chain"] = chain
+	}
+
+	return labels
+}
+
+func (sd *ServiceDiscovery) serve(w http.ResponseWriter, r *http.Request, groups []TargetGroup) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(groups); err != nil {
+		log.Error().Err(err).Msg("Could not write service discovery response")
+		return
+	}
+
+	log.Info().
+		Str("method", "GET").
+		Str("endpoint", r.URL.RequestURI()).
+		Int("targets", len(groups)).
+		Msg("Request processed")
+}
@@ -0,0 +1,182 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keys/ed25519"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeStakingServer answers the validators query with validators, filtered by
+// status like the node does.
+type fakeStakingServer struct {
+	stakingtypes.UnimplementedQueryServer
+	validators []stakingtypes.Validator
+}
+
+func (s *fakeStakingServer) Validators(ctx context.Context, request *stakingtypes.QueryValidatorsRequest) (*stakingtypes.QueryValidatorsResponse, error) {
+	response := &stakingtypes.QueryValidatorsResponse{}
+	for _, validator := range s.validators {
+		if request.Status == "" || request.Status == validator.Status.String() {
+			response.Validators = append(response.Validators, validator)
+		}
+	}
+
+	return response, nil
+}
+
+// testSDChain returns a chain whose gRPC node is an in-memory server of
+// validators.
+func testSDChain(t *testing.T, validators []stakingtypes.Validator) *Chain {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	stakingtypes.RegisterQueryServer(server, &fakeStakingServer{validators: validators})
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet", grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, address string) (net.Conn, error) {
+		return listener.Dial()
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := &BackendPool{kind: "grpc", backends: []*Backend{{Address: "bufnet", conn: conn}}}
+	if err := pool.init(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+
+	return &Chain{
+		Name:     "cosmoshub",
+		GRPC:     pool,
+		Prefixes: Bech32Prefixes{ConsensusNode: "cosmosvalcons"},
+	}
+}
+
+func TestServiceDiscoveryValidators(t *testing.T) {
+	type testValidator struct {
+		moniker string
+		status  stakingtypes.BondStatus
+		jailed  bool
+	}
+
+	var validators []stakingtypes.Validator
+	var labels []map[string]string
+	for _, v := range []testValidator{
+		{moniker: "alpha", status: stakingtypes.Bonded},
+		{moniker: "beta", status: stakingtypes.Unbonding, jailed: true},
+	} {
+		pubKey := ed25519.GenPrivKey().PubKey()
+		operator := sdk.ValAddress(pubKey.Address())
+
+		validator, err := stakingtypes.NewValidator(operator, pubKey, stakingtypes.Description{Moniker: v.moniker})
+		if err != nil {
+			t.Fatal(err)
+		}
+		validator.Status = v.status
+		validator.Jailed = v.jailed
+		validators = append(validators, validator)
+
+		consensusAddress, err := bech32.ConvertAndEncode("cosmosvalcons", pubKey.Address())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		labels = append(labels, map[string]string{
+			"__metrics_path__":                          "/metrics/validator",
+			"__param_address":                           operator.String(),
+			"__meta_cosmos_address":                     operator.String(),
+			"moniker":                                   v.moniker,
+			"status":                                    bondStatusName(v.status),
+			"consensus_address":                         consensusAddress,
+			"__meta_cosmos_validator_moniker":           v.moniker,
+			"__meta_cosmos_validator_status":            bondStatusName(v.status),
+			"__meta_cosmos_validator_jailed":            strconv.FormatBool(v.jailed),
+			"__meta_cosmos_validator_consensus_address": consensusAddress,
+		})
+	}
+	alpha, beta := labels[0], labels[1]
+
+	// the chain parameter is passed on to the scrapes
+	alphaWithChain := map[string]string{
+		"__param_chain":       "cosmoshub",
+		"__meta_cosmos_chain": "cosmoshub",
+	}
+	for name, value := range alpha {
+		alphaWithChain[name] = value
+	}
+
+	previousTimeout := ScrapeTimeout
+	ScrapeTimeout = 10 * time.Second
+	t.Cleanup(func() { ScrapeTimeout = previousTimeout })
+
+	sd := NewServiceDiscovery(NewChains(testSDChain(t, validators)), TargetsConfig{})
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		want       []map[string]string
+	}{
+		{name: "all", want: []map[string]string{alpha, beta}},
+		{name: "bonded", query: "status=bonded", want: []map[string]string{alpha}},
+		{name: "unbonded", query: "status=unbonded", want: []map[string]string{}},
+		{name: "moniker", query: "moniker=^b", want: []map[string]string{beta}},
+		{name: "chain", query: "chain=cosmoshub&status=bonded", want: []map[string]string{alphaWithChain}},
+		{name: "invalid status", query: "status=active", wantStatus: http.StatusBadRequest},
+		{name: "invalid moniker", query: "moniker=(", wantStatus: http.StatusBadRequest},
+		{name: "unknown chain", query: "chain=osmosis", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			sd.Validators(w, httptest.NewRequest(http.MethodGet, "http://exporter:9300/sd/validators?"+test.query, nil))
+
+			if test.wantStatus != 0 {
+				if w.Code != test.wantStatus {
+					t.Errorf("status = %d, want %d", w.Code, test.wantStatus)
+				}
+				return
+			}
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body)
+			}
+
+			var groups []TargetGroup
+			if err := json.Unmarshal(w.Body.Bytes(), &groups); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(groups) != len(test.want) {
+				t.Fatalf("got %d target groups, want %d: %s", len(groups), len(test.want), w.Body)
+			}
+
+			for i, group := range groups {
+				if !reflect.DeepEqual(group.Targets, []string{"exporter:9300"}) {
+					t.Errorf("targets of group %d = %q, want the exporter itself", i, group.Targets)
+				}
+
+				if !reflect.DeepEqual(group.Labels, test.want[i]) {
+					t.Errorf("labels of group %d = %v, want %v", i, group.Labels, test.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package exporter
+
+import (
+	"os"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// The settings shared by all the chains. They're set from the flags of the
+// command before any chain is created, and don't change after that.
+var (
+	Limit    uint64 = 1000
+	MaxPages        = 100
+
+	ScrapeTimeout       = 10 * time.Second
+	ScrapeTimeoutOffset = 500 * time.Millisecond
+
+	HTTPReadTimeout  = 30 * time.Second
+	HTTPWriteTimeout = 60 * time.Second
+	HTTPIdleTimeout  = 120 * time.Second
+
+	HealthCheckInterval = 15 * time.Second
+)
+
+var log = zerolog.New(zerolog.ConsoleWriter{Out: os.Stdout}).With().Timestamp().Logger()
@@ -1,9 +1,10 @@
-package main
+package exporter
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
-	"net/http"
 	"regexp"
 	"strconv"
 	"strings"
@@ -12,9 +13,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/zerolog"
-	"google.golang.org/grpc"
 )
 
 type StatusResponse struct {
@@ -85,32 +84,54 @@ type ConsensusStateResponse struct {
 	} `json:"result"`
 }
 
-func StatusHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.ClientConn) {
-	requestStart := time.Now()
+type StatusCollector struct {
+	chain *Chain
+}
+
+func NewStatusCollector(chain *Chain) *StatusCollector {
+	return &StatusCollector{
+		chain: chain,
+	}
+}
+
+func (c *StatusCollector) Name() string {
+	return "status"
+}
+
+func (c *StatusCollector) Labels() prometheus.Labels {
+	return c.chain.Labels()
+}
 
+var describeStatusMetrics = describeOnce(func() []prometheus.Collector {
+	return newStatusMetrics("").collectors()
+})
+
+func (c *StatusCollector) Describe(ch chan<- *prometheus.Desc) {
+	describeStatusMetrics(ch)
+}
+
+func (c *StatusCollector) Collect(ch chan<- prometheus.Metric) {
+	collectWithTimeout(c, ch)
+}
+
+func (c *StatusCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
 	sublogger := log.With().
 		Str("request_id", uuid.New().String()).
 		Logger()
 
-	blockAgeGauge := prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name:        "block_age",
-			Help:        "Age of the latest block in seconds",
-			ConstLabels: ConstLabels,
-		},
-	)
-
-	missingValidatorsGauge := prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name:        "missing_validators",
-			Help:        "Number of missing validators for the latest block",
-			ConstLabels: ConstLabels,
-		},
-	)
-
-	registry := prometheus.NewRegistry()
-	registry.MustRegister(blockAgeGauge)
-	registry.MustRegister(missingValidatorsGauge)
+	info, ok := c.chain.ScrapeInfo(ctx)
+	if !ok {
+		sublogger.Warn().
+			Str("chain", c.chain.Name).
+			Msg("Chain ID and denom are not resolved yet, skipping")
+		return
+	}
+
+	observer := NewScrapeObserver(ctx, info.ChainID, c.Name())
+	defer observer.Finish(ch)
+
+	metrics := newStatusMetrics(info.ChainID)
+	rpcAddress := c.chain.RPC.Address()
 
 	// Set the metric values
 	wg := sync.WaitGroup{}
@@ -118,7 +139,10 @@ func StatusHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Client
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		err := setBlockAge(&blockAgeGauge, &sublogger)
+		defer observer.Recover("Status")
+		queryStart := time.Now()
+		err := setBlockAge(ctx, rpcAddress, &metrics.blockAgeGauge, &sublogger)
+		observer.Observe("Status", queryStart, err)
 		if err != nil {
 			sublogger.Error().Err(err).Msg("Failed to set block age")
 		}
@@ -127,7 +151,10 @@ func StatusHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Client
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		err := setMissingValidators(&missingValidatorsGauge, &sublogger)
+		defer observer.Recover("ConsensusState")
+		queryStart := time.Now()
+		err := setMissingValidators(ctx, rpcAddress, &metrics.missingValidatorsGauge, &sublogger)
+		observer.Observe("ConsensusState", queryStart, err)
 		if err != nil {
 			sublogger.Error().Err(err).Msg("Failed to set missing validators")
 		}
@@ -135,22 +162,17 @@ func StatusHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Client
 
 	wg.Wait()
 
-	h := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
-	h.ServeHTTP(w, r)
-	sublogger.Info().
-		Str("method", "GET").
-		Str("endpoint", "/metrics/status").
-		Float64("request_time", time.Since(requestStart).Seconds()).
-		Msg("Request processed")
+	collectAll(ch, metrics.collectors())
 }
 
-func setBlockAge(gaugePtr *prometheus.Gauge, sublogger *zerolog.Logger) error {
+func setBlockAge(ctx context.Context, rpcAddress string, gaugePtr *prometheus.Gauge, sublogger *zerolog.Logger) error {
 	// /status endpoint
-	resp, err := http.Get(TendermintRPC + "/status")
+	resp, err := httpGet(ctx, rpcAddress+"/status")
 	if err != nil {
 		sublogger.Error().
 			Err(err).
 			Msg("Error getting the status")
+		return err
 	}
 
 	if resp.Body != nil {
@@ -162,6 +184,7 @@ func setBlockAge(gaugePtr *prometheus.Gauge, sublogger *zerolog.Logger) error {
 		sublogger.Error().
 			Err(readErr).
 			Msg("Error reading the status")
+		return readErr
 	}
 
 	statusResponse := StatusResponse{}
@@ -170,6 +193,7 @@ func setBlockAge(gaugePtr *prometheus.Gauge, sublogger *zerolog.Logger) error {
 		sublogger.Error().
 			Err(err).
 			Msg("Error unmarshalling the status json response")
+		return err
 	}
 	gauge := *gaugePtr
 
@@ -177,12 +201,13 @@ func setBlockAge(gaugePtr *prometheus.Gauge, sublogger *zerolog.Logger) error {
 	return nil
 }
 
-func setMissingValidators(gaugePtr *prometheus.Gauge, sublogger *zerolog.Logger) error {
-	resp, err := http.Get(TendermintRPC + "/consensus_state")
+func setMissingValidators(ctx context.Context, rpcAddress string, gaugePtr *prometheus.Gauge, sublogger *zerolog.Logger) error {
+	resp, err := httpGet(ctx, rpcAddress+"/consensus_state")
 	if err != nil {
 		sublogger.Error().
 			Err(err).
 			Msg("Error getting the consensus_state")
+		return err
 	}
 
 	if resp.Body != nil {
@@ -194,6 +219,7 @@ func setMissingValidators(gaugePtr *prometheus.Gauge, sublogger *zerolog.Logger)
 		sublogger.Error().
 			Err(readErr).
 			Msg("Error reading the consensus_state")
+		return readErr
 	}
 
 	consensusStateResponse := ConsensusStateResponse{}
@@ -202,18 +228,61 @@ func setMissingValidators(gaugePtr *prometheus.Gauge, sublogger *zerolog.Logger)
 		sublogger.Error().
 			Err(err).
 			Msg("Error unmarshalling the consensus_state json response")
+		return err
+	}
+
+	if len(consensusStateResponse.Result.RoundState.HeightVoteSet) == 0 {
+		return fmt.Errorf("consensus_state response has no height vote set")
 	}
 
 	summaryLine := consensusStateResponse.Result.RoundState.HeightVoteSet[0].PrecommitsBitArray
 	validatorsSignedCount := strings.Count(strings.ToLower(summaryLine), "x")
 	r, _ := regexp.Compile("{[0-9]+:")
-	validatorsTotal, err := strconv.Atoi(r.FindString(summaryLine)[1 : len(r.FindString(summaryLine))-1])
+	validatorsTotalMatch := r.FindString(summaryLine)
+	if validatorsTotalMatch == "" {
+		return fmt.Errorf("unexpected precommits bit array %q", summaryLine)
+	}
+
+	validatorsTotal, err := strconv.Atoi(validatorsTotalMatch[1 : len(validatorsTotalMatch)-1])
 	if err != nil {
 		sublogger.Error().
 			Err(err).
 			Msg("Error getting the validators total")
+		return err
 	}
 	gauge := *gaugePtr
 	gauge.Set(float64(validatorsTotal - validatorsSignedCount))
 	return nil
 }
+
+type statusMetrics struct {
+	blockAgeGauge          prometheus.Gauge
+	missingValidatorsGauge prometheus.Gauge
+}
+
+func newStatusMetrics(chainID string) *statusMetrics {
+	return &statusMetrics{
+		blockAgeGauge: newChainGauge(
+			prometheus.GaugeOpts{
+				Name: "block_age",
+				Help: "Age of the latest block in seconds",
+			},
+			chainID,
+		),
+
+		missingValidatorsGauge: newChainGauge(
+			prometheus.GaugeOpts{
+				Name: "missing_validators",
+				Help: "Number of missing validators for the latest block",
+			},
+			chainID,
+		),
+	}
+}
+
+func (m *statusMetrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		m.blockAgeGauge,
+		m.missingValidatorsGauge,
+	}
+}
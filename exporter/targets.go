@@ -0,0 +1,238 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// TargetsConfig is the targets object of the config file: the validators,
+// wallets, orchestrators and Osmosis pools /metrics exports along with the
+// chain-wide metrics, so they don't each need a scrape job with relabeling.
+// Every target has a name, added to its metrics as the name label, and an
+// optional chain, the default chain if it's empty.
+type TargetsConfig struct {
+	Validators    []AddressTarget      `mapstructure:"validators"`
+	Wallets       []AddressTarget      `mapstructure:"wallets"`
+	Orchestrators []OrchestratorTarget `mapstructure:"orchestrators"`
+	OsmosisPools  []OsmosisPoolTarget  `mapstructure:"osmosis-pools"`
+}
+
+type AddressTarget struct {
+	Name    string `mapstructure:"name"`
+	Chain   string `mapstructure:"chain"`
+	Address string `mapstructure:"address"`
+}
+
+// OrchestratorTarget is a gravity bridge orchestrator, which is only
+// configured for the default chain.
+type OrchestratorTarget struct {
+	Name            string `mapstructure:"name"`
+	CudosAddress    string `mapstructure:"cudos-orchestrator-address"`
+	EthereumAddress string `mapstructure:"ethereum-orchestrator-address"`
+}
+
+type OsmosisPoolTarget struct {
+	Name        string `mapstructure:"name"`
+	Chain       string `mapstructure:"chain"`
+	PoolID      string `mapstructure:"pool-id"`
+	PriceDenoms string `mapstructure:"price-denoms"`
+}
+
+// Check validates the targets against the prefixes of the chains they
+// belong to, keyed by chain name, with the default chain under "".
+func (t TargetsConfig) Check(prefixes map[string]Bech32Prefixes) []error {
+	var errs []error
+
+	checkName := func(scope, name string, names map[string]bool) {
+		if name == "" {
+			errs = append(errs, fmt.Errorf("%s: name is required", scope))
+		} else if names[name] {
+			errs = append(errs, fmt.Errorf("%s: duplicate name %s", scope, name))
+		}
+		names[name] = true
+	}
+
+	checkAddress := func(scope, chain, address string, prefix func(Bech32Prefixes) string) {
+		chainPrefixes, ok := prefixes[chain]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: unknown chain %s", scope, chain))
+			return
+		}
+
+		if _, err := sdk.GetFromBech32(address, prefix(chainPrefixes)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid address %q: %w", scope, address, err))
+		}
+	}
+
+	accountPrefix := func(p Bech32Prefixes) string { return p.Account }
+	validatorPrefix := func(p Bech32Prefixes) string { return p.Validator }
+
+	names := make(map[string]bool)
+	for i, target := range t.Validators {
+		scope := fmt.Sprintf("targets.validators[%d]", i)
+		checkName(scope, target.Name, names)
+		checkAddress(scope, target.Chain, target.Address, validatorPrefix)
+	}
+
+	names = make(map[string]bool)
+	for i, target := range t.Wallets {
+		scope := fmt.Sprintf("targets.wallets[%d]", i)
+		checkName(scope, target.Name, names)
+		checkAddress(scope, target.Chain, target.Address, accountPrefix)
+	}
+
+	names = make(map[string]bool)
+	for i, target := range t.Orchestrators {
+		scope := fmt.Sprintf("targets.orchestrators[%d]", i)
+		checkName(scope, target.Name, names)
+		checkAddress(scope, "", target.CudosAddress, accountPrefix)
+
+		if !common.IsHexAddress(target.EthereumAddress) {
+			errs = append(errs, fmt.Errorf("%s: invalid Ethereum address %q", scope, target.EthereumAddress))
+		}
+	}
+
+	names = make(map[string]bool)
+	for i, target := range t.OsmosisPools {
+		scope := fmt.Sprintf("targets.osmosis-pools[%d]", i)
+		checkName(scope, target.Name, names)
+
+		if _, ok := prefixes[target.Chain]; !ok {
+			errs = append(errs, fmt.Errorf("%s: unknown chain %s", scope, target.Chain))
+		}
+
+		if _, err := strconv.ParseUint(target.PoolID, 10, 64); err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid pool-id %q, expected a pool number", scope, target.PoolID))
+		}
+	}
+
+	return errs
+}
+
+// Collectors returns the collectors of the targets of chain, each with its
+// name label. ethConn is nil if the Ethereum node isn't available, and then
+// the orchestrators are left out.
+func (t TargetsConfig) Collectors(chains *Chains, chain *Chain, ethConn *ethclient.Client) ([]Collector, error) {
+	var collectors []Collector
+
+	add := func(name string, collector Collector, err error) error {
+		if err != nil {
+			return fmt.Errorf("target %s: %w", name, err)
+		}
+
+		collectors = append(collectors, NewNamedCollector(name, collector))
+		return nil
+	}
+
+	belongs := func(name string) bool {
+		targetChain, err := chains.Get(name)
+		return err == nil && targetChain == chain
+	}
+
+	for _, target := range t.Validators {
+		if belongs(target.Chain) {
+			collector, err := NewValidatorCollector(chain, target.Address)
+			if err := add(target.Name, collector, err); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, target := range t.Wallets {
+		if belongs(target.Chain) {
+			collector, err := NewWalletCollector(chain, chain.GRPC, target.Address)
+			if err := add(target.Name, collector, err); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if chain == chains.Default && ethConn != nil {
+		for _, target := range t.Orchestrators {
+			collector, err := NewGravityBridgeWalletCollector(chain, ethConn, target.CudosAddress, target.EthereumAddress)
+			if err := add(target.Name, collector, err); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, target := range t.OsmosisPools {
+		if belongs(target.Chain) {
+			collector, err := NewOsmosisCollector(chain, target.PoolID, target.PriceDenoms)
+			if err := add(target.Name, collector, err); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return collectors, nil
+}
+
+// NamedCollector adds the name label of a target to all the metrics of its
+// collector, including the exporter's own ones like cosmos_exporter_up, so
+// several targets of the same kind can be collected together.
+type NamedCollector struct {
+	name      string
+	collector Collector
+}
+
+func NewNamedCollector(name string, collector Collector) *NamedCollector {
+	return &NamedCollector{name: name, collector: collector}
+}
+
+func (c *NamedCollector) Name() string {
+	return c.collector.Name()
+}
+
+func (c *NamedCollector) Labels() prometheus.Labels {
+	return c.collector.Labels()
+}
+
+// Describe sends nothing, so the collector is unchecked: the metrics of the
+// targets have the name label, while the chain-wide ones collected along
+// with them, like their cosmos_exporter_up, don't.
+func (c *NamedCollector) Describe(ch chan<- *prometheus.Desc) {
+}
+
+func (c *NamedCollector) Collect(ch chan<- prometheus.Metric) {
+	collectWithTimeout(c, ch)
+}
+
+func (c *NamedCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	withLabels(prometheus.Labels{"name": c.name}, WithContext(ctx, c.collector)).Collect(ch)
+}
+
+// withLabels returns collector with labels added to all of its metrics, by
+// the same wrapper prometheus.WrapRegistererWith registers.
+func withLabels(labels prometheus.Labels, collector prometheus.Collector) prometheus.Collector {
+	registerer := &capturingRegisterer{}
+	prometheus.WrapRegistererWith(labels, registerer).MustRegister(collector)
+	return registerer.collector
+}
+
+// capturingRegisterer keeps the collector it's given instead of registering
+// it.
+type capturingRegisterer struct {
+	collector prometheus.Collector
+}
+
+func (r *capturingRegisterer) Register(collector prometheus.Collector) error {
+	r.collector = collector
+	return nil
+}
+
+func (r *capturingRegisterer) MustRegister(collectors ...prometheus.Collector) {
+	for _, collector := range collectors {
+		r.collector = collector
+	}
+}
+
+func (r *capturingRegisterer) Unregister(prometheus.Collector) bool {
+	return false
+}
@@ -0,0 +1,75 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func mergeLabels(labelSets ...prometheus.Labels) prometheus.Labels {
+	merged := prometheus.Labels{}
+	for _, labels := range labelSets {
+		for name, value := range labels {
+			merged[name] = value
+		}
+	}
+	return merged
+}
+
+// labelsKey identifies a label set, e.g. as a map key.
+func labelsKey(labels prometheus.Labels) string {
+	pairs := make([]string, 0, len(labels))
+	for name, value := range labels {
+		pairs = append(pairs, name+"\xff"+value)
+	}
+
+	sort.Strings(pairs)
+	return strings.Join(pairs, "\xfe")
+}
+
+// httpGet is http.Get with the request bound to ctx.
+func httpGet(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return http.DefaultClient.Do(req)
+}
+
+// CheckURL validates a URL from the config, with one of the given schemes.
+func CheckURL(key, address string, schemes ...string) error {
+	parsed, err := url.Parse(address)
+	if err != nil {
+		return fmt.Errorf("%s: invalid URL %q: %w", key, address, err)
+	}
+
+	for _, scheme := range schemes {
+		if parsed.Scheme != scheme {
+			continue
+		}
+
+		if scheme != "" && scheme != "unix" && parsed.Host == "" {
+			return fmt.Errorf("%s: URL %q has no host", key, address)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("%s: URL %q must start with one of %s://", key, address, strings.Join(schemes, "://, "))
+}
+
+// boolValue is the value of a gauge of a condition, 1 if it's true and 0 if
+// not.
+func boolValue(condition bool) float64 {
+	if condition {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,758 @@
+package exporter
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/simapp"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	querytypes "github.com/cosmos/cosmos-sdk/types/query"
+	distributiontypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	slashingtypes "github.com/cosmos/cosmos-sdk/x/slashing/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type ValidatorCollector struct {
+	chain   *Chain
+	address string
+}
+
+func NewValidatorCollector(chain *Chain, address string) (*ValidatorCollector, error) {
+	if address == "" {
+		return nil, BadRequest("address parameter is required")
+	}
+
+	if _, err := chain.ParseValAddress(address); err != nil {
+		return nil, BadRequest("invalid validator address %q: %s", address, err)
+	}
+
+	return &ValidatorCollector{
+		chain:   chain,
+		address: address,
+	}, nil
+}
+
+func (c *ValidatorCollector) Name() string {
+	return "validator"
+}
+
+func (c *ValidatorCollector) Labels() prometheus.Labels {
+	return c.chain.Labels()
+}
+
+var describeValidatorMetrics = describeOnce(func() []prometheus.Collector {
+	return newValidatorMetrics("").collectors()
+})
+
+func (c *ValidatorCollector) Describe(ch chan<- *prometheus.Desc) {
+	describeValidatorMetrics(ch)
+}
+
+func (c *ValidatorCollector) Collect(ch chan<- prometheus.Metric) {
+	collectWithTimeout(c, ch)
+}
+
+func (c *ValidatorCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	info, ok := c.chain.ScrapeInfo(ctx)
+	if !ok {
+		log.Warn().
+			Str("chain", c.chain.Name).
+			Msg("Chain ID and denom are not resolved yet, skipping")
+		return
+	}
+
+	observer := NewScrapeObserver(ctx, info.ChainID, c.Name())
+	defer observer.Finish(ch)
+
+	metrics := newValidatorMetrics(info.ChainID)
+	metrics.set(c.query(ctx, info, observer))
+	collectAll(ch, metrics.collectors())
+}
+
+// Query returns the validator CollectContext exports, for the API.
+func (c *ValidatorCollector) Query(ctx context.Context) interface{} {
+	info, ok := c.chain.ScrapeInfo(ctx)
+	if !ok {
+		return nil
+	}
+
+	observer := NewScrapeObserver(ctx, info.ChainID, c.Name())
+	defer observer.Done()
+
+	return c.query(ctx, info, observer)
+}
+
+// query queries the validator, with only the address if the validator query
+// failed.
+func (c *ValidatorCollector) query(ctx context.Context, info ChainInfo, observer *ScrapeObserver) APIValidatorDetails {
+	sublogger := log.With().
+		Str("request-id", uuid.New().String()).
+		Logger()
+
+	address := c.address
+
+	result := APIValidatorDetails{
+		APIValidator:  APIValidator{Address: address},
+		Commission:    []APIAmount{},
+		Rewards:       []APIAmount{},
+		Delegations:   []APIDelegation{},
+		Unbondings:    []APIDelegation{},
+		Redelegations: []APIRedelegation{},
+	}
+
+	// doing this not in goroutine as we'll need the moniker value later
+	sublogger.Debug().
+		Str("address", address).
+		Msg("Started querying validator")
+	validatorQueryStart := time.Now()
+
+	stakingClient := stakingtypes.NewQueryClient(c.chain.GRPC)
+	validator, err := stakingClient.Validator(
+		ctx,
+		&stakingtypes.QueryValidatorRequest{ValidatorAddr: address},
+	)
+	observer.Observe("Validator", validatorQueryStart, err)
+	if err != nil {
+		sublogger.Error().
+			Str("address", address).
+			Err(err).
+			Msg("Could not get validator")
+		observer.Fail(err)
+		return result
+	}
+
+	sublogger.Debug().
+		Str("address", address).
+		Float64("request-time", time.Since(validatorQueryStart).Seconds()).
+		Msg("Finished querying validator")
+
+	tokens := newAPIAmount(info.Denom, info.Amount(validator.Validator.Tokens))
+	delegatorShares := newAPIAmount(info.Denom, info.DecAmount(validator.Validator.DelegatorShares))
+
+	result.Address = validator.Validator.OperatorAddress
+	result.Moniker = validator.Validator.Description.Moniker
+	result.Status = bondStatusName(validator.Validator.Status)
+	result.Jailed = validator.Validator.Jailed
+	result.Tokens = &tokens
+	result.DelegatorShares = &delegatorShares
+
+	// because cosmos's dec doesn't have .toFloat64() method or whatever and returns everything as int
+	if rate, err := strconv.ParseFloat(validator.Validator.Commission.CommissionRates.Rate.String(), 64); err != nil {
+		sublogger.Error().
+			Str("address", address).
+			Err(err).
+			Msg("Could not parse commission rate")
+	} else {
+		result.CommissionRate = &rate
+	}
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer observer.Recover("ValidatorDelegations")
+
+		sublogger.Debug().
+			Str("address", address).
+			Msg("Started querying validator delegations")
+		queryStart := time.Now()
+
+		stakingClient := stakingtypes.NewQueryClient(c.chain.GRPC)
+		var delegations []stakingtypes.DelegationResponse
+		err := paginate(observer, "ValidatorDelegations", func(pageRequest *querytypes.PageRequest) (*querytypes.PageResponse, error) {
+			stakingRes, err := stakingClient.ValidatorDelegations(
+				ctx,
+				&stakingtypes.QueryValidatorDelegationsRequest{ValidatorAddr: address, Pagination: pageRequest},
+			)
+			if err != nil {
+				return nil, err
+			}
+
+			delegations = append(delegations, stakingRes.DelegationResponses...)
+			return stakingRes.Pagination, nil
+		})
+		observer.Observe("ValidatorDelegations", queryStart, err)
+		if err != nil {
+			sublogger.Error().
+				Str("address", address).
+				Err(err).
+				Msg("Could not get validator delegations")
+			return
+		}
+
+		sublogger.Debug().
+			Str("address", address).
+			Float64("request-time", time.Since(queryStart).Seconds()).
+			Msg("Finished querying validator delegations")
+
+		for _, delegation := range delegations {
+			denom, amount := info.Coin(delegation.Balance)
+			result.Delegations = append(result.Delegations, APIDelegation{
+				Delegator: delegation.Delegation.DelegatorAddress,
+				APIAmount: newAPIAmount(denom, amount),
+			})
+		}
+
+		sortDelegations(result.Delegations)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer observer.Recover("ValidatorCommission")
+
+		sublogger.Debug().
+			Str("address", address).
+			Msg("Started querying validator commission")
+		queryStart := time.Now()
+
+		distributionClient := distributiontypes.NewQueryClient(c.chain.GRPC)
+		distributionRes, err := distributionClient.ValidatorCommission(
+			ctx,
+			&distributiontypes.QueryValidatorCommissionRequest{ValidatorAddress: address},
+		)
+		observer.Observe("ValidatorCommission", queryStart, err)
+		if err != nil {
+			sublogger.Error().
+				Str("address", address).
+				Err(err).
+				Msg("Could not get validator commission")
+			return
+		}
+
+		sublogger.Debug().
+			Str("address", address).
+			Float64("request-time", time.Since(queryStart).Seconds()).
+			Msg("Finished querying validator commission")
+
+		for _, commission := range distributionRes.Commission.Commission {
+			result.Commission = append(result.Commission, newAPIAmount(info.DecCoin(commission)))
+		}
+
+		sortAmounts(result.Commission)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer observer.Recover("ValidatorOutstandingRewards")
+
+		sublogger.Debug().
+			Str("address", address).
+			Msg("Started querying validator rewards")
+		queryStart := time.Now()
+
+		distributionClient := distributiontypes.NewQueryClient(c.chain.GRPC)
+		distributionRes, err := distributionClient.ValidatorOutstandingRewards(
+			ctx,
+			&distributiontypes.QueryValidatorOutstandingRewardsRequest{ValidatorAddress: address},
+		)
+		observer.Observe("ValidatorOutstandingRewards", queryStart, err)
+		if err != nil {
+			sublogger.Error().
+				Str("address", address).
+				Err(err).
+				Msg("Could not get validator rewards")
+			return
+		}
+
+		sublogger.Debug().
+			Str("address", address).
+			Float64("request-time", time.Since(queryStart).Seconds()).
+			Msg("Finished querying validator rewards")
+
+		for _, reward := range distributionRes.Rewards.Rewards {
+			result.Rewards = append(result.Rewards, newAPIAmount(info.DecCoin(reward)))
+		}
+
+		sortAmounts(result.Rewards)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer observer.Recover("ValidatorUnbondingDelegations")
+
+		sublogger.Debug().
+			Str("address", address).
+			Msg("Started querying validator unbonding delegations")
+		queryStart := time.Now()
+
+		stakingClient := stakingtypes.NewQueryClient(c.chain.GRPC)
+		var unbondings []stakingtypes.UnbondingDelegation
+		err := paginate(observer, "ValidatorUnbondingDelegations", func(pageRequest *querytypes.PageRequest) (*querytypes.PageResponse, error) {
+			stakingRes, err := stakingClient.ValidatorUnbondingDelegations(
+				ctx,
+				&stakingtypes.QueryValidatorUnbondingDelegationsRequest{ValidatorAddr: address, Pagination: pageRequest},
+			)
+			if err != nil {
+				return nil, err
+			}
+
+			unbondings = append(unbondings, stakingRes.UnbondingResponses...)
+			return stakingRes.Pagination, nil
+		})
+		observer.Observe("ValidatorUnbondingDelegations", queryStart, err)
+		if err != nil {
+			sublogger.Error().
+				Str("address", address).
+				Err(err).
+				Msg("Could not get validator unbonding delegations")
+			return
+		}
+
+		sublogger.Debug().
+			Str("address", address).
+			Float64("request-time", time.Since(queryStart).Seconds()).
+			Msg("Finished querying validator unbonding delegations")
+
+		for _, unbonding := range unbondings {
+			sum := sdk.ZeroInt()
+			for _, entry := range unbonding.Entries {
+				sum = sum.Add(entry.Balance)
+			}
+
+			result.Unbondings = append(result.Unbondings, APIDelegation{
+				Delegator: unbonding.DelegatorAddress,
+				// unbonding does not have denom in response for some reason
+				APIAmount: newAPIAmount(info.Denom, info.Amount(sum)),
+			})
+		}
+
+		sortDelegations(result.Unbondings)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer observer.Recover("Redelegations")
+
+		sublogger.Debug().
+			Str("address", address).
+			Msg("Started querying validator redelegations")
+		queryStart := time.Now()
+
+		stakingClient := stakingtypes.NewQueryClient(c.chain.GRPC)
+		var redelegations []stakingtypes.RedelegationResponse
+		err := paginate(observer, "Redelegations", func(pageRequest *querytypes.PageRequest) (*querytypes.PageResponse, error) {
+			stakingRes, err := stakingClient.Redelegations(
+				ctx,
+				&stakingtypes.QueryRedelegationsRequest{SrcValidatorAddr: address, Pagination: pageRequest},
+			)
+			if err != nil {
+				return nil, err
+			}
+
+			redelegations = append(redelegations, stakingRes.RedelegationResponses...)
+			return stakingRes.Pagination, nil
+		})
+		observer.Observe("Redelegations", queryStart, err)
+		if err != nil {
+			sublogger.Error().
+				Str("address", address).
+				Err(err).
+				Msg("Could not get redelegations")
+			return
+		}
+
+		sublogger.Debug().
+			Str("address", address).
+			Float64("request-time", time.Since(queryStart).Seconds()).
+			Msg("Finished querying validator redelegations")
+
+		for _, redelegation := range redelegations {
+			sum := sdk.ZeroInt()
+			for _, entry := range redelegation.Entries {
+				sum = sum.Add(entry.Balance)
+			}
+
+			result.Redelegations = append(result.Redelegations, APIRedelegation{
+				Delegator:   redelegation.Redelegation.DelegatorAddress,
+				Destination: redelegation.Redelegation.ValidatorDstAddress,
+				// redelegation does not have denom in response for some reason
+				APIAmount: newAPIAmount(info.Denom, info.Amount(sum)),
+			})
+		}
+
+		sortRedelegations(result.Redelegations)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer observer.Recover("SigningInfo")
+
+		sublogger.Debug().
+			Str("address", address).
+			Msg("Started querying validator signing info")
+		queryStart := time.Now()
+
+		encCfg := simapp.MakeTestEncodingConfig()
+		interfaceRegistry := encCfg.InterfaceRegistry
+
+		err := validator.Validator.UnpackInterfaces(interfaceRegistry) // Unpack interfaces, to populate the Anys' cached values
+		if err != nil {
+			sublogger.Error().
+				Str("address", address).
+				Err(err).
+				Msg("Could not get unpack validator inferfaces")
+		}
+
+		pubKey, err := validator.Validator.GetConsAddr()
+		if err != nil {
+			sublogger.Error().
+				Str("address", address).
+				Err(err).
+				Msg("Could not get validator pubkey")
+		}
+
+		consAddress, err := c.chain.ConsAddress(pubKey)
+		if err != nil {
+			sublogger.Error().
+				Str("address", address).
+				Err(err).
+				Msg("Could not encode validator consensus address")
+		}
+
+		slashingClient := slashingtypes.NewQueryClient(c.chain.GRPC)
+		slashingRes, err := slashingClient.SigningInfo(
+			ctx,
+			&slashingtypes.QuerySigningInfoRequest{ConsAddress: consAddress},
+		)
+		observer.Observe("SigningInfo", queryStart, err)
+		if err != nil {
+			sublogger.Error().
+				Str("address", address).
+				Err(err).
+				Msg("Could not get validator signing info")
+			return
+		}
+
+		sublogger.Debug().
+			Str("address", address).
+			Float64("request-time", time.Since(queryStart).Seconds()).
+			Msg("Finished querying validator signing info")
+
+		sublogger.Debug().
+			Str("address", address).
+			Int64("missedBlocks", slashingRes.ValSigningInfo.MissedBlocksCounter).
+			Msg("Finished querying validator signing info")
+
+		missedBlocks := slashingRes.ValSigningInfo.MissedBlocksCounter
+		result.MissedBlocks = &missedBlocks
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer observer.Recover("Validators")
+
+		sublogger.Debug().
+			Str("address", address).
+			Msg("Started querying validator other validators")
+		queryStart := time.Now()
+
+		stakingClient := stakingtypes.NewQueryClient(c.chain.GRPC)
+		var validators []stakingtypes.Validator
+		err := paginate(observer, "Validators", func(pageRequest *querytypes.PageRequest) (*querytypes.PageResponse, error) {
+			stakingRes, err := stakingClient.Validators(
+				ctx,
+				&stakingtypes.QueryValidatorsRequest{Pagination: pageRequest},
+			)
+			if err != nil {
+				return nil, err
+			}
+
+			validators = append(validators, stakingRes.Validators...)
+			return stakingRes.Pagination, nil
+		})
+		observer.Observe("Validators", queryStart, err)
+		if err != nil {
+			sublogger.Error().
+				Str("address", address).
+				Err(err).
+				Msg("Could not get other validators")
+			return
+		}
+
+		sublogger.Debug().
+			Str("address", address).
+			Float64("request-time", time.Since(queryStart).Seconds()).
+			Msg("Finished querying validator other validators")
+
+		// sorting by delegator shares to display rankings
+		sort.Slice(validators, func(i, j int) bool {
+			return validators[i].DelegatorShares.GT(validators[j].DelegatorShares)
+		})
+
+		var validatorRank int
+
+		for index, validatorIterated := range validators {
+			if validatorIterated.OperatorAddress == validator.Validator.OperatorAddress {
+				validatorRank = index + 1
+				break
+			}
+		}
+
+		if validatorRank == 0 {
+			sublogger.Warn().
+				Str("address", address).
+				Msg("Could not find validator in validators list")
+			return
+		}
+
+		result.Rank = int64(validatorRank)
+
+		sublogger.Debug().
+			Str("address", address).
+			Msg("Started querying validator params")
+		queryStart = time.Now()
+
+		paramsRes, err := stakingClient.Params(
+			ctx,
+			&stakingtypes.QueryParamsRequest{},
+		)
+		observer.Observe("StakingParams", queryStart, err)
+		if err != nil {
+			sublogger.Error().
+				Str("address", address).
+				Err(err).
+				Msg("Could not get params")
+			return
+		}
+
+		sublogger.Debug().
+			Str("address", address).
+			Float64("request-time", time.Since(queryStart).Seconds()).
+			Msg("Finished querying validator params")
+
+		active := validatorRank <= int(paramsRes.Params.MaxValidators)
+		result.Active = &active
+	}()
+
+	wg.Wait()
+
+	return result
+}
+
+type validatorMetrics struct {
+	validatorDelegationsGauge     *AmountGaugeVec
+	validatorTokensGauge          *AmountGaugeVec
+	validatorDelegatorSharesGauge *AmountGaugeVec
+	validatorCommissionRateGauge  *prometheus.GaugeVec
+	validatorCommissionGauge      *AmountGaugeVec
+	validatorRewardsGauge         *AmountGaugeVec
+	validatorUnbondingsGauge      *AmountGaugeVec
+	validatorRedelegationsGauge   *AmountGaugeVec
+	validatorMissedBlocksGauge    *prometheus.GaugeVec
+	validatorRankGauge            *prometheus.GaugeVec
+	validatorIsActiveGauge        *prometheus.GaugeVec
+	validatorStatusGauge          *prometheus.GaugeVec
+	validatorJailedGauge          *prometheus.GaugeVec
+}
+
+func newValidatorMetrics(chainID string) *validatorMetrics {
+	return &validatorMetrics{
+		validatorDelegationsGauge: newChainAmountGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cosmos_validator_delegations",
+				Help: "Delegations of the Cosmos-based blockchain validator",
+			},
+			chainID,
+			[]string{"address", "moniker", "denom", "base_denom", "delegated_by"},
+		),
+
+		validatorTokensGauge: newChainAmountGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cosmos_validator_tokens",
+				Help: "Tokens of the Cosmos-based blockchain validator",
+			},
+			chainID,
+			[]string{"address", "moniker", "denom", "base_denom"},
+		),
+
+		validatorDelegatorSharesGauge: newChainAmountGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cosmos_validator_delegators_shares",
+				Help: "Delegators shares of the Cosmos-based blockchain validator",
+			},
+			chainID,
+			[]string{"address", "moniker", "denom", "base_denom"},
+		),
+
+		validatorCommissionRateGauge: newChainGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cosmos_validator_commission_rate",
+				Help: "Commission rate of the Cosmos-based blockchain validator",
+			},
+			chainID,
+			[]string{"address", "moniker"},
+		),
+
+		validatorCommissionGauge: newChainAmountGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cosmos_validator_commission",
+				Help: "Commission of the Cosmos-based blockchain validator",
+			},
+			chainID,
+			[]string{"address", "moniker", "denom", "base_denom"},
+		),
+
+		validatorRewardsGauge: newChainAmountGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cosmos_validator_rewards",
+				Help: "Rewards of the Cosmos-based blockchain validator",
+			},
+			chainID,
+			[]string{"address", "moniker", "denom", "base_denom"},
+		),
+
+		validatorUnbondingsGauge: newChainAmountGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cosmos_validator_unbondings",
+				Help: "Unbondings of the Cosmos-based blockchain validator",
+			},
+			chainID,
+			[]string{"address", "moniker", "denom", "base_denom", "unbonded_by"},
+		),
+
+		validatorRedelegationsGauge: newChainAmountGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cosmos_validator_redelegations",
+				Help: "Redelegations of the Cosmos-based blockchain validator",
+			},
+			chainID,
+			[]string{"address", "moniker", "denom", "base_denom", "redelegated_by", "redelegated_to"},
+		),
+
+		validatorMissedBlocksGauge: newChainGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cosmos_validator_missed_blocks",
+				Help: "Missed blocks of the Cosmos-based blockchain validator",
+			},
+			chainID,
+			[]string{"address", "moniker"},
+		),
+
+		validatorRankGauge: newChainGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cosmos_validator_rank",
+				Help: "Rank of the Cosmos-based blockchain validator",
+			},
+			chainID,
+			[]string{"address", "moniker"},
+		),
+
+		validatorIsActiveGauge: newChainGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cosmos_validator_active",
+				Help: "1 if the Cosmos-based blockchain validator is in active set, 0 if no",
+			},
+			chainID,
+			[]string{"address", "moniker"},
+		),
+
+		validatorStatusGauge: newChainGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cosmos_validator_status",
+				Help: "Status of the Cosmos-based blockchain validator",
+			},
+			chainID,
+			[]string{"address", "moniker"},
+		),
+
+		validatorJailedGauge: newChainGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cosmos_validator_jailed",
+				Help: "1 if the Cosmos-based blockchain validator is jailed, 0 if no",
+			},
+			chainID,
+			[]string{"address", "moniker"},
+		),
+	}
+}
+
+func (m *validatorMetrics) set(validator APIValidatorDetails) {
+	if validator.Tokens == nil {
+		// the validator query failed
+		return
+	}
+
+	labels := prometheus.Labels{
+		"address": validator.Address,
+		"moniker": validator.Moniker,
+	}
+
+	m.validatorTokensGauge.Set(validator.Tokens.labels(labels), validator.Tokens.Amount)
+	m.validatorDelegatorSharesGauge.Set(validator.DelegatorShares.labels(labels), validator.DelegatorShares.Amount)
+
+	if validator.CommissionRate != nil {
+		m.validatorCommissionRateGauge.With(labels).Set(*validator.CommissionRate)
+	}
+
+	m.validatorStatusGauge.With(labels).Set(float64(validatorStatuses[validator.Status]))
+	m.validatorJailedGauge.With(labels).Set(boolValue(validator.Jailed))
+
+	for _, delegation := range validator.Delegations {
+		m.validatorDelegationsGauge.Set(delegation.labels(mergeLabels(labels, prometheus.Labels{
+			"delegated_by": delegation.Delegator,
+		})), delegation.Amount)
+	}
+
+	for _, commission := range validator.Commission {
+		m.validatorCommissionGauge.Set(commission.labels(labels), commission.Amount)
+	}
+
+	for _, reward := range validator.Rewards {
+		m.validatorRewardsGauge.Set(reward.labels(labels), reward.Amount)
+	}
+
+	for _, unbonding := range validator.Unbondings {
+		m.validatorUnbondingsGauge.Set(unbonding.labels(mergeLabels(labels, prometheus.Labels{
+			"unbonded_by": unbonding.Delegator,
+		})), unbonding.Amount)
+	}
+
+	for _, redelegation := range validator.Redelegations {
+		m.validatorRedelegationsGauge.Set(redelegation.labels(mergeLabels(labels, prometheus.Labels{
+			"redelegated_by": redelegation.Delegator,
+			"redelegated_to": redelegation.Destination,
+		})), redelegation.Amount)
+	}
+
+	if validator.MissedBlocks != nil {
+		m.validatorMissedBlocksGauge.With(labels).Set(float64(*validator.MissedBlocks))
+	}
+
+	if validator.Rank != 0 {
+		m.validatorRankGauge.With(labels).Set(float64(validator.Rank))
+	}
+
+	if validator.Active != nil {
+		m.validatorIsActiveGauge.With(labels).Set(boolValue(*validator.Active))
+	}
+}
+
+func (m *validatorMetrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		m.validatorDelegationsGauge,
+		m.validatorTokensGauge,
+		m.validatorDelegatorSharesGauge,
+		m.validatorCommissionRateGauge,
+		m.validatorCommissionGauge,
+		m.validatorRewardsGauge,
+		m.validatorUnbondingsGauge,
+		m.validatorRedelegationsGauge,
+		m.validatorMissedBlocksGauge,
+		m.validatorRankGauge,
+		m.validatorIsActiveGauge,
+		m.validatorStatusGauge,
+		m.validatorJailedGauge,
+	}
+}
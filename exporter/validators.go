@@ -0,0 +1,438 @@
+package exporter
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/simapp"
+	querytypes "github.com/cosmos/cosmos-sdk/types/query"
+	slashingtypes "github.com/cosmos/cosmos-sdk/x/slashing/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type ValidatorsCollector struct {
+	chain *Chain
+}
+
+func NewValidatorsCollector(chain *Chain) *ValidatorsCollector {
+	return &ValidatorsCollector{
+		chain: chain,
+	}
+}
+
+func (c *ValidatorsCollector) Name() string {
+	return "validators"
+}
+
+func (c *ValidatorsCollector) Labels() prometheus.Labels {
+	return c.chain.Labels()
+}
+
+var describeValidatorsMetrics = describeOnce(func() []prometheus.Collector {
+	return newValidatorsMetrics("").collectors()
+})
+
+func (c *ValidatorsCollector) Describe(ch chan<- *prometheus.Desc) {
+	describeValidatorsMetrics(ch)
+}
+
+func (c *ValidatorsCollector) Collect(ch chan<- prometheus.Metric) {
+	collectWithTimeout(c, ch)
+}
+
+func (c *ValidatorsCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	info, ok := c.chain.ScrapeInfo(ctx)
+	if !ok {
+		log.Warn().
+			Str("chain", c.chain.Name).
+			Msg("Chain ID and denom are not resolved yet, skipping")
+		return
+	}
+
+	observer := NewScrapeObserver(ctx, info.ChainID, c.Name())
+	defer observer.Finish(ch)
+
+	metrics := newValidatorsMetrics(info.ChainID)
+	metrics.set(c.query(ctx, info, observer))
+	collectAll(ch, metrics.collectors())
+}
+
+// Query returns the validators CollectContext exports, for the API.
+func (c *ValidatorsCollector) Query(ctx context.Context) interface{} {
+	info, ok := c.chain.ScrapeInfo(ctx)
+	if !ok {
+		return nil
+	}
+
+	observer := NewScrapeObserver(ctx, info.ChainID, c.Name())
+	defer observer.Done()
+
+	return c.query(ctx, info, observer)
+}
+
+// query queries the validators, sorted by rank.
+func (c *ValidatorsCollector) query(ctx context.Context, info ChainInfo, observer *ScrapeObserver) []APIValidator {
+	encCfg := simapp.MakeTestEncodingConfig()
+	interfaceRegistry := encCfg.InterfaceRegistry
+
+	sublogger := log.With().
+		Str("request-id", uuid.New().String()).
+		Logger()
+
+	var validators []stakingtypes.Validator
+	var signingInfos []slashingtypes.ValidatorSigningInfo
+	var validatorSetLength uint32
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer observer.Recover("Validators")
+		sublogger.Debug().Msg("Started querying validators")
+		queryStart := time.Now()
+
+		stakingClient := stakingtypes.NewQueryClient(c.chain.GRPC)
+		var allValidators []stakingtypes.Validator
+		err := paginate(observer, "Validators", func(pageRequest *querytypes.PageRequest) (*querytypes.PageResponse, error) {
+			validatorsResponse, err := stakingClient.Validators(
+				ctx,
+				&stakingtypes.QueryValidatorsRequest{Pagination: pageRequest},
+			)
+			if err != nil {
+				return nil, err
+			}
+
+			allValidators = append(allValidators, validatorsResponse.Validators...)
+			return validatorsResponse.Pagination, nil
+		})
+		observer.Observe("Validators", queryStart, err)
+		if err != nil {
+			sublogger.Error().Err(err).Msg("Could not get validators")
+			return
+		}
+
+		sublogger.Debug().
+			Float64("request-time", time.Since(queryStart).Seconds()).
+			Msg("Finished querying validators")
+		validators = allValidators
+		// sorting by delegator shares to display rankings
+		sort.Slice(validators, func(i, j int) bool {
+			return validators[i].DelegatorShares.GT(validators[j].DelegatorShares)
+		})
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer observer.Recover("SigningInfos")
+		sublogger.Debug().Msg("Started querying validators signing infos")
+		queryStart := time.Now()
+
+		slashingClient := slashingtypes.NewQueryClient(c.chain.GRPC)
+		var allSigningInfos []slashingtypes.ValidatorSigningInfo
+		err := paginate(observer, "SigningInfos", func(pageRequest *querytypes.PageRequest) (*querytypes.PageResponse, error) {
+			signingInfosResponse, err := slashingClient.SigningInfos(
+				ctx,
+				&slashingtypes.QuerySigningInfosRequest{Pagination: pageRequest},
+			)
+			if err != nil {
+				return nil, err
+			}
+
+			allSigningInfos = append(allSigningInfos, signingInfosResponse.Info...)
+			return signingInfosResponse.Pagination, nil
+		})
+		observer.Observe("SigningInfos", queryStart, err)
+		if err != nil {
+			sublogger.Error().
+				Err(err).
+				Msg("Could not get validators signing infos")
+			return
+		}
+
+		sublogger.Debug().
+			Float64("request-time", time.Since(queryStart).Seconds()).
+			Msg("Finished querying validator signing infos")
+		signingInfos = allSigningInfos
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer observer.Recover("StakingParams")
+		sublogger.Debug().Msg("Started querying staking params")
+		queryStart := time.Now()
+
+		stakingClient := stakingtypes.NewQueryClient(c.chain.GRPC)
+		paramsResponse, err := stakingClient.Params(
+			ctx,
+			&stakingtypes.QueryParamsRequest{},
+		)
+		observer.Observe("StakingParams", queryStart, err)
+		if err != nil {
+			sublogger.Error().
+				Err(err).
+				Msg("Could not get staking params")
+			return
+		}
+
+		sublogger.Debug().
+			Float64("request-time", time.Since(queryStart).Seconds()).
+			Msg("Finished querying staking params")
+		validatorSetLength = paramsResponse.Params.MaxValidators
+	}()
+
+	wg.Wait()
+
+	sublogger.Debug().
+		Int("signingLength", len(signingInfos)).
+		Int("validatorsLength", len(validators)).
+		Msg("Validators info")
+
+	result := []APIValidator{}
+
+	for index, validator := range validators {
+		tokens := newAPIAmount(info.Denom, info.Amount(validator.Tokens))
+		delegatorShares := newAPIAmount(info.Denom, info.DecAmount(validator.DelegatorShares))
+		minSelfDelegation := newAPIAmount(info.Denom, info.Amount(validator.MinSelfDelegation))
+
+		apiValidator := APIValidator{
+			Address:           validator.OperatorAddress,
+			Moniker:           validator.Description.Moniker,
+			Status:            bondStatusName(validator.Status),
+			Jailed:            validator.Jailed,
+			Tokens:            &tokens,
+			DelegatorShares:   &delegatorShares,
+			MinSelfDelegation: &minSelfDelegation,
+		}
+
+		// because cosmos's dec doesn't have .toFloat64() method or whatever and returns everything as int
+		rate, err := strconv.ParseFloat(validator.Commission.CommissionRates.Rate.String(), 64)
+		if err != nil {
+			log.Error().
+				Err(err).
+				Str("address", validator.OperatorAddress).
+				Msg("Could not get commission")
+		} else {
+			apiValidator.CommissionRate = &rate
+		}
+
+		err = validator.UnpackInterfaces(interfaceRegistry) // Unpack interfaces, to populate the Anys' cached values
+		if err != nil {
+			sublogger.Error().
+				Str("address", validator.OperatorAddress).
+				Err(err).
+				Msg("Could not get unpack validator inferfaces")
+		}
+
+		pubKey, err := validator.GetConsAddr()
+		if err != nil {
+			sublogger.Error().
+				Str("address", validator.OperatorAddress).
+				Err(err).
+				Msg("Could not get validator pubkey")
+		}
+
+		consAddress, err := c.chain.ConsAddress(pubKey)
+		if err != nil {
+			sublogger.Error().
+				Str("address", validator.OperatorAddress).
+				Err(err).
+				Msg("Could not encode validator consensus address")
+		}
+
+		var signingInfo slashingtypes.ValidatorSigningInfo
+		found := false
+
+		for _, signingInfoIterated := range signingInfos {
+			if consAddress == signingInfoIterated.Address {
+				found = true
+				signingInfo = signingInfoIterated
+				break
+			}
+		}
+
+		if !found {
+			sublogger.Debug().
+				Str("address", validator.OperatorAddress).
+				Msg("Could not get signing info for validator")
+			result = append(result, apiValidator)
+			continue
+		}
+
+		if validator.Status == stakingtypes.Bonded {
+			missedBlocks := signingInfo.MissedBlocksCounter
+			apiValidator.MissedBlocks = &missedBlocks
+		} else {
+			sublogger.Trace().
+				Str("address", validator.OperatorAddress).
+				Msg("Validator is not active, not returning missed blocks amount.")
+		}
+
+		apiValidator.Rank = int64(index + 1)
+
+		if validatorSetLength != 0 {
+			active := index+1 <= int(validatorSetLength)
+			apiValidator.Active = &active
+		}
+
+		result = append(result, apiValidator)
+	}
+
+	return result
+}
+
+type validatorsMetrics struct {
+	validatorsCommissionGauge        *prometheus.GaugeVec
+	validatorsStatusGauge            *prometheus.GaugeVec
+	validatorsJailedGauge            *prometheus.GaugeVec
+	validatorsTokensGauge            *AmountGaugeVec
+	validatorsDelegatorSharesGauge   *AmountGaugeVec
+	validatorsMinSelfDelegationGauge *AmountGaugeVec
+	validatorsMissedBlocksGauge      *prometheus.GaugeVec
+	validatorsRankGauge              *prometheus.GaugeVec
+	validatorsIsActiveGauge          *prometheus.GaugeVec
+}
+
+func newValidatorsMetrics(chainID string) *validatorsMetrics {
+	return &validatorsMetrics{
+		validatorsCommissionGauge: newChainGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cosmos_validators_commission",
+				Help: "Commission of the Cosmos-based blockchain validator",
+			},
+			chainID,
+			[]string{"address", "moniker"},
+		),
+
+		validatorsStatusGauge: newChainGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cosmos_validators_status",
+				Help: "Status of the Cosmos-based blockchain validator",
+			},
+			chainID,
+			[]string{"address", "moniker"},
+		),
+
+		validatorsJailedGauge: newChainGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cosmos_validators_jailed",
+				Help: "Jailed status of the Cosmos-based blockchain validator",
+			},
+			chainID,
+			[]string{"address", "moniker"},
+		),
+
+		validatorsTokensGauge: newChainAmountGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cosmos_validators_tokens",
+				Help: "Tokens of the Cosmos-based blockchain validator",
+			},
+			chainID,
+			[]string{"address", "moniker", "denom", "base_denom"},
+		),
+
+		validatorsDelegatorSharesGauge: newChainAmountGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cosmos_validators_delegator_shares",
+				Help: "Delegator shares of the Cosmos-based blockchain validator",
+			},
+			chainID,
+			[]string{"address", "moniker", "denom", "base_denom"},
+		),
+
+		validatorsMinSelfDelegationGauge: newChainAmountGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cosmos_validators_min_self_delegation",
+				Help: "Self declared minimum self delegation shares of the Cosmos-based blockchain validator",
+			},
+			chainID,
+			[]string{"address", "moniker", "denom", "base_denom"},
+		),
+
+		validatorsMissedBlocksGauge: newChainGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cosmos_validators_missed_blocks",
+				Help: "Missed blocks of the Cosmos-based blockchain validator",
+			},
+			chainID,
+			[]string{"address", "moniker"},
+		),
+
+		validatorsRankGauge: newChainGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cosmos_validators_rank",
+				Help: "Rank of the Cosmos-based blockchain validator",
+			},
+			chainID,
+			[]string{"address", "moniker"},
+		),
+
+		validatorsIsActiveGauge: newChainGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cosmos_validators_active",
+				Help: "1 if the Cosmos-based blockchain validator is in active set, 0 if no",
+			},
+			chainID,
+			[]string{"address", "moniker"},
+		),
+	}
+}
+
+func (m *validatorsMetrics) set(validators []APIValidator) {
+	for _, validator := range validators {
+		labels := prometheus.Labels{
+			"address": validator.Address,
+			"moniker": validator.Moniker,
+		}
+
+		if validator.CommissionRate != nil {
+			m.validatorsCommissionGauge.With(labels).Set(*validator.CommissionRate)
+		}
+
+		m.validatorsStatusGauge.With(labels).Set(float64(validatorStatuses[validator.Status]))
+		m.validatorsJailedGauge.With(labels).Set(boolValue(validator.Jailed))
+
+		for gauge, amount := range map[*AmountGaugeVec]*APIAmount{
+			m.validatorsTokensGauge:            validator.Tokens,
+			m.validatorsDelegatorSharesGauge:   validator.DelegatorShares,
+			m.validatorsMinSelfDelegationGauge: validator.MinSelfDelegation,
+		} {
+			if amount != nil {
+				gauge.Set(amount.labels(labels), amount.Amount)
+			}
+		}
+
+		if validator.MissedBlocks != nil {
+			m.validatorsMissedBlocksGauge.With(labels).Set(float64(*validator.MissedBlocks))
+		}
+
+		if validator.Rank != 0 {
+			m.validatorsRankGauge.With(labels).Set(float64(validator.Rank))
+		}
+
+		if validator.Active != nil {
+			m.validatorsIsActiveGauge.With(labels).Set(boolValue(*validator.Active))
+		}
+	}
+}
+
+func (m *validatorsMetrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		m.validatorsCommissionGauge,
+		m.validatorsStatusGauge,
+		m.validatorsJailedGauge,
+		m.validatorsTokensGauge,
+		m.validatorsDelegatorSharesGauge,
+		m.validatorsMinSelfDelegationGauge,
+		m.validatorsMissedBlocksGauge,
+		m.validatorsRankGauge,
+		m.validatorsIsActiveGauge,
+	}
+}
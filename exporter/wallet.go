@@ -0,0 +1,453 @@
+package exporter
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	querytypes "github.com/cosmos/cosmos-sdk/types/query"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distributiontypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+)
+
+type WalletCollector struct {
+	chain    *Chain
+	grpcConn grpc.ClientConnInterface
+	address  string
+}
+
+// NewWalletCollector queries the wallet over grpcConn, which is either the
+// gRPC pool of the chain or the connection of an optional network.
+func NewWalletCollector(chain *Chain, grpcConn grpc.ClientConnInterface, address string) (*WalletCollector, error) {
+	if address == "" {
+		return nil, BadRequest("address parameter is required")
+	}
+
+	if _, err := chain.ParseAccAddress(address); err != nil {
+		return nil, BadRequest("invalid wallet address %q: %s", address, err)
+	}
+
+	return &WalletCollector{
+		chain:    chain,
+		grpcConn: grpcConn,
+		address:  address,
+	}, nil
+}
+
+func (c *WalletCollector) Name() string {
+	return "wallet"
+}
+
+func (c *WalletCollector) Labels() prometheus.Labels {
+	return c.chain.Labels()
+}
+
+var describeWalletMetrics = describeOnce(func() []prometheus.Collector {
+	return newWalletMetrics("").collectors()
+})
+
+func (c *WalletCollector) Describe(ch chan<- *prometheus.Desc) {
+	describeWalletMetrics(ch)
+}
+
+func (c *WalletCollector) Collect(ch chan<- prometheus.Metric) {
+	collectWithTimeout(c, ch)
+}
+
+func (c *WalletCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	info, ok := c.chain.ScrapeInfo(ctx)
+	if !ok {
+		log.Warn().
+			Str("chain", c.chain.Name).
+			Msg("Chain ID and denom are not resolved yet, skipping")
+		return
+	}
+
+	observer := NewScrapeObserver(ctx, info.ChainID, c.Name())
+	defer observer.Finish(ch)
+
+	metrics := newWalletMetrics(info.ChainID)
+	metrics.set(c.query(ctx, info, observer))
+	collectAll(ch, metrics.collectors())
+}
+
+// Query returns the wallet CollectContext exports, for the API.
+func (c *WalletCollector) Query(ctx context.Context) interface{} {
+	info, ok := c.chain.ScrapeInfo(ctx)
+	if !ok {
+		return nil
+	}
+
+	observer := NewScrapeObserver(ctx, info.ChainID, c.Name())
+	defer observer.Done()
+
+	return c.query(ctx, info, observer)
+}
+
+// query queries the wallet, leaving the amounts whose query failed empty.
+func (c *WalletCollector) query(ctx context.Context, info ChainInfo, observer *ScrapeObserver) APIWallet {
+	sublogger := log.With().
+		Str("request-id", uuid.New().String()).
+		Logger()
+
+	address := c.address
+
+	result := APIWallet{
+		Address:       address,
+		Balances:      []APIAmount{},
+		Delegations:   []APIDelegation{},
+		Unbondings:    []APIDelegation{},
+		Redelegations: []APIRedelegation{},
+		Rewards:       []APIDelegation{},
+	}
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer observer.Recover("AllBalances")
+		sublogger.Debug().
+			Str("address", address).
+			Msg("Started querying balance")
+		queryStart := time.Now()
+
+		bankClient := banktypes.NewQueryClient(c.grpcConn)
+		var balances sdk.Coins
+		err := paginate(observer, "AllBalances", func(pageRequest *querytypes.PageRequest) (*querytypes.PageResponse, error) {
+			bankRes, err := bankClient.AllBalances(
+				ctx,
+				&banktypes.QueryAllBalancesRequest{Address: address, Pagination: pageRequest},
+			)
+			if err != nil {
+				return nil, err
+			}
+
+			balances = append(balances, bankRes.Balances...)
+			return bankRes.Pagination, nil
+		})
+		observer.Observe("AllBalances", queryStart, err)
+		if err != nil {
+			sublogger.Error().
+				Str("address", address).
+				Err(err).
+				Msg("Could not get balance")
+			observer.Fail(err)
+			return
+		}
+
+		sublogger.Debug().
+			Str("address", address).
+			Float64("request-time", time.Since(queryStart).Seconds()).
+			Msg("Finished querying balance")
+
+		for _, balance := range balances {
+			result.Balances = append(result.Balances, newAPIAmount(info.Coin(balance)))
+		}
+
+		sortAmounts(result.Balances)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer observer.Recover("DelegatorDelegations")
+		sublogger.Debug().
+			Str("address", address).
+			Msg("Started querying delegations")
+		queryStart := time.Now()
+
+		stakingClient := stakingtypes.NewQueryClient(c.grpcConn)
+		var delegations []stakingtypes.DelegationResponse
+		err := paginate(observer, "DelegatorDelegations", func(pageRequest *querytypes.PageRequest) (*querytypes.PageResponse, error) {
+			stakingRes, err := stakingClient.DelegatorDelegations(
+				ctx,
+				&stakingtypes.QueryDelegatorDelegationsRequest{DelegatorAddr: address, Pagination: pageRequest},
+			)
+			if err != nil {
+				return nil, err
+			}
+
+			delegations = append(delegations, stakingRes.DelegationResponses...)
+			return stakingRes.Pagination, nil
+		})
+		observer.Observe("DelegatorDelegations", queryStart, err)
+		if err != nil {
+			sublogger.Error().
+				Str("address", address).
+				Err(err).
+				Msg("Could not get delegations")
+			return
+		}
+
+		sublogger.Debug().
+			Str("address", address).
+			Float64("request-time", time.Since(queryStart).Seconds()).
+			Msg("Finished querying delegations")
+
+		for _, delegation := range delegations {
+			result.Delegations = append(result.Delegations, APIDelegation{
+				Validator: delegation.Delegation.ValidatorAddress,
+				APIAmount: newAPIAmount(info.Coin(delegation.Balance)),
+			})
+		}
+
+		sortDelegations(result.Delegations)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer observer.Recover("DelegatorUnbondingDelegations")
+		sublogger.Debug().
+			Str("address", address).
+			Msg("Started querying unbonding delegations")
+		queryStart := time.Now()
+
+		stakingClient := stakingtypes.NewQueryClient(c.grpcConn)
+		var unbondings []stakingtypes.UnbondingDelegation
+		err := paginate(observer, "DelegatorUnbondingDelegations", func(pageRequest *querytypes.PageRequest) (*querytypes.PageResponse, error) {
+			stakingRes, err := stakingClient.DelegatorUnbondingDelegations(
+				ctx,
+				&stakingtypes.QueryDelegatorUnbondingDelegationsRequest{DelegatorAddr: address, Pagination: pageRequest},
+			)
+			if err != nil {
+				return nil, err
+			}
+
+			unbondings = append(unbondings, stakingRes.UnbondingResponses...)
+			return stakingRes.Pagination, nil
+		})
+		observer.Observe("DelegatorUnbondingDelegations", queryStart, err)
+		if err != nil {
+			sublogger.Error().
+				Str("address", address).
+				Err(err).
+				Msg("Could not get unbonding delegations")
+			return
+		}
+
+		sublogger.Debug().
+			Str("address", address).
+			Float64("request-time", time.Since(queryStart).Seconds()).
+			Msg("Finished querying unbonding delegations")
+
+		for _, unbonding := range unbondings {
+			sum := sdk.ZeroInt()
+			for _, entry := range unbonding.Entries {
+				sum = sum.Add(entry.Balance)
+			}
+
+			result.Unbondings = append(result.Unbondings, APIDelegation{
+				Validator: unbonding.ValidatorAddress,
+				// unbonding does not have denom in response for some reason
+				APIAmount: newAPIAmount(info.Denom, info.Amount(sum)),
+			})
+		}
+
+		sortDelegations(result.Unbondings)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer observer.Recover("Redelegations")
+		sublogger.Debug().
+			Str("address", address).
+			Msg("Started querying redelegations")
+		queryStart := time.Now()
+
+		stakingClient := stakingtypes.NewQueryClient(c.grpcConn)
+		var redelegations []stakingtypes.RedelegationResponse
+		err := paginate(observer, "Redelegations", func(pageRequest *querytypes.PageRequest) (*querytypes.PageResponse, error) {
+			stakingRes, err := stakingClient.Redelegations(
+				ctx,
+				&stakingtypes.QueryRedelegationsRequest{DelegatorAddr: address, Pagination: pageRequest},
+			)
+			if err != nil {
+				return nil, err
+			}
+
+			redelegations = append(redelegations, stakingRes.RedelegationResponses...)
+			return stakingRes.Pagination, nil
+		})
+		observer.Observe("Redelegations", queryStart, err)
+		if err != nil {
+			sublogger.Error().
+				Str("address", address).
+				Err(err).
+				Msg("Could not get redelegations")
+			return
+		}
+
+		sublogger.Debug().
+			Str("address", address).
+			Float64("request-time", time.Since(queryStart).Seconds()).
+			Msg("Finished querying redelegations")
+
+		for _, redelegation := range redelegations {
+			sum := sdk.ZeroInt()
+			for _, entry := range redelegation.Entries {
+				sum = sum.Add(entry.Balance)
+			}
+
+			result.Redelegations = append(result.Redelegations, APIRedelegation{
+				Source:      redelegation.Redelegation.ValidatorSrcAddress,
+				Destination: redelegation.Redelegation.ValidatorDstAddress,
+				// redelegation does not have denom in response for some reason
+				APIAmount: newAPIAmount(info.Denom, info.Amount(sum)),
+			})
+		}
+
+		sortRedelegations(result.Redelegations)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer observer.Recover("DelegationTotalRewards")
+
+		sublogger.Debug().
+			Str("address", address).
+			Msg("Started querying rewards")
+		queryStart := time.Now()
+
+		distributionClient := distributiontypes.NewQueryClient(c.grpcConn)
+		distributionRes, err := distributionClient.DelegationTotalRewards(
+			ctx,
+			&distributiontypes.QueryDelegationTotalRewardsRequest{DelegatorAddress: address},
+		)
+		observer.Observe("DelegationTotalRewards", queryStart, err)
+		if err != nil {
+			sublogger.Error().
+				Str("address", address).
+				Err(err).
+				Msg("Could not get rewards")
+			return
+		}
+		sublogger.Debug().
+			Str("address", address).
+			Float64("request-time", time.Since(queryStart).Seconds()).
+			Msg("Finished querying rewards")
+
+		for _, reward := range distributionRes.Rewards {
+			for _, entry := range reward.Reward {
+				result.Rewards = append(result.Rewards, APIDelegation{
+					Validator: reward.ValidatorAddress,
+					APIAmount: newAPIAmount(info.DecCoin(entry)),
+				})
+			}
+		}
+
+		sortDelegations(result.Rewards)
+	}()
+
+	wg.Wait()
+
+	return result
+}
+
+type walletMetrics struct {
+	walletBalanceGauge      *AmountGaugeVec
+	walletDelegationGauge   *AmountGaugeVec
+	walletRedelegationGauge *AmountGaugeVec
+	walletUnbondingsGauge   *AmountGaugeVec
+	walletRewardsGauge      *AmountGaugeVec
+}
+
+func newWalletMetrics(chainID string) *walletMetrics {
+	return &walletMetrics{
+		walletBalanceGauge: newChainAmountGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cosmos_wallet_balance",
+				Help: "Balance of the Cosmos-based blockchain wallet",
+			},
+			chainID,
+			[]string{"address", "denom", "base_denom"},
+		),
+
+		walletDelegationGauge: newChainAmountGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cosmos_wallet_delegations",
+				Help: "Delegations of the Cosmos-based blockchain wallet",
+			},
+			chainID,
+			[]string{"address", "denom", "base_denom", "delegated_to"},
+		),
+
+		walletRedelegationGauge: newChainAmountGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cosmos_wallet_redelegations",
+				Help: "Redlegations of the Cosmos-based blockchain wallet",
+			},
+			chainID,
+			[]string{"address", "denom", "base_denom", "redelegated_from", "redelegated_to"},
+		),
+
+		walletUnbondingsGauge: newChainAmountGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cosmos_wallet_unbondings",
+				Help: "Unbondings of the Cosmos-based blockchain wallet",
+			},
+			chainID,
+			[]string{"address", "denom", "base_denom", "unbonded_from"},
+		),
+
+		walletRewardsGauge: newChainAmountGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cosmos_wallet_rewards",
+				Help: "Rewards of the Cosmos-based blockchain wallet",
+			},
+			chainID,
+			[]string{"address", "denom", "base_denom", "validator_address"},
+		),
+	}
+}
+
+func (m *walletMetrics) set(wallet APIWallet) {
+	labels := prometheus.Labels{"address": wallet.Address}
+
+	for _, balance := range wallet.Balances {
+		m.walletBalanceGauge.Set(balance.labels(labels), balance.Amount)
+	}
+
+	for _, delegation := range wallet.Delegations {
+		m.walletDelegationGauge.Set(delegation.labels(mergeLabels(labels, prometheus.Labels{
+			"delegated_to": delegation.Validator,
+		})), delegation.Amount)
+	}
+
+	for _, unbonding := range wallet.Unbondings {
+		m.walletUnbondingsGauge.Set(unbonding.labels(mergeLabels(labels, prometheus.Labels{
+			"unbonded_from": unbonding.Validator,
+		})), unbonding.Amount)
+	}
+
+	for _, redelegation := range wallet.Redelegations {
+		m.walletRedelegationGauge.Set(redelegation.labels(mergeLabels(labels, prometheus.Labels{
+			"redelegated_from": redelegation.Source,
+			"redelegated_to":   redelegation.Destination,
+		})), redelegation.Amount)
+	}
+
+	for _, reward := range wallet.Rewards {
+		m.walletRewardsGauge.Set(reward.labels(mergeLabels(labels, prometheus.Labels{
+			"validator_address": reward.Validator,
+		})), reward.Amount)
+	}
+}
+
+func (m *walletMetrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		m.walletBalanceGauge,
+		m.walletDelegationGauge,
+		m.walletUnbondingsGauge,
+		m.walletRedelegationGauge,
+		m.walletRewardsGauge,
+	}
+}
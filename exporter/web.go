@@ -0,0 +1,408 @@
+package exporter
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v2"
+)
+
+// WebConfig protects the HTTP listener. The format follows the web config of
+// the Prometheus exporter-toolkit, with bearer tokens and per-endpoint access
+// added on top:
+//
+//	tls_server_config:
+//	  cert_file: server.crt
+//	  key_file: server.key
+//	basic_auth_users:
+//	  alice: $2y$10$...
+//	bearer_tokens:
+//	  grafana: some-long-random-token
+//	endpoints:
+//	  /metrics/status:
+//	    public: true
+//	  /metrics/wallet:
+//	    allow: [alice]
+//	  /metrics/gravity-bridge/:
+//	    allow: [alice]
+//
+// Once any users or tokens are configured, every endpoint requires one of
+// them, unless it's public or restricted further by its allow-list. A path
+// ending in a slash applies to every path under it without a rule of its own,
+// and the API and service discovery paths without a rule take the rule of the
+// metrics endpoint they serve the data of, so /api/v1/wallet/{address} and
+// /sd/wallets are restricted like /metrics/wallet.
+type WebConfig struct {
+	TLSServerConfig *TLSServerConfig          `yaml:"tls_server_config"`
+	BasicAuthUsers  map[string]string         `yaml:"basic_auth_users"`
+	BearerTokens    map[string]string         `yaml:"bearer_tokens"`
+	Endpoints       map[string]EndpointAccess `yaml:"endpoints"`
+
+	// successful bcrypt checks are cached, as they are slow on purpose
+	cacheMutex sync.Mutex
+	authCache  map[[sha256.Size]byte]bool
+
+	// unknown users are checked against this, so they take as long as
+	// wrong passwords
+	dummyHash []byte
+}
+
+type TLSServerConfig struct {
+	CertFile       string `yaml:"cert_file"`
+	KeyFile        string `yaml:"key_file"`
+	ClientCAFile   string `yaml:"client_ca_file"`
+	ClientAuthType string `yaml:"client_auth_type"`
+}
+
+type EndpointAccess struct {
+	// Public endpoints don't require any credentials.
+	Public bool `yaml:"public"`
+	// Allow lists the users and token names that can access the endpoint.
+	// An empty list allows all of them.
+	Allow []string `yaml:"allow"`
+}
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"":                           tls.NoClientCert,
+	"NoClientCert":               tls.NoClientCert,
+	"RequestClientCert":          tls.RequestClientCert,
+	"RequireAnyClientCert":       tls.RequireAnyClientCert,
+	"VerifyClientCertIfGiven":    tls.VerifyClientCertIfGiven,
+	"RequireAndVerifyClientCert": tls.RequireAndVerifyClientCert,
+}
+
+func LoadWebConfig(path string) (*WebConfig, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &WebConfig{}
+	if err := yaml.UnmarshalStrict(content, config); err != nil {
+		return nil, err
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
+	config.authCache = make(map[[sha256.Size]byte]bool)
+	config.dummyHash, err = bcrypt.GenerateFromPassword([]byte("dummy"), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+func (c *WebConfig) validate() error {
+	if c.TLSServerConfig != nil {
+		if c.TLSServerConfig.CertFile == "" || c.TLSServerConfig.KeyFile == "" {
+			return fmt.Errorf("tls_server_config requires both cert_file and key_file")
+		}
+
+		if _, ok := clientAuthTypes[c.TLSServerConfig.ClientAuthType]; !ok {
+			return fmt.Errorf("invalid client_auth_type %s", c.TLSServerConfig.ClientAuthType)
+		}
+	}
+
+	for user, hash := range c.BasicAuthUsers {
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return fmt.Errorf("invalid bcrypt hash for user %s: %w", user, err)
+		}
+
+		if _, ok := c.BearerTokens[user]; ok {
+			return fmt.Errorf("%s is both a user and a token name", user)
+		}
+	}
+
+	for name, token := range c.BearerTokens {
+		if token == "" {
+			return fmt.Errorf("empty bearer token %s", name)
+		}
+	}
+
+	for path, access := range c.Endpoints {
+		if access.Public && len(access.Allow) > 0 {
+			return fmt.Errorf("endpoint %s can't be both public and have an allow-list", path)
+		}
+
+		for _, name := range access.Allow {
+			_, isUser := c.BasicAuthUsers[name]
+			_, isToken := c.BearerTokens[name]
+			if !isUser && !isToken {
+				return fmt.Errorf("endpoint %s allows unknown user or token %s", path, name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// TLSConfig returns the TLS config of the listener, or nil to serve plain HTTP.
+func (c *WebConfig) TLSConfig() (*tls.Config, error) {
+	if c.TLSServerConfig == nil {
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.TLSServerConfig.CertFile, c.TLSServerConfig.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not load server certificate: %w", err)
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   clientAuthTypes[c.TLSServerConfig.ClientAuthType],
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if c.TLSServerConfig.ClientCAFile != "" {
+		ca, err := ioutil.ReadFile(c.TLSServerConfig.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read client CA bundle: %w", err)
+		}
+
+		config.ClientCAs = x509.NewCertPool()
+		if !config.ClientCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in client CA bundle %s", c.TLSServerConfig.ClientCAFile)
+		}
+	}
+
+	return config, nil
+}
+
+// Handler wraps next with the authentication of the web config.
+func (c *WebConfig) Handler(next http.Handler) http.Handler {
+	if len(c.BasicAuthUsers) == 0 && len(c.BearerTokens) == 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		access := c.access(r.URL.Path)
+		if access.Public {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		name, ok := c.authenticate(r)
+		if ok && access.allows(name) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !ok {
+			log.Info().
+				Str("endpoint", r.URL.Path).
+				Str("remote", r.RemoteAddr).
+				Msg("Rejected unauthenticated request")
+
+			w.Header().Set("WWW-Authenticate", `Basic realm="cosmos-exporter"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		log.Info().
+			Str("endpoint", r.URL.Path).
+			Str("user", name).
+			Msg("Rejected request not in the endpoint allow-list")
+
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	})
+}
+
+// access returns the rule of path: its own, the one of the longest path
+// ending in a slash it's under, or the rule of the metrics endpoint it
+// mirrors.
+func (c *WebConfig) access(path string) EndpointAccess {
+	if access, ok := c.pathAccess(path); ok {
+		return access
+	}
+
+	if name, _ := apiEndpoint(path); name != "" {
+		path = "/metrics/" + name
+	} else if endpoint, ok := sdEndpoints[path]; ok {
+		path = endpoint
+	} else {
+		return EndpointAccess{}
+	}
+
+	access, _ := c.pathAccess(path)
+	return access
+}
+
+func (c *WebConfig) pathAccess(path string) (EndpointAccess, bool) {
+	if access, ok := c.Endpoints[path]; ok {
+		return access, true
+	}
+
+	var prefix string
+	for key := range c.Endpoints {
+		if strings.HasSuffix(key, "/") && strings.HasPrefix(path, key) && len(key) > len(prefix) {
+			prefix = key
+		}
+	}
+
+	if prefix == "" {
+		return EndpointAccess{}, false
+	}
+
+	return c.Endpoints[prefix], true
+}
+
+func (a EndpointAccess) allows(name string) bool {
+	if len(a.Allow) == 0 {
+		return true
+	}
+
+	for _, allowed := range a.Allow {
+		if allowed == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// authenticate returns the name of the user or token the request is
+// authenticated with.
+func (c *WebConfig) authenticate(r *http.Request) (string, bool) {
+	if user, password, ok := r.BasicAuth(); ok {
+		return user, c.checkPassword(user, password)
+	}
+
+	authorization := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authorization, "Bearer ") {
+		return "", false
+	}
+
+	token := []byte(strings.TrimPrefix(authorization, "Bearer "))
+	for name, expected := range c.BearerTokens {
+		if subtle.ConstantTimeCompare(token, []byte(expected)) == 1 {
+			return name, true
+		}
+	}
+
+	return "", false
+}
+
+func (c *WebConfig) checkPassword(user, password string) bool {
+	hash, ok := c.BasicAuthUsers[user]
+	if !ok {
+		hash = string(c.dummyHash)
+	}
+
+	key := sha256.Sum256([]byte(user + ":" + password + ":" + hash))
+
+	c.cacheMutex.Lock()
+	cached := c.authCache[key]
+	c.cacheMutex.Unlock()
+
+	if cached {
+		return ok
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return false
+	}
+
+	c.cacheMutex.Lock()
+	c.authCache[key] = true
+	c.cacheMutex.Unlock()
+
+	return ok
+}
+
+// Protects returns whether requests to path need credentials.
+func (c *WebConfig) Protects(path string) bool {
+	if c == nil || (len(c.BasicAuthUsers) == 0 && len(c.BearerTokens) == 0) {
+		return false
+	}
+
+	return !c.access(path).Public
+}
+
+// WebHandler applies the authentication of the current web config to every
+// request, so a reload can swap the config.
+type WebHandler struct {
+	next   http.Handler
+	config atomic.Value
+}
+
+// NewWebHandler wraps next with config, which is nil without a web config.
+func NewWebHandler(next http.Handler, config *WebConfig) *WebHandler {
+	handler := &WebHandler{next: next}
+	handler.config.Store(config)
+	return handler
+}
+
+func (h *WebHandler) Config() *WebConfig {
+	return h.config.Load().(*WebConfig)
+}
+
+// Swap applies config to the requests from now on.
+func (h *WebHandler) Swap(config *WebConfig) {
+	h.config.Store(config)
+}
+
+func (h *WebHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	config := h.Config()
+	if config == nil {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+
+	config.Handler(h.next).ServeHTTP(w, r)
+}
+
+// NewServer builds the listener of the exporter with the --http-* timeouts,
+// and the TLS of the web config, if any.
+func NewServer(address string, handler *WebHandler) (*http.Server, error) {
+	server := &http.Server{
+		Addr:              address,
+		Handler:           handler,
+		ReadHeaderTimeout: HTTPReadTimeout,
+		ReadTimeout:       HTTPReadTimeout,
+		WriteTimeout:      HTTPWriteTimeout,
+		IdleTimeout:       HTTPIdleTimeout,
+	}
+
+	webConfig := handler.Config()
+	if webConfig == nil {
+		return server, nil
+	}
+
+	tlsConfig, err := webConfig.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	server.TLSConfig = tlsConfig
+	return server, nil
+}
+
+// Serve listens until the server is shut down, over TLS if it has a TLS
+// config.
+func Serve(server *http.Server) error {
+	var err error
+	if server.TLSConfig != nil {
+		err = server.ListenAndServeTLS("", "")
+	} else {
+		err = server.ListenAndServe()
+	}
+
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,200 @@
+package exporter
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func writeWebConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "web-config.yml")
+	if err := ioutil.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func testWebConfig(t *testing.T) *WebConfig {
+	t.Helper()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadWebConfig(writeWebConfig(t, fmt.Sprintf(`
+basic_auth_users:
+  alice: %s
+  bob: %s
+bearer_tokens:
+  grafana: some-long-random-token
+endpoints:
+  /metrics/status:
+    public: true
+  /metrics/wallet:
+    allow: [alice, grafana]
+  /metrics/gravity-bridge/:
+    allow: [alice]
+  /metrics/gravity-bridge/contract:
+    public: true
+`, hash, hash)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return config
+}
+
+func TestWebConfigHandler(t *testing.T) {
+	handler := testWebConfig(t).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name   string
+		path   string
+		user   string
+		pass   string
+		bearer string
+		want   int
+	}{
+		{name: "no credentials", path: "/metrics", want: http.StatusUnauthorized},
+		{name: "basic auth", path: "/metrics", user: "alice", pass: "secret", want: http.StatusOK},
+		{name: "wrong password", path: "/metrics", user: "alice", pass: "wrong", want: http.StatusUnauthorized},
+		{name: "unknown user", path: "/metrics", user: "mallory", pass: "secret", want: http.StatusUnauthorized},
+		{name: "bearer token", path: "/metrics", bearer: "some-long-random-token", want: http.StatusOK},
+		{name: "wrong bearer token", path: "/metrics", bearer: "some-long-random-tokem", want: http.StatusUnauthorized},
+		{name: "public endpoint", path: "/metrics/status", want: http.StatusOK},
+		{name: "allowed user", path: "/metrics/wallet", user: "alice", pass: "secret", want: http.StatusOK},
+		{name: "allowed token", path: "/metrics/wallet", bearer: "some-long-random-token", want: http.StatusOK},
+		{name: "user not allowed", path: "/metrics/wallet", user: "bob", pass: "secret", want: http.StatusForbidden},
+		{name: "not allowed without credentials", path: "/metrics/wallet", want: http.StatusUnauthorized},
+		{name: "allowed under a prefix", path: "/metrics/gravity-bridge/wallet", user: "alice", pass: "secret", want: http.StatusOK},
+		{name: "not allowed under a prefix", path: "/metrics/gravity-bridge/wallet", user: "bob", pass: "secret", want: http.StatusForbidden},
+		{name: "own rule under a prefix", path: "/metrics/gravity-bridge/contract", want: http.StatusOK},
+		{name: "allowed API path", path: "/api/v1/wallet/cosmos1abc", user: "alice", pass: "secret", want: http.StatusOK},
+		{name: "API path of a restricted endpoint", path: "/api/v1/wallet/cosmos1abc", user: "bob", pass: "secret", want: http.StatusForbidden},
+		{name: "API path of another endpoint", path: "/api/v1/validators", user: "bob", pass: "secret", want: http.StatusOK},
+		{name: "service discovery of a restricted endpoint", path: "/sd/wallets", user: "bob", pass: "secret", want: http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// twice, the second time from the cache of bcrypt checks
+			for i := 0; i < 2; i++ {
+				r := httptest.NewRequest(http.MethodGet, test.path, nil)
+				if test.user != "" {
+					r.SetBasicAuth(test.user, test.pass)
+				}
+				if test.bearer != "" {
+					r.Header.Set("Authorization", "Bearer "+test.bearer)
+				}
+
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, r)
+
+				if w.Code != test.want {
+					t.Fatalf("status = %d, want %d", w.Code, test.want)
+				}
+
+				if test.want == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+					t.Error("401 without a WWW-Authenticate header")
+				}
+			}
+		})
+	}
+}
+
+func TestEndpointAccessAllows(t *testing.T) {
+	tests := []struct {
+		access EndpointAccess
+		name   string
+		want   bool
+	}{
+		{EndpointAccess{}, "alice", true},
+		{EndpointAccess{Allow: []string{"alice"}}, "alice", true},
+		{EndpointAccess{Allow: []string{"alice", "grafana"}}, "grafana", true},
+		{EndpointAccess{Allow: []string{"alice"}}, "bob", false},
+		{EndpointAccess{Allow: []string{"alice"}}, "", false},
+	}
+
+	for _, test := range tests {
+		if got := test.access.allows(test.name); got != test.want {
+			t.Errorf("%+v allows(%q) = %t, want %t", test.access, test.name, got, test.want)
+		}
+	}
+}
+
+func TestWebConfigProtects(t *testing.T) {
+	config := testWebConfig(t)
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/metrics", true},
+		{"/metrics/wallet", true},
+		{"/metrics/status", false},
+		{"/-/reload", true},
+		{"/metrics/gravity-bridge/contract", false},
+		{"/metrics/gravity-bridge/wallet", true},
+		{"/api/v1/wallet/cosmos1abc", true},
+	}
+
+	for _, test := range tests {
+		if got := config.Protects(test.path); got != test.want {
+			t.Errorf("Protects(%q) = %t, want %t", test.path, got, test.want)
+		}
+	}
+
+	var none *WebConfig
+	if none.Protects("/-/reload") {
+		t.Error("Protects() without a web config = true, want false")
+	}
+}
+
+func TestWebConfigWithoutCredentials(t *testing.T) {
+	config, err := LoadWebConfig(writeWebConfig(t, "endpoints: {}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	config.Handler(http.NotFoundHandler()).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want the handler to be served without credentials", w.Code)
+	}
+}
+
+func TestLoadWebConfigInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"unknown key", "basic_auth_user: {}\n"},
+		{"invalid hash", "basic_auth_users:\n  alice: not-a-hash\n"},
+		{"empty token", "bearer_tokens:\n  grafana: \"\"\n"},
+		{"user and token", "basic_auth_users:\n  grafana: $2a$04$0000000000000000000000000000000000000000000000000000.\nbearer_tokens:\n  grafana: token\n"},
+		{"public with allow-list", "bearer_tokens:\n  grafana: token\nendpoints:\n  /metrics:\n    public: true\n    allow: [grafana]\n"},
+		{"unknown allowed name", "bearer_tokens:\n  grafana: token\nendpoints:\n  /metrics:\n    allow: [alice]\n"},
+		{"cert without key", "tls_server_config:\n  cert_file: server.crt\n"},
+		{"invalid client auth", "tls_server_config:\n  cert_file: server.crt\n  key_file: server.key\n  client_auth_type: Sometimes\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := LoadWebConfig(writeWebConfig(t, test.content)); err == nil {
+				t.Error("LoadWebConfig() didn't fail")
+			}
+		})
+	}
+}
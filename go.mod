@@ -1,4 +1,4 @@
-module main
+module github.com/CudoVentures/cosmos-exporter
 
 go 1.16
 
@@ -8,16 +8,23 @@ replace google.golang.org/grpc => google.golang.org/grpc v1.33.2
 
 require (
 	github.com/cosmos/cosmos-sdk v0.45.1
-	github.com/enigmampc/btcutil v1.0.3-0.20200723161021-e2fb6adb2a25 // indirect
 	github.com/ethereum/go-ethereum v1.10.16
+	github.com/golang/snappy v0.0.4
 	github.com/google/uuid v1.2.0
+	github.com/hashicorp/go-immutable-radix v1.2.0 // indirect
+	github.com/mitchellh/mapstructure v1.4.1
 	github.com/prometheus/client_golang v1.11.0
+	github.com/prometheus/client_model v0.2.0
+	github.com/prometheus/common v0.29.0
 	github.com/rs/zerolog v1.26.1
 	github.com/spf13/cobra v1.2.1
 	github.com/spf13/pflag v1.0.5
 	github.com/spf13/viper v1.8.1
 	github.com/tendermint/tendermint v0.34.14
-	golang.org/x/crypto v0.0.0-20220214200702-86341886e292 // indirect
+	go.opentelemetry.io/proto/otlp v0.19.0
+	golang.org/x/crypto v0.0.0-20220214200702-86341886e292
 	golang.org/x/sys v0.0.0-20220209214540-3681064d5158 // indirect
 	google.golang.org/grpc v1.44.0
+	google.golang.org/protobuf v1.27.1
+	gopkg.in/yaml.v2 v2.4.0
 )
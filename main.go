@@ -3,34 +3,42 @@ package main
 import (
 	"context"
 	"fmt"
-	"math"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
+	"github.com/CudoVentures/cosmos-exporter/exporter"
 	sdk "github.com/cosmos/cosmos-sdk/types"
-	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/zerolog"
 	"github.com/spf13/cobra"
-	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
-	tmrpc "github.com/tendermint/tendermint/rpc/client/http"
 	"google.golang.org/grpc"
 )
 
 var (
 	ConfigPath string
 
-	Denom              string
-	ListenAddress      string
-	NodeAddress        string
-	TendermintRPC      string
-	OsmosisAPI         string
-	EthRPC             string
-	ethTokenContract   string
-	ethGravityContract string
-	OptionalNetworks   map[string]string
-	LogLevel           string
-	Limit              uint64
+	Denom          string
+	ListenAddress  string
+	NodeAddresses  []string
+	TendermintRPCs []string
+	OsmosisAPI     string
+	EthRPC         string
+	MainNode       exporter.NodeSettings
+	PollInterval   time.Duration
+	PollIntervals  map[string]string
+
+	WebConfigFile string
+
+	ShutdownTimeout time.Duration
 
 	Prefix                    string
 	AccountPrefix             string
@@ -40,11 +48,8 @@ var (
 	ConsensusNodePrefix       string
 	ConsensusNodePubkeyPrefix string
 
-	ChainID          string
-	ConstLabels      map[string]string
 	DenomCoefficient float64
-
-	TokenPrices []string
+	DenomOverrides   []string
 )
 
 var log = zerolog.New(zerolog.ConsoleWriter{Out: os.Stdout}).With().Timestamp().Logger()
@@ -52,40 +57,36 @@ var log = zerolog.New(zerolog.ConsoleWriter{Out: os.Stdout}).With().Timestamp().
 var rootCmd = &cobra.Command{
 	Use:  "cosmos-exporter",
 	Long: "Scrape the data about the validators set, specific validators or wallets in the Cosmos network.",
+	// config errors are logged in main, and are listed better without the usage
+	SilenceUsage:  true,
+	SilenceErrors: true,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-		if ConfigPath == "" {
-			log.Info().Msg("Config file not provided")
-			setBechPrefixes(cmd)
-			return nil
-		}
-
-		log.Info().Msg("Config file provided")
-
-		viper.SetConfigFile(ConfigPath)
-		if err := viper.ReadInConfig(); err != nil {
-			log.Info().Err(err).Msg("Error reading config file")
-			if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-				return err
-			}
-		}
-
-		// Credits to https://carolynvanslyck.com/blog/2020/08/sting-of-the-viper/
-		cmd.Flags().VisitAll(func(f *pflag.Flag) {
-			if !f.Changed && viper.IsSet(f.Name) {
-				val := viper.Get(f.Name)
-				if err := cmd.Flags().Set(f.Name, fmt.Sprintf("%v", val)); err != nil {
-					log.Fatal().Err(err).Msg("Could not set flag")
-				}
-			}
-		})
-
-		setBechPrefixes(cmd)
-
-		return nil
+		return loadConfig(cmd)
 	},
 	Run: Execute,
 }
 
+// flagValue formats a config file value the way pflag parses it, so objects
+// and arrays can set map and slice flags like --node-headers.
+func flagValue(val interface{}) string {
+	switch val := val.(type) {
+	case map[string]interface{}:
+		pairs := make([]string, 0, len(val))
+		for key, value := range val {
+			pairs = append(pairs, fmt.Sprintf("%s=%v", key, value))
+		}
+		return strings.Join(pairs, ",")
+	case []interface{}:
+		values := make([]string, 0, len(val))
+		for _, value := range val {
+			values = append(values, fmt.Sprintf("%v", value))
+		}
+		return strings.Join(values, ",")
+	default:
+		return fmt.Sprintf("%v", val)
+	}
+}
+
 func setBechPrefixes(cmd *cobra.Command) {
 	if flag, err := cmd.Flags().GetString("bech-account-prefix"); flag != "" && err == nil {
 		AccountPrefix = flag
@@ -125,13 +126,10 @@ func setBechPrefixes(cmd *cobra.Command) {
 }
 
 func Execute(cmd *cobra.Command, args []string) {
-	logLevel, err := zerolog.ParseLevel(LogLevel)
-	if err != nil {
-		log.Fatal().Err(err).Msg("Could not parse log level")
-	}
+	exporter.SetLiveConfig(&StartupConfig)
 
+	logLevel, _ := zerolog.ParseLevel(StartupConfig.LogLevel)
 	zerolog.SetGlobalLevel(logLevel)
-	fmt.Println(OptionalNetworks["osmosis"])
 	log.Info().
 		Str("--bech-account-prefix", AccountPrefix).
 		Str("--bech-account-pubkey-prefix", AccountPubkeyPrefix).
@@ -140,153 +138,460 @@ func Execute(cmd *cobra.Command, args []string) {
 		Str("--bech-consensus-node-prefix", ConsensusNodePrefix).
 		Str("--bech-consensus-node-pubkey-prefix", ConsensusNodePubkeyPrefix).
 		Str("--denom", Denom).
+		Strs("--denoms", DenomOverrides).
 		Str("--listen-address", ListenAddress).
-		Str("--node", NodeAddress).
+		Str("--web-config-file", WebConfigFile).
+		Strs("--node", NodeAddresses).
+		Strs("--tendermint-rpc", TendermintRPCs).
 		Str("--eth-node", EthRPC).
-		Str("--eth-token-contract", ethTokenContract).
-		Str("--eth-gravity-contract", ethGravityContract).
-		Str("--log-level", LogLevel).
+		Str("--eth-token-contract", StartupConfig.EthTokenContract).
+		Str("--eth-gravity-contract", StartupConfig.EthGravityContract).
+		Str("--log-level", StartupConfig.LogLevel).
+		Dur("--poll-interval", PollInterval).
+		Dur("--scrape-timeout", exporter.ScrapeTimeout).
+		Dur("--http-write-timeout", exporter.HTTPWriteTimeout).
+		Dur("--shutdown-timeout", ShutdownTimeout).
 		Msg("Started with following parameters")
 
+	if exporter.HTTPWriteTimeout > 0 && exporter.HTTPWriteTimeout <= exporter.ScrapeTimeout {
+		log.Warn().
+			Dur("--http-write-timeout", exporter.HTTPWriteTimeout).
+			Dur("--scrape-timeout", exporter.ScrapeTimeout).
+			Msg("HTTP write timeout is not longer than the scrape timeout, slow scrapes will be cut off")
+	}
+
 	config := sdk.GetConfig()
 	config.SetBech32PrefixForAccount(AccountPrefix, AccountPubkeyPrefix)
 	config.SetBech32PrefixForValidator(ValidatorPrefix, ValidatorPubkeyPrefix)
 	config.SetBech32PrefixForConsensusNode(ConsensusNodePrefix, ConsensusNodePubkeyPrefix)
 	// config.Seal()
 
-	grpcConn, err := grpc.Dial(
-		NodeAddress,
-		grpc.WithInsecure(),
-	)
+	defaultChain, err := exporter.NewChain(exporter.DefaultChainName, exporter.ChainConfig{
+		Node:             NodeAddresses,
+		TendermintRPC:    TendermintRPCs,
+		Denom:            Denom,
+		DenomCoefficient: DenomCoefficient,
+		Denoms:           DenomOverrides,
+		NodeSettings:     MainNode,
+	}, exporter.Bech32Prefixes{
+		Account:             AccountPrefix,
+		AccountPubkey:       AccountPubkeyPrefix,
+		Validator:           ValidatorPrefix,
+		ValidatorPubkey:     ValidatorPubkeyPrefix,
+		ConsensusNode:       ConsensusNodePrefix,
+		ConsensusNodePubkey: ConsensusNodePubkeyPrefix,
+	})
 	if err != nil {
-		log.Fatal().Err(err).Msg("Could not connect to gRPC node")
+		log.Fatal().Err(err).Msg("Could not set up chain")
 	}
 
-	setChainID()
-	setDenom(grpcConn)
+	chains := exporter.NewChains(defaultChain)
 
-	http.HandleFunc("/metrics/wallet", func(w http.ResponseWriter, r *http.Request) {
-		WalletHandler(w, r, grpcConn)
-	})
+	configs, err := chainConfigs()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Could not read chains config")
+	}
 
-	http.HandleFunc("/metrics/validator", func(w http.ResponseWriter, r *http.Request) {
-		ValidatorHandler(w, r, grpcConn)
-	})
+	for name, config := range configs {
+		chain, err := exporter.NewChain(name, config, config.Prefixes())
+		if err != nil {
+			log.Fatal().Err(err).Str("chain", name).Msg("Could not set up chain")
+		}
 
-	http.HandleFunc("/metrics/validators", func(w http.ResponseWriter, r *http.Request) {
-		ValidatorsHandler(w, r, grpcConn)
-	})
+		if err := chains.Add(chain); err != nil {
+			log.Fatal().Err(err).Msg("Could not add chain")
+		}
+	}
 
-	http.HandleFunc("/metrics/params", func(w http.ResponseWriter, r *http.Request) {
-		ParamsHandler(w, r, grpcConn)
-	})
+	networkSettings, err := optionalNetworkSettings(viper.GetViper(), StartupConfig.OptionalNetworks)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Could not read optional network settings")
+	}
 
-	http.HandleFunc("/metrics/general", func(w http.ResponseWriter, r *http.Request) {
-		GeneralHandler(w, r, grpcConn)
-	})
+	optionalNetworks, err := exporter.NewOptionalNetworkPool(StartupConfig.OptionalNetworks, networkSettings)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Could not connect to optional networks")
+	}
+
+	prometheus.MustRegister(optionalNetworks)
 
-	http.HandleFunc("/metrics/gravity-bridge/wallet", func(w http.ResponseWriter, r *http.Request) {
-		GravityBridgeWalletHandler(w, r, grpcConn)
+	registerChainEndpoint("general", chains, func(chain *exporter.Chain) (exporter.Collector, error) {
+		return exporter.NewGeneralCollector(chain), nil
 	})
 
-	http.HandleFunc("/metrics/gravity-bridge/contract", func(w http.ResponseWriter, r *http.Request) {
-		GravityBridgeContractHandler(w, r, grpcConn)
+	paramsEndpoint := registerChainEndpoint("params", chains, func(chain *exporter.Chain) (exporter.Collector, error) {
+		return exporter.NewParamsCollector(chain), nil
 	})
 
-	http.HandleFunc("/metrics/status", func(w http.ResponseWriter, r *http.Request) {
-		StatusHandler(w, r, grpcConn)
+	validatorsEndpoint := registerChainEndpoint("validators", chains, func(chain *exporter.Chain) (exporter.Collector, error) {
+		return exporter.NewValidatorsCollector(chain), nil
 	})
 
-	http.HandleFunc("/metrics/osmosis", func(w http.ResponseWriter, r *http.Request) {
-		OsmosisHandler(w, r)
+	registerChainEndpoint("status", chains, func(chain *exporter.Chain) (exporter.Collector, error) {
+		return exporter.NewStatusCollector(chain), nil
 	})
 
-	log.Info().Str("address", ListenAddress).Msg("Listening")
-	err = http.ListenAndServe(ListenAddress, nil)
+	validatorEndpoint := registerEndpoint("validator", func(query url.Values) (exporter.Collector, error) {
+		chain, err := chains.Get(query.Get("chain"))
+		if err != nil {
+			return nil, err
+		}
+
+		return exporter.NewValidatorCollector(chain, query.Get("address"))
+	}, "chain", "address")
+
+	walletEndpoint := registerEndpoint("wallet", func(query url.Values) (exporter.Collector, error) {
+		chain, err := chains.Get(query.Get("chain"))
+		if err != nil {
+			return nil, err
+		}
+
+		var network grpc.ClientConnInterface = chain.GRPC
+
+		if optionalNetwork := query.Get("network"); optionalNetwork != "" {
+			network, err = optionalNetworks.Conn(optionalNetwork)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return exporter.NewWalletCollector(chain, network, query.Get("address"))
+	}, "chain", "network", "address")
+
+	registerEndpoint("osmosis", func(query url.Values) (exporter.Collector, error) {
+		chain, err := chains.Get(query.Get("chain"))
+		if err != nil {
+			return nil, err
+		}
+
+		return exporter.NewOsmosisCollector(chain, query.Get("pool_id"), query.Get("price_denoms"))
+	}, "chain", "pool_id", "price_denoms")
+
+	// the gravity bridge flags belong to the chain of the top-level flags, so
+	// the gravity bridge endpoints only serve that one
+	gravityChain := func(query url.Values) (*exporter.Chain, error) {
+		chain, err := chains.Get(query.Get("chain"))
+		if err != nil {
+			return nil, err
+		}
+
+		if chain != chains.Default {
+			return nil, exporter.BadRequest("gravity bridge is only configured for the default chain")
+		}
+
+		return chain, nil
+	}
+
+	var ethPool *exporter.BackendPool
+
+	ethConn, err := ethclient.Dial(EthRPC)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Could not start application")
+		log.Error().Err(err).Msg("Could not connect to Ethereum node, gravity bridge endpoints are disabled")
+	} else {
+		// --eth-rpc has a default, so the node is only checked if the
+		// gravity bridge is actually configured at startup
+		if StartupConfig.EthTokenContract != "" || StartupConfig.EthGravityContract != "" {
+			ethPool, err = exporter.NewEthPool(exporter.PoolOwner{Chain: chains.Default.Name}, EthRPC, ethConn)
+			if err != nil {
+				log.Fatal().Err(err).Msg("Could not create Ethereum node health check")
+			}
+
+			ethPool.Start(exporter.HealthCheckInterval)
+		}
+
+		registerEndpoint("gravity-bridge/wallet", func(query url.Values) (exporter.Collector, error) {
+			chain, err := gravityChain(query)
+			if err != nil {
+				return nil, err
+			}
+
+			return exporter.NewGravityBridgeWalletCollector(
+				chain,
+				ethConn,
+				query.Get("cudos_orchestrator_address"),
+				query.Get("ethereum_orchestrator_address"),
+			)
+		}, "chain", "cudos_orchestrator_address", "ethereum_orchestrator_address")
+
+		gravityBridgeContractCollector := exporter.NewGravityBridgeContractCollector(chains.Default, ethConn)
+
+		registerEndpoint("gravity-bridge/contract", func(query url.Values) (exporter.Collector, error) {
+			if _, err := gravityChain(query); err != nil {
+				return nil, err
+			}
+
+			if !exporter.CurrentLiveConfig().GravityBridgeConfigured() {
+				return nil, exporter.BadRequest("--eth-token-contract and --eth-gravity-contract are not configured")
+			}
+
+			return gravityBridgeContractCollector, nil
+		}, "chain")
 	}
-}
 
-func setChainID() {
-	client, err := tmrpc.New(TendermintRPC, "/websocket")
+	targets, err := targetsConfig()
 	if err != nil {
-		log.Fatal().Err(err).Msg("Could not create Tendermint client")
+		log.Fatal().Err(err).Msg("Could not read targets config")
 	}
 
-	status, err := client.Status(context.Background())
+	// collectors without request parameters are also served together from
+	// /metrics, for the chain selected the same way, along with the targets
+	// of the chain
+	aggregate, err := exporter.ChainCollector(chains, func(chain *exporter.Chain) (exporter.Collector, error) {
+		collectors := []exporter.Collector{
+			exporter.NewGeneralCollector(chain),
+			exporter.NewParamsCollector(chain),
+			exporter.NewValidatorsCollector(chain),
+			exporter.NewStatusCollector(chain),
+		}
+
+		// it skips collections while the contracts aren't configured
+		if chain == chains.Default && ethConn != nil {
+			collectors = append(collectors, exporter.NewGravityBridgeContractCollector(chain, ethConn))
+		}
+
+		targetCollectors, err := targets.Collectors(chains, chain, ethConn)
+		if err != nil {
+			return nil, err
+		}
+		collectors = append(collectors, targetCollectors...)
+
+		return exporter.NewMultiCollector("all", chain.Labels, collectors...), nil
+	})
 	if err != nil {
-		log.Fatal().Err(err).Msg("Could not query Tendermint status")
+		log.Fatal().Err(err).Msg("Could not create aggregate collector")
 	}
 
-	log.Info().Str("network", status.NodeInfo.Network).Msg("Got network status from Tendermint")
-	ChainID = status.NodeInfo.Network
-	ConstLabels = map[string]string{
-		"chain_id": ChainID,
+	// the exporter's own metrics are always served live, next to the aggregate
+	aggregateEndpoint := exporter.NewEndpoint("all", pollInterval("all"), aggregate, "chain")
+	aggregateEndpoint.Extra = prometheus.DefaultGatherer
+	http.Handle("/metrics", aggregateEndpoint)
+	http.Handle("/metrics/exporter", promhttp.Handler())
+
+	pushCtx, stopPushing := context.WithCancel(context.Background())
+	var pushers sync.WaitGroup
+	var otlpReceiver *exporter.OTLPReceiver
+
+	if exporter.PushSettings.Enabled() {
+		gatherer := exporter.PushGatherer(pushCtx, chains, aggregateEndpoint)
+
+		var receivers []exporter.PushReceiver
+		if exporter.PushSettings.PushgatewayURL != "" {
+			receivers = append(receivers, exporter.NewPushgatewayReceiver(exporter.PushSettings.PushgatewayURL, exporter.PushSettings.PushgatewayJob, exporter.PushSettings.ExternalLabels))
+		}
+		if exporter.PushSettings.RemoteWriteURL != "" {
+			receivers = append(receivers, exporter.NewRemoteWriteReceiver(exporter.PushSettings.RemoteWriteURL, exporter.PushSettings.ExternalLabels))
+		}
+		if exporter.PushSettings.OTLPEndpoint != "" {
+			otlpReceiver, err = exporter.NewOTLPReceiver(exporter.PushSettings.OTLPEndpoint, exporter.PushSettings.OTLPProtocol, exporter.PushSettings.ExternalLabels)
+			if err != nil {
+				log.Fatal().Err(err).Str("receiver", "otlp").Msg("Could not set up push")
+			}
+
+			receivers = append(receivers, otlpReceiver)
+		}
+
+		pusher, err := exporter.NewPusher(receivers, gatherer, exporter.PushSettings)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Could not set up push")
+		}
+
+		pushers.Add(1)
+		go func() {
+			defer pushers.Done()
+			pusher.Run(pushCtx)
+		}()
 	}
-}
 
-func setDenom(grpcConn *grpc.ClientConn) {
-	// if --denom and --denom-coefficient are both provided, use them
-	// instead of fetching them via gRPC. Can be useful for networks like osmosis.
-	if Denom != "" && DenomCoefficient != 0 {
-		log.Info().
-			Str("denom", Denom).
-			Float64("coefficient", DenomCoefficient).
-			Msg("Using provided denom and coefficient.")
-		return
+	health := exporter.NewHealth(chains, optionalNetworks, ethPool)
+	http.HandleFunc("/healthz", health.Live)
+	http.HandleFunc("/readyz", health.Ready)
+
+	api := exporter.NewAPI(map[string]exporter.CollectorFactory{
+		"params":     paramsEndpoint.Factory,
+		"validators": validatorsEndpoint.Factory,
+		"validator":  validatorEndpoint.Factory,
+		"wallet":     walletEndpoint.Factory,
+	})
+	http.Handle("/api/v1/", api)
+
+	serviceDiscovery := exporter.NewServiceDiscovery(chains, targets)
+	http.HandleFunc("/sd/validators", serviceDiscovery.Validators)
+	http.HandleFunc("/sd/wallets", serviceDiscovery.Wallets)
+
+	var webConfig *exporter.WebConfig
+	if WebConfigFile != "" {
+		webConfig, err = exporter.LoadWebConfig(WebConfigFile)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Could not load web config")
+		}
 	}
 
-	bankClient := banktypes.NewQueryClient(grpcConn)
-	denoms, err := bankClient.DenomsMetadata(
-		context.Background(),
-		&banktypes.QueryDenomsMetadataRequest{},
-	)
+	webHandler := exporter.NewWebHandler(http.DefaultServeMux, webConfig)
+
+	reloader := NewReloader(os.Args[1:], cmd.Flags(), optionalNetworks, webHandler)
+	http.Handle("/-/reload", reloader)
+	go reloadOnSignal(reloader)
+
+	server, err := exporter.NewServer(ListenAddress, webHandler)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Error querying denom")
+		log.Fatal().Err(err).Msg("Could not set up listener")
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		shutdownOnSignal(server)
+	}()
+
+	log.Info().Str("address", ListenAddress).Msg("Listening")
+	if err := exporter.Serve(server); err != nil {
+		log.Fatal().Err(err).Msg("Could not start application")
 	}
 
-	if len(denoms.Metadatas) == 0 {
-		log.Fatal().Msg("No denom infos. Try running the binary with --denom and --denom-coefficient to set them manually.")
+	// serve returns as soon as the shutdown starts, so wait for the
+	// in-flight requests before closing the connections they use
+	<-stopped
+
+	// a push that's still failing is buffered before the nodes go away
+	stopPushing()
+	pushers.Wait()
+
+	if otlpReceiver != nil {
+		otlpReceiver.Close()
+	}
+
+	for _, chain := range chains.All() {
+		chain.Close()
+	}
+
+	optionalNetworks.Close()
+
+	if ethPool != nil {
+		ethPool.Close()
+	}
+
+	if ethConn != nil {
+		ethConn.Close()
 	}
 
-	metadata := denoms.Metadatas[0] // always using the first one
-	if Denom == "" {                // using display currency
-		Denom = metadata.Display
+	log.Info().Msg("Stopped")
+}
+
+// shutdownOnSignal waits for SIGTERM or SIGINT, then stops accepting new
+// requests and gives the in-flight ones --shutdown-timeout to finish.
+func shutdownOnSignal(server *http.Server) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+
+	received := <-signals
+	signal.Stop(signals)
+
+	log.Info().
+		Str("signal", received.String()).
+		Dur("grace-period", ShutdownTimeout).
+		Msg("Shutting down")
+
+	ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		log.Warn().Err(err).Msg("In-flight requests did not finish in time, closing them")
+		server.Close()
 	}
+}
+
+// reloadOnSignal reloads the config on every SIGHUP. Errors are logged, and
+// the current config stays in place.
+func reloadOnSignal(reloader *Reloader) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
 
-	for _, unit := range metadata.DenomUnits {
-		log.Debug().
-			Str("denom", unit.Denom).
-			Uint32("exponent", unit.Exponent).
-			Msg("Denom info")
-		if unit.Denom == Denom {
-			DenomCoefficient = math.Pow10(int(unit.Exponent))
-			log.Info().
-				Str("denom", Denom).
-				Float64("coefficient", DenomCoefficient).
-				Msg("Got denom info")
-			return
+	for received := range signals {
+		log.Info().Str("signal", received.String()).Msg("Reloading config")
+		reloader.Reload()
+	}
+}
+
+// historicalEndpoints take a height parameter, as they only query the gRPC
+// nodes. The others query Tendermint RPC, Ethereum or REST APIs, which only
+// have the latest state.
+var historicalEndpoints = map[string]bool{
+	"general":    true,
+	"params":     true,
+	"validators": true,
+	"validator":  true,
+	"wallet":     true,
+}
+
+func registerEndpoint(name string, factory exporter.CollectorFactory, params ...string) *exporter.Endpoint {
+	endpoint := exporter.NewEndpoint(name, pollInterval(name), factory, params...)
+	endpoint.Historical = historicalEndpoints[name]
+	http.Handle("/metrics/"+name, endpoint)
+	return endpoint
+}
+
+func registerChainEndpoint(name string, chains *exporter.Chains, build func(chain *exporter.Chain) (exporter.Collector, error)) *exporter.Endpoint {
+	factory, err := exporter.ChainCollector(chains, build)
+	if err != nil {
+		log.Fatal().Err(err).Str("endpoint", name).Msg("Could not create collector")
+	}
+
+	return registerEndpoint(name, factory, "chain")
+}
+
+func pollInterval(name string) time.Duration {
+	interval := PollInterval
+	if value, ok := PollIntervals[name]; ok {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			log.Fatal().Err(err).Str("endpoint", name).Msg("Could not parse poll interval")
 		}
+		interval = parsed
+	}
+
+	if interval > 0 {
+		log.Info().
+			Str("endpoint", name).
+			Dur("interval", interval).
+			Msg("Serving endpoint from background poller")
 	}
 
-	log.Fatal().Msg("Could not find the denom info")
+	return interval
 }
 
 func main() {
 	rootCmd.PersistentFlags().StringVar(&ConfigPath, "config", "/var/lib/cosmos/config.json", "Config file path")
 	rootCmd.PersistentFlags().StringVar(&Denom, "denom", "", "Cosmos coin denom")
 	rootCmd.PersistentFlags().Float64Var(&DenomCoefficient, "denom-coefficient", 0, "Denom coefficient")
+	rootCmd.PersistentFlags().StringSliceVar(&DenomOverrides, "denoms", nil, "Units of denoms other than the ones in their metadata, as base=display or base=display:exponent")
+	rootCmd.PersistentFlags().BoolVar(&exporter.ExactAmounts, "exact-amounts", false, "Also export every token amount exactly, as the amount label of an _exact_info metric")
 	rootCmd.PersistentFlags().StringVar(&ListenAddress, "listen-address", ":9300", "The address this exporter would listen on")
-	rootCmd.PersistentFlags().StringVar(&NodeAddress, "node", "localhost:9090", "RPC node address")
-	rootCmd.PersistentFlags().StringVar(&LogLevel, "log-level", "info", "Logging level")
-	rootCmd.PersistentFlags().Uint64Var(&Limit, "limit", 1000, "Pagination limit for gRPC requests")
-	rootCmd.PersistentFlags().StringVar(&TendermintRPC, "tendermint-rpc", "http://localhost:26657", "Tendermint RPC address")
-	rootCmd.PersistentFlags().StringToStringVar(&OptionalNetworks, "optional-networks", nil, "Optional grpc networks")
+	rootCmd.PersistentFlags().StringVar(&WebConfigFile, "web-config-file", "", "Web config file with TLS and authentication settings of the listener")
+	rootCmd.PersistentFlags().DurationVar(&exporter.HTTPReadTimeout, "http-read-timeout", 30*time.Second, "Maximum duration for reading a whole request, including its headers")
+	rootCmd.PersistentFlags().DurationVar(&exporter.HTTPWriteTimeout, "http-write-timeout", 60*time.Second, "Maximum duration of a request from the end of its headers to the end of the response, should be longer than the scrape timeout")
+	rootCmd.PersistentFlags().DurationVar(&exporter.HTTPIdleTimeout, "http-idle-timeout", 120*time.Second, "Maximum time an idle keep-alive connection is kept open")
+	rootCmd.PersistentFlags().DurationVar(&ShutdownTimeout, "shutdown-timeout", 30*time.Second, "Grace period for in-flight requests to finish on SIGTERM or SIGINT")
+	rootCmd.PersistentFlags().StringSliceVar(&NodeAddresses, "node", []string{"localhost:9090"}, "gRPC node addresses, queries go to the first healthy one")
+	rootCmd.PersistentFlags().Uint64Var(&exporter.Limit, "limit", 1000, "Page size of paginated gRPC queries")
+	rootCmd.PersistentFlags().IntVar(&exporter.MaxPages, "max-pages", 100, "Maximum number of pages fetched by a single paginated gRPC query")
+	rootCmd.PersistentFlags().StringSliceVar(&TendermintRPCs, "tendermint-rpc", []string{"http://localhost:26657"}, "Tendermint RPC addresses, queries go to the first healthy one")
+	rootCmd.PersistentFlags().DurationVar(&exporter.HealthCheckInterval, "health-check-interval", 15*time.Second, "Interval of the health checks of the gRPC and Tendermint RPC nodes")
+	rootCmd.PersistentFlags().BoolVar(&MainNode.TLS, "node-tls", false, "Connect to the gRPC node over TLS, verified with the system CA pool")
+	rootCmd.PersistentFlags().StringVar(&MainNode.TLSCA, "node-tls-ca", "", "CA bundle to verify the gRPC node certificate with, instead of the system CA pool")
+	rootCmd.PersistentFlags().StringVar(&MainNode.TLSCert, "node-tls-cert", "", "Client certificate for the gRPC node")
+	rootCmd.PersistentFlags().StringVar(&MainNode.TLSKey, "node-tls-key", "", "Client certificate key for the gRPC node")
+	rootCmd.PersistentFlags().StringVar(&MainNode.TLSServerName, "node-tls-server-name", "", "Server name to verify the gRPC node certificate against, if it differs from the host in --node")
+	rootCmd.PersistentFlags().StringToStringVar(&MainNode.Headers, "node-headers", nil, "Metadata headers sent with every gRPC call to the node, e.g. x-api-key=secret")
 	rootCmd.PersistentFlags().StringVar(&EthRPC, "eth-rpc", "http://localhost:8545", "Ethereum RPC address")
-	rootCmd.PersistentFlags().StringVar(&ethTokenContract, "eth-token-contract", "", "Ethereum token contract")
-	rootCmd.PersistentFlags().StringVar(&ethGravityContract, "eth-gravity-contract", "", "Ethereum gravity contract")
-	rootCmd.PersistentFlags().StringSliceVar(&TokenPrices, "token-prices", nil, "List of CoinGecko token ids to retrieve current prices")
+	rootCmd.PersistentFlags().DurationVar(&PollInterval, "poll-interval", 0, "Interval to query the node in the background and serve scrapes from the latest snapshot, 0 to query on every scrape")
+	rootCmd.PersistentFlags().StringToStringVar(&PollIntervals, "poll-intervals", nil, "Per-endpoint poll interval overrides, e.g. validators=1m,status=10s")
+	rootCmd.PersistentFlags().DurationVar(&exporter.ScrapeTimeout, "scrape-timeout", 10*time.Second, "Timeout of the node queries for scrapers that don't send X-Prometheus-Scrape-Timeout-Seconds, and for background polls")
+	rootCmd.PersistentFlags().DurationVar(&exporter.ScrapeTimeoutOffset, "scrape-timeout-offset", 500*time.Millisecond, "Subtracted from the timeout sent by Prometheus, to leave time to send the response")
+
+	// log level, token prices, optional networks and gravity bridge contracts
+	// can change on reload
+	StartupConfig.AddFlags(rootCmd.PersistentFlags())
+	exporter.PushSettings.AddFlags(rootCmd.PersistentFlags())
 
 	// some networks, like Iris, have the different prefixes for address, validator and consensus node
 	rootCmd.PersistentFlags().StringVar(&Prefix, "bech-prefix", "persistence", "Bech32 global prefix")
@@ -297,6 +602,9 @@ func main() {
 	rootCmd.PersistentFlags().StringVar(&ConsensusNodePrefix, "bech-consensus-node-prefix", "", "Bech32 consensus node prefix")
 	rootCmd.PersistentFlags().StringVar(&ConsensusNodePubkeyPrefix, "bech-consensus-node-pubkey-prefix", "", "Bech32 pubkey consensus node prefix")
 
+	configCmd.AddCommand(configValidateCmd, configPrintCmd)
+	rootCmd.AddCommand(configCmd)
+
 	if err := rootCmd.Execute(); err != nil {
 		log.Fatal().Err(err).Msg("Could not start application")
 	}
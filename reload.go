@@ -0,0 +1,264 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/CudoVentures/cosmos-exporter/exporter"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+var (
+	configReloadSuccessGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cosmos_exporter_config_last_reload_successful",
+			Help: "1 if the latest config reload succeeded, 0 if no",
+		},
+	)
+
+	configReloadTimestampGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cosmos_exporter_config_last_reload_success_timestamp_seconds",
+			Help: "Timestamp of the latest successful config reload, or of the startup",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(configReloadSuccessGauge)
+	prometheus.MustRegister(configReloadTimestampGauge)
+}
+
+// liveKeys are the config file keys of the live config. Changes to any other
+// key are rejected on reload, as they need a restart.
+var liveKeys = map[string]bool{
+	"log-level":                 true,
+	"token-prices":              true,
+	"optional-networks":         true,
+	"optional-network-settings": true,
+	"eth-token-contract":        true,
+	"eth-gravity-contract":      true,
+}
+
+// StartupConfig holds the flags of the live config until startup.
+var StartupConfig exporter.LiveConfig
+
+// Reloader re-reads the config file and swaps in the live config. Nothing
+// is applied unless the whole file is valid and only live keys changed.
+type Reloader struct {
+	mutex sync.Mutex
+	// the command line is parsed again, as its flags take precedence over
+	// the config file like at startup
+	args     []string
+	flags    *pflag.FlagSet
+	config   *viper.Viper
+	networks *exporter.OptionalNetworkPool
+	web      *exporter.WebHandler
+}
+
+func NewReloader(args []string, flags *pflag.FlagSet, networks *exporter.OptionalNetworkPool, web *exporter.WebHandler) *Reloader {
+	configReloadSuccessGauge.Set(1)
+	configReloadTimestampGauge.SetToCurrentTime()
+
+	return &Reloader{
+		args:     args,
+		flags:    flags,
+		config:   viper.GetViper(),
+		networks: networks,
+		web:      web,
+	}
+}
+
+// Reload applies the config file, or keeps the current config and returns
+// why it couldn't.
+func (r *Reloader) Reload() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if err := r.reload(); err != nil {
+		configReloadSuccessGauge.Set(0)
+		log.Error().Err(err).Msg("Could not reload config, keeping the current one")
+		return err
+	}
+
+	configReloadSuccessGauge.Set(1)
+	configReloadTimestampGauge.SetToCurrentTime()
+	return nil
+}
+
+func (r *Reloader) reload() error {
+	if ConfigPath == "" {
+		return fmt.Errorf("no config file to reload")
+	}
+
+	config := viper.New()
+	config.SetConfigFile(ConfigPath)
+	if err := config.ReadInConfig(); err != nil {
+		return fmt.Errorf("could not read config file: %w", err)
+	}
+
+	if errs := checkConfigFile(config, r.flags); len(errs) > 0 {
+		return fmt.Errorf("invalid config file:\n%w", errs)
+	}
+
+	if keys := restartRequired(r.config, config); len(keys) > 0 {
+		return fmt.Errorf("changes to %s can't be applied without a restart", strings.Join(keys, ", "))
+	}
+
+	next, err := r.readLiveConfig(config)
+	if err != nil {
+		return err
+	}
+
+	if err := next.Validate(); err != nil {
+		return err
+	}
+
+	settings, err := optionalNetworkSettings(config, next.OptionalNetworks)
+	if err != nil {
+		return fmt.Errorf("could not read optional network settings: %w", err)
+	}
+
+	var webConfig *exporter.WebConfig
+	if WebConfigFile != "" {
+		webConfig, err = exporter.LoadWebConfig(WebConfigFile)
+		if err != nil {
+			return fmt.Errorf("could not load web config: %w", err)
+		}
+
+		if !reflect.DeepEqual(webConfig.TLSServerConfig, r.web.Config().TLSServerConfig) {
+			return fmt.Errorf("changes to tls_server_config of the web config can't be applied without a restart")
+		}
+	}
+
+	// unchanged networks keep their connections
+	var networks *exporter.OptionalNetworkPool
+	if !reflect.DeepEqual(next.OptionalNetworks, exporter.CurrentLiveConfig().OptionalNetworks) ||
+		!reflect.DeepEqual(config.Get("optional-network-settings"), r.config.Get("optional-network-settings")) {
+		networks, err = exporter.NewOptionalNetworkPool(next.OptionalNetworks, settings)
+		if err != nil {
+			return err
+		}
+	}
+
+	// nothing can fail from here on, so the config is applied as a whole
+	level, _ := zerolog.ParseLevel(next.LogLevel)
+	zerolog.SetGlobalLevel(level)
+
+	if networks != nil {
+		r.networks.Swap(networks, inFlightGracePeriod())
+	}
+
+	if webConfig != nil {
+		r.web.Swap(webConfig)
+	}
+
+	exporter.SetLiveConfig(next)
+	r.config = config
+
+	log.Info().
+		Str("--log-level", next.LogLevel).
+		Strs("--token-prices", next.TokenPrices).
+		Bool("optional-networks-changed", networks != nil).
+		Str("--eth-token-contract", next.EthTokenContract).
+		Str("--eth-gravity-contract", next.EthGravityContract).
+		Bool("web-config-reloaded", webConfig != nil).
+		Msg("Config reloaded")
+
+	return nil
+}
+
+// readLiveConfig resolves the live flags the same way as at startup: the
+// command line first, then the config file, then the defaults.
+func (r *Reloader) readLiveConfig(config *viper.Viper) (*exporter.LiveConfig, error) {
+	next := &exporter.LiveConfig{}
+
+	flags := pflag.NewFlagSet("reload", pflag.ContinueOnError)
+	flags.ParseErrorsWhitelist.UnknownFlags = true
+	flags.SetOutput(ioutil.Discard)
+	next.AddFlags(flags)
+
+	if err := flags.Parse(r.args); err != nil {
+		return nil, err
+	}
+
+	var err error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if err == nil && !f.Changed && config.IsSet(f.Name) {
+			if setErr := flags.Set(f.Name, flagValue(config.Get(f.Name))); setErr != nil {
+				err = fmt.Errorf("invalid %s: %w", f.Name, setErr)
+			}
+		}
+	})
+
+	return next, err
+}
+
+// restartRequired returns the top-level keys outside of the live config that
+// differ between the config files.
+func restartRequired(current, next *viper.Viper) []string {
+	currentSettings := current.AllSettings()
+	nextSettings := next.AllSettings()
+
+	keys := make(map[string]bool)
+	for key := range currentSettings {
+		keys[key] = true
+	}
+	for key := range nextSettings {
+		keys[key] = true
+	}
+
+	var changed []string
+	for key := range keys {
+		if !liveKeys[key] && !reflect.DeepEqual(currentSettings[key], nextSettings[key]) {
+			changed = append(changed, key)
+		}
+	}
+
+	sort.Strings(changed)
+	return changed
+}
+
+// inFlightGracePeriod is how long replaced connections are kept open for the
+// requests that started before a reload.
+func inFlightGracePeriod() time.Duration {
+	if exporter.HTTPWriteTimeout > exporter.ScrapeTimeout {
+		return exporter.HTTPWriteTimeout
+	}
+
+	return exporter.ScrapeTimeout
+}
+
+// ServeHTTP reloads the config on POST /-/reload. Reloading over HTTP needs
+// a web config that requires credentials for it, so anonymous clients can't
+// trigger reloads.
+func (r *Reloader) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Only POST requests allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !r.web.Config().Protects(req.URL.Path) {
+		http.Error(w, "Reloading over HTTP requires basic_auth_users or bearer_tokens in the web config", http.StatusForbidden)
+		return
+	}
+
+	log.Info().Str("remote", req.RemoteAddr).Msg("Reloading config")
+
+	if err := r.Reload(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	fmt.Fprintln(w, "Config reloaded")
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/CudoVentures/cosmos-exporter/exporter"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+func readTestConfig(t *testing.T, content string) *viper.Viper {
+	t.Helper()
+
+	config := viper.New()
+	config.SetConfigType("yaml")
+	if err := config.ReadConfig(strings.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+
+	return config
+}
+
+func TestRestartRequired(t *testing.T) {
+	current := `
+node: localhost:9090
+log-level: info
+chains:
+  osmosis:
+    node: [localhost:9091]
+`
+
+	tests := []struct {
+		name string
+		next string
+		want []string
+	}{
+		{
+			name: "unchanged",
+			next: current,
+		},
+		{
+			name: "live keys",
+			next: `
+node: localhost:9090
+log-level: debug
+token-prices: [cosmos]
+optional-networks:
+  osmosis: localhost:9091
+eth-token-contract: "0x0000000000000000000000000000000000000001"
+chains:
+  osmosis:
+    node: [localhost:9091]
+`,
+		},
+		{
+			name: "changed",
+			next: `
+node: other:9090
+log-level: info
+chains:
+  osmosis:
+    node: [localhost:9092]
+`,
+			want: []string{"chains", "node"},
+		},
+		{
+			name: "added and removed",
+			next: `
+log-level: info
+limit: 100
+chains:
+  osmosis:
+    node: [localhost:9091]
+`,
+			want: []string{"limit", "node"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := restartRequired(readTestConfig(t, current), readTestConfig(t, test.next))
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("restartRequired() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestReloaderReload(t *testing.T) {
+	current := "node: localhost:9090\nlog-level: info\n"
+
+	tests := []struct {
+		name    string
+		next    string
+		wantErr string
+	}{
+		{
+			name: "live change",
+			next: "node: localhost:9090\nlog-level: debug\n",
+		},
+		{
+			name:    "unknown key",
+			next:    "node: localhost:9090\nlog-level: debug\nlog-levels: debug\n",
+			wantErr: "unknown key log-levels",
+		},
+		{
+			name:    "invalid live value",
+			next:    "node: localhost:9090\nlog-level: loud\n",
+			wantErr: "log-level",
+		},
+		{
+			name:    "invalid Ethereum contract",
+			next:    "node: localhost:9090\nlog-level: debug\neth-token-contract: not-an-address\n",
+			wantErr: "eth-token-contract",
+		},
+		{
+			name:    "restart required",
+			next:    "node: other:9090\nlog-level: debug\n",
+			wantErr: "changes to node can't be applied without a restart",
+		},
+		{
+			name:    "unreadable file",
+			next:    "node: [localhost:9090\n",
+			wantErr: "could not read config file",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := ioutil.WriteFile(path, []byte(test.next), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			previousPath, previousLiveConfig, previousLevel := ConfigPath, exporter.CurrentLiveConfig(), zerolog.GlobalLevel()
+			t.Cleanup(func() {
+				ConfigPath = previousPath
+				exporter.SetLiveConfig(previousLiveConfig)
+				zerolog.SetGlobalLevel(previousLevel)
+			})
+
+			ConfigPath = path
+			live := &exporter.LiveConfig{LogLevel: "info"}
+			exporter.SetLiveConfig(live)
+
+			flags := testConfigFlags()
+			(&exporter.LiveConfig{}).AddFlags(flags)
+
+			config := readTestConfig(t, current)
+			reloader := &Reloader{flags: flags, config: config}
+
+			err := reloader.reload()
+
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("reload() = %v", err)
+				}
+
+				if exporter.CurrentLiveConfig().LogLevel != "debug" || reloader.config == config {
+					t.Errorf("reload() applied log level %s, want the new config applied", exporter.CurrentLiveConfig().LogLevel)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("reload() = %v, want an error with %q", err, test.wantErr)
+			}
+
+			// nothing of an invalid config is applied
+			if exporter.CurrentLiveConfig() != live || reloader.config != config {
+				t.Errorf("reload() replaced the config with %+v, want the old one kept", exporter.CurrentLiveConfig())
+			}
+		})
+	}
+}